@@ -0,0 +1,62 @@
+// Command webhook-receiver is a local endpoint for testing webhook
+// subscriptions. It verifies the signature of every request and prints the
+// event. Use -fail to answer with 500 and watch the retries.
+//
+// Usage:
+//
+//	go run ./cmd/webhook-receiver -addr :9090 -secret whsec_...
+//
+// Then create a webhook with url http://localhost:9090/ and send a test event.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"inventory-backend/services"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "listen address")
+	secret := flag.String("secret", "", "signing secret of the subscription, empty skips verification")
+	fail := flag.Bool("fail", false, "answer every request with 500")
+	flag.Parse()
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		status := "unverified"
+		if *secret != "" {
+			if err := services.VerifyWebhookSignature(*secret, r.Header.Get(services.WebhookSignatureHeader), body, 5*time.Minute); err != nil {
+				log.Printf("❌ %s %s rejected: %v", r.Header.Get(services.WebhookEventHeader), r.Header.Get(services.WebhookDeliveryHeader), err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			status = "verified"
+		}
+
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, body, "", "  ") != nil {
+			pretty.Write(body)
+		}
+		log.Printf("📨 %s %s (%s)\n%s", r.Header.Get(services.WebhookEventHeader), r.Header.Get(services.WebhookDeliveryHeader), status, pretty.String())
+
+		if *fail {
+			http.Error(w, "failing on purpose", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+
+	log.Printf("Listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"inventory-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AttributeDefinitionRequest struct {
+	Key      string   `json:"key"` // e.g. "voltage", fixed once created
+	Label    string   `json:"label"`
+	Type     string   `json:"type"` // string, number, bool, enum or date, fixed once created
+	Required bool     `json:"required"`
+	Options  []string `json:"options"` // enum only
+	Position int      `json:"position"`
+}
+
+func attributeDefinitionInput(req *AttributeDefinitionRequest) services.AttributeDefinitionInput {
+	return services.AttributeDefinitionInput{
+		Key:      req.Key,
+		Label:    req.Label,
+		Type:     req.Type,
+		Required: req.Required,
+		Options:  req.Options,
+		Position: req.Position,
+	}
+}
+
+// GetCategoryAttributes lists the attributes of the products of a category,
+// including those inherited from its parents
+func (cc *CategoryController) GetCategoryAttributes(c *fiber.Ctx) error {
+	attributes, err := cc.categories.ListAttributes(c.UserContext(), paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to fetch attributes")
+	}
+	return c.JSON(attributes)
+}
+
+// CreateCategoryAttribute defines a custom attribute on a category
+func (cc *CategoryController) CreateCategoryAttribute(c *fiber.Ctx) error {
+	req := new(AttributeDefinitionRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	attribute, err := cc.categories.CreateAttribute(c.UserContext(), paramID(c), attributeDefinitionInput(req))
+	if err != nil {
+		return serviceError(c, err, "Failed to create attribute")
+	}
+
+	return c.Status(201).JSON(attribute)
+}
+
+// UpdateCategoryAttribute changes the label, required flag, options and
+// position of an attribute
+func (cc *CategoryController) UpdateCategoryAttribute(c *fiber.Ctx) error {
+	req := new(AttributeDefinitionRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	attribute, err := cc.categories.UpdateAttribute(c.UserContext(), paramID(c), attributeDefinitionInput(req))
+	if err != nil {
+		return serviceError(c, err, "Failed to update attribute")
+	}
+
+	return c.JSON(attribute)
+}
+
+// DeleteCategoryAttribute removes an attribute and its values on products
+func (cc *CategoryController) DeleteCategoryAttribute(c *fiber.Ctx) error {
+	if err := cc.categories.DeleteAttribute(c.UserContext(), paramID(c)); err != nil {
+		return serviceError(c, err, "Failed to delete attribute")
+	}
+
+	return c.JSON(fiber.Map{"message": "Attribute deleted successfully"})
+}
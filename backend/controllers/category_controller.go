@@ -1,8 +1,8 @@
 package controllers
 
 import (
-	"inventory-backend/config"
-	"inventory-backend/models"
+	"inventory-backend/services"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -10,75 +10,112 @@ import (
 type CategoryRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	ParentID    *uint  `json:"parent_id"` // Create only
+}
+
+type CategoryMoveRequest struct {
+	ParentID *uint `json:"parent_id"` // null moves the category to the top level
+}
+
+type CategoryController struct {
+	categories *services.CategoryService
+}
+
+func NewCategoryController(categories *services.CategoryService) *CategoryController {
+	return &CategoryController{categories: categories}
 }
 
 // Get All Categories
-func GetCategories(c *fiber.Ctx) error {
-	var categories []models.Category
-	if err := config.DB.Find(&categories).Error; err != nil {
+func (cc *CategoryController) GetCategories(c *fiber.Ctx) error {
+	categories, err := cc.categories.List(c.UserContext())
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch categories"})
 	}
 	return c.JSON(categories)
 }
 
+// GetCategoryTree returns the top level categories with their subcategories
+// nested under "children"
+func (cc *CategoryController) GetCategoryTree(c *fiber.Ctx) error {
+	tree, err := cc.categories.Tree(c.UserContext())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch categories"})
+	}
+	return c.JSON(tree)
+}
+
 // Create Category
-func CreateCategory(c *fiber.Ctx) error {
+func (cc *CategoryController) CreateCategory(c *fiber.Ctx) error {
 	req := new(CategoryRequest)
 	if err := c.BodyParser(req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	if req.Name == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "Category name is required"})
-	}
-
-	category := models.Category{
+	category, err := cc.categories.Create(c.UserContext(), services.CategoryInput{
 		Name:        req.Name,
 		Description: req.Description,
-	}
-
-	if err := config.DB.Create(&category).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to create category"})
+		ParentID:    req.ParentID,
+	})
+	if err != nil {
+		return serviceError(c, err, "Failed to create category")
 	}
 
 	return c.Status(201).JSON(category)
 }
 
 // Update Category
-func UpdateCategory(c *fiber.Ctx) error {
-	id := c.Params("id")
+func (cc *CategoryController) UpdateCategory(c *fiber.Ctx) error {
 	req := new(CategoryRequest)
-
 	if err := c.BodyParser(req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	var category models.Category
-	if err := config.DB.First(&category, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Category not found"})
+	category, err := cc.categories.Update(c.UserContext(), paramID(c), services.CategoryInput{
+		Name:        req.Name,
+		Description: req.Description,
+	})
+	if err != nil {
+		return serviceError(c, err, "Failed to update category")
 	}
 
-	category.Name = req.Name
-	category.Description = req.Description
+	return c.JSON(category)
+}
+
+// MoveCategory moves a category and its subcategories under another parent
+func (cc *CategoryController) MoveCategory(c *fiber.Ctx) error {
+	req := new(CategoryMoveRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
 
-	if err := config.DB.Save(&category).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to update category"})
+	category, err := cc.categories.Move(c.UserContext(), paramID(c), req.ParentID)
+	if err != nil {
+		return serviceError(c, err, "Failed to move category")
 	}
 
 	return c.JSON(category)
 }
 
 // Delete Category
-func DeleteCategory(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var category models.Category
-
-	if err := config.DB.First(&category, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Category not found"})
+// A category with subcategories or products needs reassign_to, either the id
+// of the category that takes them over or "parent" for the parent of the
+// deleted category
+func (cc *CategoryController) DeleteCategory(c *fiber.Ctx) error {
+	var options services.CategoryDeleteOptions
+	if reassignTo := c.Query("reassign_to"); reassignTo != "" {
+		options.Reassign = true
+		if reassignTo != "parent" {
+			targetID, err := strconv.ParseUint(reassignTo, 10, 32)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "reassign_to must be a category id or 'parent'"})
+			}
+			target := uint(targetID)
+			options.TargetID = &target
+		}
 	}
 
-	if err := config.DB.Delete(&category).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to delete category"})
+	if err := cc.categories.Delete(c.UserContext(), paramID(c), options); err != nil {
+		return serviceError(c, err, "Failed to delete category")
 	}
 
 	return c.JSON(fiber.Map{"message": "Category deleted successfully"})
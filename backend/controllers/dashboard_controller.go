@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"inventory-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type DashboardController struct {
+	dashboard *services.DashboardService
+}
+
+func NewDashboardController(dashboard *services.DashboardService) *DashboardController {
+	return &DashboardController{dashboard: dashboard}
+}
+
+// GetDashboardSummary returns inventory totals, top movers, daily movements
+// and category/supplier breakdowns. Query: days (default 30), top (default 5).
+func (dc *DashboardController) GetDashboardSummary(c *fiber.Ctx) error {
+	summary, err := dc.dashboard.Summary(c.UserContext(), c.QueryInt("days", 30), c.QueryInt("top", 5))
+	if err != nil {
+		return serviceError(c, err, "Failed to build dashboard summary")
+	}
+
+	return c.JSON(summary)
+}
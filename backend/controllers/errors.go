@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"errors"
+	"inventory-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// serviceError maps a service error to the matching HTTP response. Errors the
+// service layer does not know about are reported as 500 with fallback.
+func serviceError(c *fiber.Ctx, err error, fallback string) error {
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		return c.Status(400).JSON(fiber.Map{"error": validationErr.Message})
+	}
+
+	switch {
+	case errors.Is(err, services.ErrProductNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Product not found"})
+	case errors.Is(err, services.ErrSupplierNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Supplier not found"})
+	case errors.Is(err, services.ErrCategoryNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Category not found"})
+	case errors.Is(err, services.ErrAttributeNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Attribute not found"})
+	case errors.Is(err, services.ErrSKUExists):
+		return c.Status(400).JSON(fiber.Map{"error": "SKU already exists"})
+	case errors.Is(err, services.ErrCategoryExists):
+		return c.Status(400).JSON(fiber.Map{"error": "A category with this name already exists at this level"})
+	case errors.Is(err, services.ErrInsufficientStock):
+		return c.Status(400).JSON(fiber.Map{"error": "Insufficient stock"})
+	case errors.Is(err, services.ErrSupplierInUse):
+		return c.Status(400).JSON(fiber.Map{"error": "Cannot delete supplier with existing products"})
+	case errors.Is(err, services.ErrCategoryInUse):
+		return c.Status(400).JSON(fiber.Map{"error": "Cannot delete category with subcategories or products, pass reassign_to"})
+	case errors.Is(err, services.ErrProductHasVariants):
+		return c.Status(400).JSON(fiber.Map{"error": "Cannot delete product with existing variants"})
+	case errors.Is(err, services.ErrProductInKit):
+		return c.Status(400).JSON(fiber.Map{"error": "Cannot delete product used as a kit component"})
+	case errors.Is(err, services.ErrExportJobNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Export job not found"})
+	case errors.Is(err, services.ErrExportNotReady):
+		return c.Status(409).JSON(fiber.Map{"error": "Export is not ready yet"})
+	case errors.Is(err, services.ErrExportExpired):
+		return c.Status(410).JSON(fiber.Map{"error": "Export has expired"})
+	case errors.Is(err, services.ErrReportScheduleNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Report schedule not found"})
+	case errors.Is(err, services.ErrPurchaseOrderNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Purchase order not found"})
+	case errors.Is(err, services.ErrStockAlertNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Stock alert not found"})
+	case errors.Is(err, services.ErrAlertChannelNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Alert channel not found"})
+	case errors.Is(err, services.ErrWebhookNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Webhook not found"})
+	case errors.Is(err, services.ErrWebhookDeliveryNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Webhook delivery not found"})
+	case errors.Is(err, services.ErrVersionMismatch):
+		return c.Status(412).JSON(fiber.Map{"error": "The resource was changed since you loaded it, reload and try again"})
+	case errors.Is(err, services.ErrVersionConflict):
+		return c.Status(409).JSON(fiber.Map{"error": "The resource was changed by another request, reload and try again"})
+	case errors.Is(err, services.ErrAlertTestFailed):
+		return c.Status(502).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(500).JSON(fiber.Map{"error": fallback})
+}
+
+// paramID parses the :id route parameter
+func paramID(c *fiber.Ctx) uint {
+	id, _ := c.ParamsInt("id")
+	if id < 0 {
+		return 0
+	}
+	return uint(id)
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"inventory-backend/services"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// setETag sets the ETag header of a versioned resource
+func setETag(c *fiber.Ctx, version uint) {
+	c.Set(fiber.HeaderETag, fmt.Sprintf("%q", strconv.FormatUint(uint64(version), 10)))
+}
+
+var (
+	errIfMatchMissing = errors.New("If-Match header missing")
+	errIfMatchInvalid = errors.New("If-Match header invalid")
+)
+
+// ifMatch reads the version from the If-Match header. "*" matches any version
+// and is returned as 0.
+func ifMatch(c *fiber.Ctx) (uint, error) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	switch header {
+	case "":
+		return 0, errIfMatchMissing
+	case "*":
+		return 0, nil
+	}
+
+	value := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || version == 0 {
+		return 0, errIfMatchInvalid
+	}
+	return uint(version), nil
+}
+
+// ifMatchError answers a request whose If-Match header is missing or invalid
+func ifMatchError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errIfMatchMissing) {
+		return c.Status(428).JSON(fiber.Map{"error": "If-Match header with the ETag of the resource is required"})
+	}
+	return c.Status(400).JSON(fiber.Map{"error": "If-Match must be an ETag returned by this API"})
+}
+
+// isStale reports whether err is a failed version check
+func isStale(err error) bool {
+	return errors.Is(err, services.ErrVersionMismatch) || errors.Is(err, services.ErrVersionConflict)
+}
+
+// staleVersion answers a failed version check with the current state of the
+// resource under key, so the client can merge and retry with its ETag. current
+// is nil when the resource could not be reloaded.
+func staleVersion(c *fiber.Ctx, err error, key string, current interface{}, version uint) error {
+	status, message := 412, "The resource was changed since you loaded it"
+	if errors.Is(err, services.ErrVersionConflict) {
+		status, message = 409, "The resource was changed by another request"
+	}
+
+	response := fiber.Map{"error": message}
+	if current != nil {
+		setETag(c, version)
+		response[key] = current
+	}
+	return c.Status(status).JSON(response)
+}
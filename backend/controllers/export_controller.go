@@ -1,27 +1,58 @@
 package controllers
 
 import (
+	"bufio"
 	"fmt"
 	"inventory-backend/config"
 	"inventory-backend/models"
+	"inventory-backend/repositories"
 	"inventory-backend/services"
+	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// ExportProducts generates an Excel file of all products
-func ExportProducts(c *fiber.Ctx) error {
-	var products []models.Product
-	if err := config.DB.Preload("Category").Preload("Supplier").Find(&products).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch products"})
+type ExportController struct {
+	products *services.ProductService
+}
+
+func NewExportController(products *services.ProductService) *ExportController {
+	return &ExportController{products: products}
+}
+
+// ExportProducts exports products as csv, json or xlsx (default). It accepts
+// the same search and category_id filters as GetProducts. Products are read
+// in batches and streamed to the client as the file is written.
+func (ec *ExportController) ExportProducts(c *fiber.Ctx) error {
+	format := strings.ToLower(c.Query("format", "xlsx"))
+	contentType, ok := services.ProductExportContentTypes[format]
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "Format must be 'csv', 'json' or 'xlsx'"})
 	}
 
-	fileName := fmt.Sprintf("inventory_products_%s.xlsx", time.Now().Format("20060102_150405"))
-	c.Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	categoryID, _ := strconv.ParseUint(c.Query("category_id"), 10, 32)
+	filter := repositories.ProductFilter{
+		Search:     c.Query("search"),
+		CategoryID: uint(categoryID),
+	}
+
+	fileName := fmt.Sprintf("inventory_products_%s.%s", time.Now().Format("20060102_150405"), format)
+	c.Set("Content-Type", contentType)
 	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
 
-	return services.GenerateProductExcel(products, c.Response().BodyWriter())
+	// The stream writer runs after the handler returns, so it must not touch c
+	ctx := c.UserContext()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := ec.products.Export(ctx, filter, format, w); err != nil {
+			log.Printf("Failed to export products: %v", err)
+		}
+		w.Flush()
+	})
+
+	return nil
 }
 
 // ExportActivityLogs generates a PDF of activity logs
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"inventory-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ExportJobController struct {
+	exports *services.ExportJobService
+}
+
+func NewExportJobController(exports *services.ExportJobService) *ExportJobController {
+	return &ExportJobController{exports: exports}
+}
+
+// CreateExportJob queues an export to be generated in the background
+func (ec *ExportJobController) CreateExportJob(c *fiber.Ctx) error {
+	req := new(services.ExportJobInput)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	userID, _ := c.Locals("userID").(uint)
+	job, err := ec.exports.Create(c.UserContext(), userID, *req)
+	if err != nil {
+		return serviceError(c, err, "Failed to create export job")
+	}
+
+	return c.Status(202).JSON(fiber.Map{
+		"message": "Export job queued",
+		"job":     job,
+	})
+}
+
+// GetExportJobs lists the most recent export jobs
+func (ec *ExportJobController) GetExportJobs(c *fiber.Ctx) error {
+	jobs, err := ec.exports.List(c.UserContext(), 50)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch export jobs"})
+	}
+
+	return c.JSON(fiber.Map{"jobs": jobs})
+}
+
+// GetExportJob reports the status and progress of an export job
+func (ec *ExportJobController) GetExportJob(c *fiber.Ctx) error {
+	job, err := ec.exports.Get(c.UserContext(), paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to fetch export job")
+	}
+
+	return c.JSON(fiber.Map{"job": job})
+}
+
+// DownloadExportJob serves the generated file of a completed export job
+func (ec *ExportJobController) DownloadExportJob(c *fiber.Ctx) error {
+	job, err := ec.exports.Artifact(c.UserContext(), paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to download export")
+	}
+
+	return c.Download(job.FilePath, job.FileName)
+}
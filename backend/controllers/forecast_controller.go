@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"context"
+	"inventory-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ReorderReviewRequest selects the proposals to approve or reject. All must
+// be set explicitly to review every pending proposal.
+type ReorderReviewRequest struct {
+	IDs []uint `json:"ids"`
+	All bool   `json:"all"`
+}
+
+type ForecastController struct {
+	forecasts *services.ForecastService
+}
+
+func NewForecastController(forecasts *services.ForecastService) *ForecastController {
+	return &ForecastController{forecasts: forecasts}
+}
+
+// forecastParams reads the optional days and service_level (e.g. 0.95) query parameters
+func forecastParams(c *fiber.Ctx) services.ForecastParams {
+	return services.ForecastParams{
+		WindowDays:   c.QueryInt("days"),
+		ServiceLevel: c.QueryFloat("service_level"),
+	}
+}
+
+// GetProductForecast returns the demand forecast and suggested reorder point of a product
+func (fc *ForecastController) GetProductForecast(c *fiber.Ctx) error {
+	forecast, err := fc.forecasts.Forecast(c.UserContext(), paramID(c), forecastParams(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to forecast demand")
+	}
+
+	return c.JSON(fiber.Map{"forecast": forecast})
+}
+
+// ProposeReorderPoints computes new reorder points for the catalog and stores
+// them as pending proposals
+func (fc *ForecastController) ProposeReorderPoints(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(uint)
+	run, err := fc.forecasts.Propose(c.UserContext(), userID, forecastParams(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to propose reorder points")
+	}
+
+	return c.Status(201).JSON(run)
+}
+
+func (fc *ForecastController) GetReorderProposals(c *fiber.Ctx) error {
+	proposals, err := fc.forecasts.ListProposals(c.UserContext(), c.Query("status", "pending"))
+	if err != nil {
+		return serviceError(c, err, "Failed to fetch reorder proposals")
+	}
+
+	return c.JSON(fiber.Map{"proposals": proposals})
+}
+
+// ApproveReorderProposals applies the proposed reorder points to MinStock
+func (fc *ForecastController) ApproveReorderProposals(c *fiber.Ctx) error {
+	return fc.reviewReorderProposals(c, fc.forecasts.Approve)
+}
+
+func (fc *ForecastController) RejectReorderProposals(c *fiber.Ctx) error {
+	return fc.reviewReorderProposals(c, fc.forecasts.Reject)
+}
+
+func (fc *ForecastController) reviewReorderProposals(c *fiber.Ctx, review func(ctx context.Context, userID uint, ids []uint) (*services.ReorderReview, error)) error {
+	req := new(ReorderReviewRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if len(req.IDs) == 0 && !req.All {
+		return c.Status(400).JSON(fiber.Map{"error": "Provide proposal ids or set all to true"})
+	}
+
+	userID, _ := c.Locals("userID").(uint)
+	result, err := review(c.UserContext(), userID, req.IDs)
+	if err != nil {
+		return serviceError(c, err, "Failed to review reorder proposals")
+	}
+
+	return c.JSON(result)
+}
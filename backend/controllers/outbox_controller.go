@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"inventory-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type OutboxController struct {
+	outbox *services.OutboxService
+}
+
+func NewOutboxController(outbox *services.OutboxService) *OutboxController {
+	return &OutboxController{outbox: outbox}
+}
+
+// GetOutbox returns the relay backlog and the latest events, only the
+// unpublished ones with ?pending=true
+func (oc *OutboxController) GetOutbox(c *fiber.Ctx) error {
+	status, err := oc.outbox.Status(c.UserContext(), c.QueryBool("pending"), c.QueryInt("limit", 50))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch outbox"})
+	}
+
+	return c.JSON(status)
+}
@@ -1,11 +1,13 @@
 package controllers
 
 import (
+	"encoding/json"
 	"fmt"
-	"inventory-backend/config"
-	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"inventory-backend/services"
 	"inventory-backend/utils"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -13,46 +15,47 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-type ProductRequest struct {
-	SKU         string  `json:"sku"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	Stock       int     `json:"stock"`
-	MinStock    int     `json:"min_stock"`
-	SupplierID  uint    `json:"supplier_id"`
-	CategoryID  *uint   `json:"category_id"`
+type ProductController struct {
+	products *services.ProductService
 }
 
-// Get All Products dengan Search & Pagination
-func GetProducts(c *fiber.Ctx) error {
-	var products []models.Product
+func NewProductController(products *services.ProductService) *ProductController {
+	return &ProductController{products: products}
+}
 
+// Get All Products dengan Search & Pagination
+// search is matched against the search index: SKU, name, description,
+// supplier and category, ranked by relevance and tolerant of typos
+func (pc *ProductController) GetProducts(c *fiber.Ctx) error {
 	// Pagination
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	limit, _ := strconv.Atoi(c.Query("limit", "10"))
 	offset := (page - 1) * limit
 
 	// Search
-	search := c.Query("search")
-	categoryID := c.Query("category_id")
-
-	query := config.DB.Preload("Supplier").Preload("Category")
-
-	if search != "" {
-		query = query.Where("name LIKE ? OR sku LIKE ?", "%"+search+"%", "%"+search+"%")
+	categoryID, _ := strconv.ParseUint(c.Query("category_id"), 10, 32)
+	filter := repositories.ProductFilter{
+		Search:     c.Query("search"),
+		CategoryID: uint(categoryID),
+		AbcClass:   strings.ToUpper(c.Query("abc_class")),
+		Variants:   c.Query("variants"),
+		Offset:     offset,
+		Limit:      limit,
 	}
-
-	if categoryID != "" {
-		query = query.Where("category_id = ?", categoryID)
+	if value := c.Query("dead_stock"); value != "" {
+		deadStock := value == "true" || value == "1"
+		filter.DeadStock = &deadStock
+	}
+	// variants=nested returns parents with their variants, variants=flat only
+	// the products that hold stock
+	if filter.Variants != "" && filter.Variants != repositories.VariantsNested && filter.Variants != repositories.VariantsFlat {
+		return c.Status(400).JSON(fiber.Map{"error": "variants must be 'nested' or 'flat'"})
 	}
 
-	// Count total
-	var total int64
-	query.Model(&models.Product{}).Count(&total)
+	filter.Attributes = attributeFilters(c)
 
-	// Get products
-	if err := query.Offset(offset).Limit(limit).Find(&products).Error; err != nil {
+	products, total, err := pc.products.List(c.UserContext(), filter)
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch products"})
 	}
 
@@ -68,84 +71,76 @@ func GetProducts(c *fiber.Ctx) error {
 }
 
 // Get Single Product
-func GetProduct(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var product models.Product
-
-	if err := config.DB.Preload("Supplier").Preload("Category").Preload("StockHistory").First(&product, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Product not found"})
+func (pc *ProductController) GetProduct(c *fiber.Ctx) error {
+	product, err := pc.products.Get(c.UserContext(), paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to fetch product")
 	}
 
+	setETag(c, product.Version)
 	return c.JSON(fiber.Map{"product": product})
 }
 
 // Create Product dengan Upload Image
-func CreateProduct(c *fiber.Ctx) error {
+func (pc *ProductController) CreateProduct(c *fiber.Ctx) error {
 	// Parse SKU/Quantity etc from Form Data
-	sku := c.FormValue("sku")
-	name := c.FormValue("name")
-	desc := c.FormValue("description")
 	price, _ := strconv.ParseFloat(c.FormValue("price"), 64)
-	stock, _ := strconv.Atoi(c.FormValue("stock"))
-	minStock, _ := strconv.Atoi(c.FormValue("min_stock"))
+	stock, _ := strconv.ParseFloat(c.FormValue("stock"), 64)
+	minStock, _ := strconv.ParseFloat(c.FormValue("min_stock"), 64)
+	maxStock, _ := strconv.ParseFloat(c.FormValue("max_stock"), 64)
+	minOrderQty, _ := strconv.ParseFloat(c.FormValue("min_order_qty"), 64)
+	packSize, _ := strconv.ParseFloat(c.FormValue("pack_size"), 64)
+	fractional, _ := strconv.ParseBool(c.FormValue("fractional"))
 	supplierID, _ := strconv.Atoi(c.FormValue("supplier_id"))
 	categoryID, _ := strconv.Atoi(c.FormValue("category_id"))
 
-	// Validasi
-	if sku == "" || name == "" || price <= 0 || supplierID == 0 {
-		return c.Status(400).JSON(fiber.Map{"error": "SKU, Name, Price, and Supplier are required"})
+	input := services.ProductInput{
+		SKU:         c.FormValue("sku"),
+		Name:        c.FormValue("name"),
+		Description: c.FormValue("description"),
+		Price:       price,
+		Stock:       stock,
+		MinStock:    minStock,
+		MaxStock:    maxStock,
+		MinOrderQty: minOrderQty,
+		PackSize:    packSize,
+		SupplierID:  uint(supplierID),
+		BaseUnit:    c.FormValue("base_unit"),
+		Fractional:  fractional,
 	}
 
-	// Cek SKU Unik (Handle Soft Delete collision)
-	var existingProduct models.Product
-	if err := config.DB.Unscoped().Where("sku = ?", sku).First(&existingProduct).Error; err == nil {
-		if existingProduct.DeletedAt.Valid {
-			// SKU collision with deleted product -> Rename old product
-			newSKU := fmt.Sprintf("%s_DELETED_%d", existingProduct.SKU, time.Now().Unix())
-			config.DB.Model(&existingProduct).Update("sku", newSKU)
-		} else {
-			return c.Status(400).JSON(fiber.Map{"error": "SKU already exists"})
-		}
+	// Assign CategoryID safely
+	if categoryID != 0 {
+		cid := uint(categoryID)
+		input.CategoryID = &cid
 	}
+	attributes, err := attributeValues(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	input.Attributes = attributes
 
 	// Handle Image Upload
-	var imageURL string
+	var imagePath string
 	if file, err := c.FormFile("image"); err == nil {
 		filename := fmt.Sprintf("%d_%s", time.Now().Unix(), file.Filename)
-		path := fmt.Sprintf("./uploads/%s", filename)
+		imagePath = fmt.Sprintf("./uploads/%s", filename)
 
-		if err := c.SaveFile(file, path); err != nil {
+		if err := c.SaveFile(file, imagePath); err != nil {
 			return c.Status(500).JSON(fiber.Map{"error": "Failed to save image"})
 		}
-		imageURL = "/uploads/" + filename
-	}
-
-	// Assign CategoryID safely
-	var catIDPtr *uint
-	if categoryID != 0 {
-		cid := uint(categoryID)
-		catIDPtr = &cid
+		input.ImageURL = "/uploads/" + filename
 	}
 
-	product := models.Product{
-		SKU:         sku,
-		Name:        name,
-		Description: desc,
-		Price:       price,
-		Stock:       stock,
-		MinStock:    minStock,
-		SupplierID:  uint(supplierID),
-		CategoryID:  catIDPtr,
-		ImageURL:    imageURL,
-	}
-
-	if err := config.DB.Create(&product).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to create product"})
-	}
-
-	// Log Activity
 	userID, _ := c.Locals("userID").(uint)
-	utils.LogActivity(userID, "CREATE", "Product", product.ID, fmt.Sprintf("Created product: %s (%s)", product.Name, product.SKU))
+	product, err := pc.products.Create(c.UserContext(), userID, input)
+	if err != nil {
+		// Don't keep an image for a product that was never created
+		if imagePath != "" {
+			os.Remove(imagePath)
+		}
+		return serviceError(c, err, "Failed to create product")
+	}
 
 	return c.JSON(fiber.Map{
 		"message": "Product created successfully",
@@ -153,105 +148,106 @@ func CreateProduct(c *fiber.Ctx) error {
 	})
 }
 
-// Update Product
-func UpdateProduct(c *fiber.Ctx) error {
-	id := c.Params("id")
+// UpdateProduct requires the product's ETag in If-Match so concurrent edits
+// are not overwritten
+func (pc *ProductController) UpdateProduct(c *fiber.Ctx) error {
+	id := paramID(c)
+	version, err := ifMatch(c)
+	if err != nil {
+		return ifMatchError(c, err)
+	}
 
-	var product models.Product
-	if err := config.DB.First(&product, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Product not found"})
+	existing, err := pc.products.Find(c.UserContext(), id)
+	if err != nil {
+		return serviceError(c, err, "Failed to update product")
 	}
 
 	// Parse form data
-	sku := c.FormValue("sku")
-	name := c.FormValue("name")
-	description := c.FormValue("description")
-	priceStr := c.FormValue("price")
-	stockStr := c.FormValue("stock")
-	minStockStr := c.FormValue("min_stock")
-	supplierIDStr := c.FormValue("supplier_id")
-	categoryIDStr := c.FormValue("category_id")
-
-	// Update fields
-	if sku != "" && sku != product.SKU {
-		// Check if new SKU already exists
-		var existingProduct models.Product
-		if err := config.DB.Where("sku = ? AND id != ?", sku, id).First(&existingProduct).Error; err == nil {
-			return c.Status(400).JSON(fiber.Map{"error": "SKU already exists"})
-		}
-		product.SKU = sku
+	var input services.ProductUpdate
+	if sku := c.FormValue("sku"); sku != "" {
+		input.SKU = &sku
 	}
-
-	if name != "" {
-		product.Name = name
+	if name := c.FormValue("name"); name != "" {
+		input.Name = &name
 	}
-	if description != "" {
-		product.Description = description
+	if description := c.FormValue("description"); description != "" {
+		input.Description = &description
 	}
-	if priceStr != "" {
+	if priceStr := c.FormValue("price"); priceStr != "" {
 		price, _ := strconv.ParseFloat(priceStr, 64)
-		product.Price = price
+		input.Price = &price
+	}
+	if stockStr := c.FormValue("stock"); stockStr != "" {
+		stock, _ := strconv.ParseFloat(stockStr, 64)
+		input.Stock = &stock
+	}
+	if minStockStr := c.FormValue("min_stock"); minStockStr != "" {
+		minStock, _ := strconv.ParseFloat(minStockStr, 64)
+		input.MinStock = &minStock
 	}
-	if stockStr != "" {
-		stock, _ := strconv.Atoi(stockStr)
-		product.Stock = stock
+	if maxStockStr := c.FormValue("max_stock"); maxStockStr != "" {
+		maxStock, _ := strconv.ParseFloat(maxStockStr, 64)
+		input.MaxStock = &maxStock
 	}
-	if minStockStr != "" {
-		minStock, _ := strconv.Atoi(minStockStr)
-		product.MinStock = minStock
+	if minOrderQtyStr := c.FormValue("min_order_qty"); minOrderQtyStr != "" {
+		minOrderQty, _ := strconv.ParseFloat(minOrderQtyStr, 64)
+		input.MinOrderQty = &minOrderQty
 	}
-	if supplierIDStr != "" {
+	if packSizeStr := c.FormValue("pack_size"); packSizeStr != "" {
+		packSize, _ := strconv.ParseFloat(packSizeStr, 64)
+		input.PackSize = &packSize
+	}
+	if supplierIDStr := c.FormValue("supplier_id"); supplierIDStr != "" {
 		supplierID, _ := strconv.ParseUint(supplierIDStr, 10, 32)
-		var supplier models.Supplier
-		if err := config.DB.First(&supplier, supplierID).Error; err != nil {
-			return c.Status(404).JSON(fiber.Map{"error": "Supplier not found"})
-		}
-		product.SupplierID = uint(supplierID)
+		sid := uint(supplierID)
+		input.SupplierID = &sid
 	}
-
-	if categoryIDStr != "" {
-		categoryID, _ := strconv.Atoi(categoryIDStr)
-		// Validation could refer to checking if category exists
-		var category models.Category
-		if err := config.DB.First(&category, categoryID).Error; err != nil {
-			return c.Status(404).JSON(fiber.Map{"error": "Category not found"})
-		}
+	if categoryIDStr := c.FormValue("category_id"); categoryIDStr != "" {
+		categoryID, _ := strconv.ParseUint(categoryIDStr, 10, 32)
 		cid := uint(categoryID)
-		product.CategoryID = &cid
+		input.CategoryID = &cid
+	}
+	if input.Attributes, err = attributeValues(c); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
+	// Variants only: follow the parent price again
+	input.InheritPrice, _ = strconv.ParseBool(c.FormValue("inherit_price"))
 
 	// Handle image upload
-	file, err := c.FormFile("image")
-	if err == nil {
-		uploadPath := os.Getenv("UPLOAD_PATH")
-		if uploadPath == "" {
-			uploadPath = "./uploads"
-		}
-
-		// Delete old image if exists
-		if product.ImageURL != "" {
-			oldFileName := strings.Replace(product.ImageURL, "/uploads/", "", 1)
-			utils.DeleteFile(oldFileName, uploadPath)
-		}
+	uploadPath := os.Getenv("UPLOAD_PATH")
+	if uploadPath == "" {
+		uploadPath = "./uploads"
+	}
 
-		fileName, err := utils.SaveUploadedFile(file, uploadPath)
+	var newFileName string
+	if file, err := c.FormFile("image"); err == nil {
+		newFileName, err = utils.SaveUploadedFile(file, uploadPath)
 		if err != nil {
 			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 		}
-		product.ImageURL = "/uploads/" + fileName
+		imageURL := "/uploads/" + newFileName
+		input.ImageURL = &imageURL
 	}
 
-	if err := config.DB.Save(&product).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to update product"})
+	userID, _ := c.Locals("userID").(uint)
+	product, err := pc.products.Update(c.UserContext(), userID, id, version, input)
+	if err != nil {
+		if newFileName != "" {
+			utils.DeleteFile(newFileName, uploadPath)
+		}
+		if isStale(err) {
+			return pc.staleProduct(c, err, id)
+		}
+		return serviceError(c, err, "Failed to update product")
 	}
 
-	// Load supplier relation
-	config.DB.Preload("Supplier").First(&product, product.ID)
-
-	// Log Activity
-	userID, _ := c.Locals("userID").(uint)
-	utils.LogActivity(userID, "UPDATE", "Product", product.ID, "Updated product: "+product.Name+" ("+product.SKU+")")
+	// Delete old image once the new one is stored
+	if newFileName != "" && existing.ImageURL != "" {
+		oldFileName := strings.Replace(existing.ImageURL, "/uploads/", "", 1)
+		utils.DeleteFile(oldFileName, uploadPath)
+	}
 
+	setETag(c, product.Version)
 	return c.JSON(fiber.Map{
 		"message": "Product updated successfully",
 		"product": product,
@@ -259,12 +255,19 @@ func UpdateProduct(c *fiber.Ctx) error {
 }
 
 // Delete Product
-func DeleteProduct(c *fiber.Ctx) error {
-	id := c.Params("id")
+func (pc *ProductController) DeleteProduct(c *fiber.Ctx) error {
+	version, err := ifMatch(c)
+	if err != nil {
+		return ifMatchError(c, err)
+	}
 
-	var product models.Product
-	if err := config.DB.First(&product, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Product not found"})
+	userID, _ := c.Locals("userID").(uint)
+	product, err := pc.products.Delete(c.UserContext(), userID, paramID(c), version)
+	if err != nil {
+		if isStale(err) {
+			return pc.staleProduct(c, err, paramID(c))
+		}
+		return serviceError(c, err, "Failed to delete product")
 	}
 
 	// Delete image if exists
@@ -277,31 +280,139 @@ func DeleteProduct(c *fiber.Ctx) error {
 		utils.DeleteFile(fileName, uploadPath)
 	}
 
-	// Smart Delete: Rename SKU to release it
-	originalSKU := product.SKU
-	product.SKU = fmt.Sprintf("%s_DELETED_%d", product.SKU, time.Now().Unix())
-	config.DB.Save(&product)
+	return c.JSON(fiber.Map{
+		"message": "Product deleted successfully",
+	})
+}
+
+// attributeValues parses the "attributes" form field, a JSON object of custom
+// attribute values by key, e.g. {"voltage": 220, "wireless": true}
+func attributeValues(c *fiber.Ctx) (map[string]interface{}, error) {
+	field := c.FormValue("attributes")
+	if field == "" {
+		return nil, nil
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(field), &values); err != nil {
+		return nil, fmt.Errorf("attributes must be a JSON object of values by key")
+	}
+	return values, nil
+}
+
+// attributeFilters reads the custom attribute filters of a product search:
+// attr.<key>=value matches a value, attr.<key>.min and attr.<key>.max bound
+// numbers and dates
+func attributeFilters(c *fiber.Ctx) []repositories.AttributeFilter {
+	byKey := map[string]*repositories.AttributeFilter{}
+	var keys []string
+	for name, value := range c.Queries() {
+		key, found := strings.CutPrefix(name, "attr.")
+		if !found || value == "" {
+			continue
+		}
+		bound := ""
+		if base, ok := strings.CutSuffix(key, ".min"); ok {
+			key, bound = base, "min"
+		} else if base, ok := strings.CutSuffix(key, ".max"); ok {
+			key, bound = base, "max"
+		}
 
-	if err := config.DB.Delete(&product).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to delete product"})
+		filter, exists := byKey[key]
+		if !exists {
+			filter = &repositories.AttributeFilter{Key: key}
+			byKey[key] = filter
+			keys = append(keys, key)
+		}
+		switch bound {
+		case "min":
+			filter.Min = value
+		case "max":
+			filter.Max = value
+		default:
+			filter.Value = value
+		}
 	}
 
-	// Log Activity
+	sort.Strings(keys)
+	filters := make([]repositories.AttributeFilter, len(keys))
+	for i, key := range keys {
+		filters[i] = *byKey[key]
+	}
+	return filters
+}
+
+// staleProduct answers a failed version check with the current product
+func (pc *ProductController) staleProduct(c *fiber.Ctx, err error, id uint) error {
+	current, findErr := pc.products.Find(c.UserContext(), id)
+	if findErr != nil {
+		return staleVersion(c, err, "product", nil, 0)
+	}
+	return staleVersion(c, err, "product", current, current.Version)
+}
+
+// Import Products from a CSV, XLSX or JSON upload (field "file"). Pass dry_run=true
+// to validate the file without saving anything.
+func (pc *ProductController) ImportProducts(c *fiber.Ctx) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "File is required"})
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Failed to read file"})
+	}
+	defer src.Close()
+
+	rows, err := services.ParseProductImport(src, file.Filename)
+	if err != nil {
+		return serviceError(c, err, "Failed to read file")
+	}
+
+	dryRun, _ := strconv.ParseBool(c.FormValue("dry_run", c.Query("dry_run")))
+
 	userID, _ := c.Locals("userID").(uint)
-	utils.LogActivity(userID, "DELETE", "Product", product.ID, "Deleted product: "+product.Name+" ("+originalSKU+")")
+	result, err := pc.products.Import(c.UserContext(), userID, rows, dryRun)
+	if err != nil {
+		return serviceError(c, err, "Failed to import products")
+	}
+
+	if len(result.Errors) > 0 && !dryRun {
+		return c.Status(422).JSON(fiber.Map{
+			"error":  "Import rejected, no products were saved",
+			"result": result,
+		})
+	}
+
+	message := "Products imported successfully"
+	if dryRun {
+		message = "Dry run completed, no products were saved"
+	}
 
 	return c.JSON(fiber.Map{
-		"message": "Product deleted successfully",
+		"message": message,
+		"result":  result,
 	})
 }
 
-// Get Low Stock Products (stock < min_stock)
-func GetLowStockProducts(c *fiber.Ctx) error {
-	var products []models.Product
+// ReindexSearch rebuilds the product search index, e.g. after restoring a
+// database backup
+func (pc *ProductController) ReindexSearch(c *fiber.Ctx) error {
+	count, err := pc.products.ReindexSearch(c.UserContext())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to rebuild search index"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": fmt.Sprintf("Indexed %d products", count),
+		"count":   count,
+	})
+}
 
-	if err := config.DB.Preload("Supplier").
-		Where("stock < min_stock").
-		Find(&products).Error; err != nil {
+// Get Low Stock Products (stock < min_stock)
+func (pc *ProductController) GetLowStockProducts(c *fiber.Ctx) error {
+	products, err := pc.products.ListLowStock(c.UserContext())
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch low stock products"})
 	}
 
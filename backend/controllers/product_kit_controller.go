@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"inventory-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type KitComponentRequest struct {
+	ProductID uint    `json:"product_id"`
+	Quantity  float64 `json:"quantity"` // In the base unit of the component
+}
+
+type KitRequest struct {
+	Type       string                `json:"type"` // "assembled", "virtual" or "" to remove the kit
+	Components []KitComponentRequest `json:"components"`
+}
+
+// GetProductKit returns a kit with its bill of materials and availability
+func (pc *ProductController) GetProductKit(c *fiber.Ctx) error {
+	kit, err := pc.products.GetKit(c.UserContext(), paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to fetch kit")
+	}
+
+	setETag(c, kit.Product.Version)
+	return c.JSON(fiber.Map{"kit": kit})
+}
+
+// SetProductKit replaces the kit type and bill of materials of a product. It
+// requires the product's ETag in If-Match like UpdateProduct.
+func (pc *ProductController) SetProductKit(c *fiber.Ctx) error {
+	id := paramID(c)
+	version, err := ifMatch(c)
+	if err != nil {
+		return ifMatchError(c, err)
+	}
+
+	req := new(KitRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	components := make([]services.KitComponentInput, len(req.Components))
+	for i, component := range req.Components {
+		components[i] = services.KitComponentInput{ProductID: component.ProductID, Quantity: component.Quantity}
+	}
+
+	userID, _ := c.Locals("userID").(uint)
+	kit, err := pc.products.SetKit(c.UserContext(), userID, id, version, services.KitInput{
+		Type:       req.Type,
+		Components: components,
+	})
+	if err != nil {
+		if isStale(err) {
+			return pc.staleProduct(c, err, id)
+		}
+		return serviceError(c, err, "Failed to update kit")
+	}
+
+	setETag(c, kit.Product.Version)
+	return c.JSON(fiber.Map{
+		"message": "Kit updated successfully",
+		"kit":     kit,
+	})
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"inventory-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ProductUnitRequest struct {
+	Name   string  `json:"name"`
+	Factor float64 `json:"factor"` // Base units in one of this unit
+}
+
+type ProductUnitsRequest struct {
+	BaseUnit     string               `json:"base_unit"`
+	Fractional   bool                 `json:"fractional"`
+	PurchaseUnit string               `json:"purchase_unit"`
+	SalesUnit    string               `json:"sales_unit"`
+	Units        []ProductUnitRequest `json:"units"` // e.g. [{"name": "carton", "factor": 24}]
+}
+
+// GetProductUnits returns a product with its base unit and alternative units
+func (pc *ProductController) GetProductUnits(c *fiber.Ctx) error {
+	product, err := pc.products.GetUnits(c.UserContext(), paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to fetch units")
+	}
+
+	setETag(c, product.Version)
+	return c.JSON(fiber.Map{"product": product})
+}
+
+// SetProductUnits replaces the units of a product. It requires the product's
+// ETag in If-Match like UpdateProduct.
+func (pc *ProductController) SetProductUnits(c *fiber.Ctx) error {
+	id := paramID(c)
+	version, err := ifMatch(c)
+	if err != nil {
+		return ifMatchError(c, err)
+	}
+
+	req := new(ProductUnitsRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	units := make([]services.ProductUnitInput, len(req.Units))
+	for i, unit := range req.Units {
+		units[i] = services.ProductUnitInput{Name: unit.Name, Factor: unit.Factor}
+	}
+
+	userID, _ := c.Locals("userID").(uint)
+	product, err := pc.products.SetUnits(c.UserContext(), userID, id, version, services.ProductUnitsInput{
+		BaseUnit:     req.BaseUnit,
+		Fractional:   req.Fractional,
+		PurchaseUnit: req.PurchaseUnit,
+		SalesUnit:    req.SalesUnit,
+		Units:        units,
+	})
+	if err != nil {
+		if isStale(err) {
+			return pc.staleProduct(c, err, id)
+		}
+		return serviceError(c, err, "Failed to update units")
+	}
+
+	setETag(c, product.Version)
+	return c.JSON(fiber.Map{
+		"message": "Units updated successfully",
+		"product": product,
+	})
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"inventory-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type VariantAttributeRequest struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+type VariantRequest struct {
+	Attributes []VariantAttributeRequest `json:"attributes"` // e.g. [{"name": "Size", "values": ["S", "M"]}]
+}
+
+// GetProductVariants returns a product with its variant attributes and variants
+func (pc *ProductController) GetProductVariants(c *fiber.Ctx) error {
+	product, err := pc.products.GetVariants(c.UserContext(), paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to fetch variants")
+	}
+
+	setETag(c, product.Version)
+	return c.JSON(fiber.Map{"product": product})
+}
+
+// GenerateProductVariants defines the variant attributes of a product and
+// creates the missing variants. Sending more values for the same attributes
+// adds the new combinations.
+func (pc *ProductController) GenerateProductVariants(c *fiber.Ctx) error {
+	req := new(VariantRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	attributes := make([]services.VariantAttributeInput, len(req.Attributes))
+	for i, attribute := range req.Attributes {
+		attributes[i] = services.VariantAttributeInput{Name: attribute.Name, Values: attribute.Values}
+	}
+
+	userID, _ := c.Locals("userID").(uint)
+	result, err := pc.products.GenerateVariants(c.UserContext(), userID, paramID(c), attributes)
+	if err != nil {
+		return serviceError(c, err, "Failed to generate variants")
+	}
+
+	setETag(c, result.Parent.Version)
+	return c.Status(201).JSON(fiber.Map{
+		"message": "Variants generated successfully",
+		"product": result.Parent,
+		"created": result.Created,
+	})
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"inventory-backend/repositories"
+	"inventory-backend/services"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DraftOrdersRequest limits the conversion to some suppliers, all when empty
+type DraftOrdersRequest struct {
+	SupplierIDs []uint `json:"supplier_ids"`
+}
+
+type ReplenishmentController struct {
+	replenishment *services.ReplenishmentService
+}
+
+func NewReplenishmentController(replenishment *services.ReplenishmentService) *ReplenishmentController {
+	return &ReplenishmentController{replenishment: replenishment}
+}
+
+// GetSuggestions returns suggested order quantities grouped by supplier
+func (rc *ReplenishmentController) GetSuggestions(c *fiber.Ctx) error {
+	supplierID, _ := strconv.ParseUint(c.Query("supplier_id"), 10, 32)
+	plan, err := rc.replenishment.Suggest(c.UserContext(), uint(supplierID))
+	if err != nil {
+		return serviceError(c, err, "Failed to compute replenishment suggestions")
+	}
+
+	return c.JSON(plan)
+}
+
+// CreateDraftOrders converts the current suggestions into draft purchase orders
+func (rc *ReplenishmentController) CreateDraftOrders(c *fiber.Ctx) error {
+	req := new(DraftOrdersRequest)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+		}
+	}
+
+	userID, _ := c.Locals("userID").(uint)
+	orders, err := rc.replenishment.CreateDraftOrders(c.UserContext(), userID, req.SupplierIDs)
+	if err != nil {
+		return serviceError(c, err, "Failed to create purchase orders")
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"message":         "Draft purchase orders created successfully",
+		"purchase_orders": orders,
+	})
+}
+
+func (rc *ReplenishmentController) GetPurchaseOrders(c *fiber.Ctx) error {
+	supplierID, _ := strconv.ParseUint(c.Query("supplier_id"), 10, 32)
+	orders, err := rc.replenishment.ListOrders(c.UserContext(), repositories.PurchaseOrderFilter{
+		Status:     c.Query("status"),
+		SupplierID: uint(supplierID),
+		Limit:      c.QueryInt("limit", 50),
+	})
+	if err != nil {
+		return serviceError(c, err, "Failed to fetch purchase orders")
+	}
+
+	return c.JSON(fiber.Map{"purchase_orders": orders})
+}
+
+func (rc *ReplenishmentController) GetPurchaseOrder(c *fiber.Ctx) error {
+	order, err := rc.replenishment.GetOrder(c.UserContext(), paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to fetch purchase order")
+	}
+
+	return c.JSON(fiber.Map{"purchase_order": order})
+}
+
+func (rc *ReplenishmentController) CancelPurchaseOrder(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(uint)
+	order, err := rc.replenishment.CancelOrder(c.UserContext(), userID, paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to cancel purchase order")
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "Purchase order cancelled successfully",
+		"purchase_order": order,
+	})
+}
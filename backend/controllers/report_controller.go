@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"fmt"
+	"inventory-backend/repositories"
+	"inventory-backend/services"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReportController struct {
+	reports   *services.ReportService
+	snapshots *services.StockSnapshotService
+}
+
+func NewReportController(reports *services.ReportService, snapshots *services.StockSnapshotService) *ReportController {
+	return &ReportController{reports: reports, snapshots: snapshots}
+}
+
+// GetMovementReport aggregates stock movements between from and to (default
+// the last 30 days) by group_by and interval. Pass format=xlsx or format=pdf
+// to download the result instead of JSON.
+func (rc *ReportController) GetMovementReport(c *fiber.Ctx) error {
+	from, to, err := parseDateRange(c, 30)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	productID, _ := strconv.ParseUint(c.Query("product_id"), 10, 32)
+	categoryID, _ := strconv.ParseUint(c.Query("category_id"), 10, 32)
+	supplierID, _ := strconv.ParseUint(c.Query("supplier_id"), 10, 32)
+
+	result, err := rc.reports.Movements(c.UserContext(), repositories.MovementQuery{
+		From:       from,
+		To:         to,
+		GroupBy:    c.Query("group_by"),
+		Interval:   c.Query("interval"),
+		ProductID:  uint(productID),
+		CategoryID: uint(categoryID),
+		SupplierID: uint(supplierID),
+		Type:       c.Query("type"),
+	})
+	if err != nil {
+		return serviceError(c, err, "Failed to build movement report")
+	}
+
+	return sendReport(c, "stock_movements", result.Report(), result)
+}
+
+// GetStockAsOf returns the stock of every product at date. A plain date
+// (YYYY-MM-DD) means the end of that day, an RFC 3339 timestamp is used as is.
+func (rc *ReportController) GetStockAsOf(c *fiber.Ctx) error {
+	at, err := parsePointInTime(c.Query("date"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	categoryID, _ := strconv.ParseUint(c.Query("category_id"), 10, 32)
+	result, err := rc.reports.StockAsOf(c.UserContext(), at, uint(categoryID))
+	if err != nil {
+		return serviceError(c, err, "Failed to build stock report")
+	}
+
+	return sendReport(c, "stock_as_of", result.Report(), result)
+}
+
+// GetABCAnalysis classifies products into A/B/C by consumption value over
+// the last days days (default 90). a and b set the cumulative share limits of
+// classes A and B in percent (default 80 and 95).
+func (rc *ReportController) GetABCAnalysis(c *fiber.Ctx) error {
+	return rc.abcAnalysis(c, false)
+}
+
+// ApplyABCAnalysis runs the ABC analysis and stores the classes on the products
+func (rc *ReportController) ApplyABCAnalysis(c *fiber.Ctx) error {
+	return rc.abcAnalysis(c, true)
+}
+
+func (rc *ReportController) abcAnalysis(c *fiber.Ctx, apply bool) error {
+	params := services.ABCParams{
+		Days:   c.QueryInt("days"),
+		AShare: c.QueryFloat("a"),
+		BShare: c.QueryFloat("b"),
+	}
+
+	userID, _ := c.Locals("userID").(uint)
+	result, err := rc.reports.ABC(c.UserContext(), userID, params, apply)
+	if err != nil {
+		return serviceError(c, err, "Failed to build ABC analysis")
+	}
+
+	return sendReport(c, "abc_analysis", result.Report(), result)
+}
+
+// GetDeadStock lists products in stock without outbound movement in the last
+// days days (default 90)
+func (rc *ReportController) GetDeadStock(c *fiber.Ctx) error {
+	return rc.deadStock(c, false)
+}
+
+// ApplyDeadStock refreshes the dead stock flag on every product
+func (rc *ReportController) ApplyDeadStock(c *fiber.Ctx) error {
+	return rc.deadStock(c, true)
+}
+
+func (rc *ReportController) deadStock(c *fiber.Ctx, apply bool) error {
+	userID, _ := c.Locals("userID").(uint)
+	result, err := rc.reports.DeadStock(c.UserContext(), userID, c.QueryInt("days"), apply)
+	if err != nil {
+		return serviceError(c, err, "Failed to build dead stock report")
+	}
+
+	return sendReport(c, "dead_stock", result.Report(), result)
+}
+
+// CreateStockSnapshot stores a stock snapshot for date, e.g. to backfill
+// snapshots for older periods
+func (rc *ReportController) CreateStockSnapshot(c *fiber.Ctx) error {
+	at, err := parsePointInTime(c.Query("date"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	count, err := rc.snapshots.Snapshot(c.UserContext(), at)
+	if err != nil {
+		return serviceError(c, err, "Failed to create stock snapshot")
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"message":     "Stock snapshot created successfully",
+		"snapshot_at": at,
+		"products":    count,
+	})
+}
+
+// sendReport renders report when a file format is requested and otherwise
+// returns data as JSON
+func sendReport(c *fiber.Ctx, name string, report *services.Report, data interface{}) error {
+	format := c.Query("format", "json")
+	if format == "json" {
+		return c.JSON(data)
+	}
+
+	contentType, ok := services.ReportContentTypes[format]
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "Format must be 'json', 'xlsx' or 'pdf'"})
+	}
+
+	fileName := fmt.Sprintf("%s_%s.%s", name, time.Now().Format("20060102_150405"), format)
+	c.Set("Content-Type", contentType)
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+
+	return services.RenderReport(report, format, c.Response().BodyWriter())
+}
+
+// parseDateRange reads the from and to query parameters. Both accept a date
+// (2006-01-02) or an RFC 3339 timestamp; a plain to date includes that whole
+// day. Missing values default to the last defaultDays days.
+func parseDateRange(c *fiber.Ctx, defaultDays int) (time.Time, time.Time, error) {
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		t, dateOnly, err := parseTime(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("Invalid 'to' date, use YYYY-MM-DD or RFC 3339")
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		to = t
+	}
+
+	from := to.AddDate(0, 0, -defaultDays)
+	if value := c.Query("from"); value != "" {
+		t, _, err := parseTime(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("Invalid 'from' date, use YYYY-MM-DD or RFC 3339")
+		}
+		from = t
+	}
+
+	return from, to, nil
+}
+
+// parsePointInTime parses the date query parameter, defaulting to now. A plain
+// date means the end of that day.
+func parsePointInTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now(), nil
+	}
+
+	t, dateOnly, err := parseTime(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid date, use YYYY-MM-DD or RFC 3339")
+	}
+	if dateOnly {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// parseTime parses a date in local time or an RFC 3339 timestamp and reports
+// whether the value was a plain date
+func parseTime(value string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"inventory-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReportScheduleController struct {
+	schedules *services.ReportScheduleService
+}
+
+func NewReportScheduleController(schedules *services.ReportScheduleService) *ReportScheduleController {
+	return &ReportScheduleController{schedules: schedules}
+}
+
+// GetReportSchedules lists all schedules with their last run status
+func (rc *ReportScheduleController) GetReportSchedules(c *fiber.Ctx) error {
+	schedules, err := rc.schedules.List(c.UserContext())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch report schedules"})
+	}
+
+	return c.JSON(fiber.Map{"schedules": schedules})
+}
+
+func (rc *ReportScheduleController) GetReportSchedule(c *fiber.Ctx) error {
+	schedule, err := rc.schedules.Get(c.UserContext(), paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to fetch report schedule")
+	}
+
+	return c.JSON(fiber.Map{"schedule": schedule})
+}
+
+func (rc *ReportScheduleController) CreateReportSchedule(c *fiber.Ctx) error {
+	req := new(services.ReportScheduleInput)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	userID, _ := c.Locals("userID").(uint)
+	schedule, err := rc.schedules.Create(c.UserContext(), userID, *req)
+	if err != nil {
+		return serviceError(c, err, "Failed to create report schedule")
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"message":  "Report schedule created successfully",
+		"schedule": schedule,
+	})
+}
+
+func (rc *ReportScheduleController) UpdateReportSchedule(c *fiber.Ctx) error {
+	req := new(services.ReportScheduleInput)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	schedule, err := rc.schedules.Update(c.UserContext(), paramID(c), *req)
+	if err != nil {
+		return serviceError(c, err, "Failed to update report schedule")
+	}
+
+	return c.JSON(fiber.Map{
+		"message":  "Report schedule updated successfully",
+		"schedule": schedule,
+	})
+}
+
+func (rc *ReportScheduleController) DeleteReportSchedule(c *fiber.Ctx) error {
+	if err := rc.schedules.Delete(c.UserContext(), paramID(c)); err != nil {
+		return serviceError(c, err, "Failed to delete report schedule")
+	}
+
+	return c.JSON(fiber.Map{"message": "Report schedule deleted successfully"})
+}
+
+// RunReportSchedule runs a schedule immediately and returns its run status
+func (rc *ReportScheduleController) RunReportSchedule(c *fiber.Ctx) error {
+	schedule, err := rc.schedules.RunNow(c.UserContext(), paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to run report schedule")
+	}
+
+	return c.JSON(fiber.Map{
+		"message":  "Report schedule executed",
+		"schedule": schedule,
+	})
+}
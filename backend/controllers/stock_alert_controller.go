@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"inventory-backend/repositories"
+	"inventory-backend/services"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AlertChannelRequest struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`   // webhook, email
+	Target  string   `json:"target"` // URL or comma separated email addresses
+	Events  []string `json:"events"` // stock.low, stock.recovered; empty for all
+	Enabled *bool    `json:"enabled"`
+}
+
+func (req *AlertChannelRequest) input() services.AlertChannelInput {
+	return services.AlertChannelInput{
+		Name:    req.Name,
+		Type:    req.Type,
+		Target:  req.Target,
+		Events:  req.Events,
+		Enabled: req.Enabled,
+	}
+}
+
+type StockAlertController struct {
+	alerts *services.StockAlertService
+}
+
+func NewStockAlertController(alerts *services.StockAlertService) *StockAlertController {
+	return &StockAlertController{alerts: alerts}
+}
+
+// GetAlerts lists stock alerts, filtered by status and product_id
+func (ac *StockAlertController) GetAlerts(c *fiber.Ctx) error {
+	productID, _ := strconv.ParseUint(c.Query("product_id"), 10, 32)
+	alerts, err := ac.alerts.ListAlerts(c.UserContext(), repositories.StockAlertFilter{
+		Status:    c.Query("status"),
+		ProductID: uint(productID),
+		Limit:     c.QueryInt("limit", 50),
+	})
+	if err != nil {
+		return serviceError(c, err, "Failed to fetch alerts")
+	}
+
+	return c.JSON(fiber.Map{"alerts": alerts})
+}
+
+// GetAlert returns an alert with its notification history
+func (ac *StockAlertController) GetAlert(c *fiber.Ctx) error {
+	alert, err := ac.alerts.GetAlert(c.UserContext(), paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to fetch alert")
+	}
+
+	return c.JSON(fiber.Map{"alert": alert})
+}
+
+func (ac *StockAlertController) AcknowledgeAlert(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(uint)
+	alert, err := ac.alerts.Acknowledge(c.UserContext(), userID, paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to acknowledge alert")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Alert acknowledged",
+		"alert":   alert,
+	})
+}
+
+func (ac *StockAlertController) GetAlertChannels(c *fiber.Ctx) error {
+	channels, err := ac.alerts.ListChannels(c.UserContext())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch alert channels"})
+	}
+
+	return c.JSON(fiber.Map{"channels": channels})
+}
+
+func (ac *StockAlertController) CreateAlertChannel(c *fiber.Ctx) error {
+	req := new(AlertChannelRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	channel, err := ac.alerts.CreateChannel(c.UserContext(), req.input())
+	if err != nil {
+		return serviceError(c, err, "Failed to create alert channel")
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"message": "Alert channel created successfully",
+		"channel": channel,
+	})
+}
+
+func (ac *StockAlertController) UpdateAlertChannel(c *fiber.Ctx) error {
+	req := new(AlertChannelRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	channel, err := ac.alerts.UpdateChannel(c.UserContext(), paramID(c), req.input())
+	if err != nil {
+		return serviceError(c, err, "Failed to update alert channel")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Alert channel updated successfully",
+		"channel": channel,
+	})
+}
+
+func (ac *StockAlertController) DeleteAlertChannel(c *fiber.Ctx) error {
+	if err := ac.alerts.DeleteChannel(c.UserContext(), paramID(c)); err != nil {
+		return serviceError(c, err, "Failed to delete alert channel")
+	}
+
+	return c.JSON(fiber.Map{"message": "Alert channel deleted successfully"})
+}
+
+// TestAlertChannel sends a test notification to the channel right away
+func (ac *StockAlertController) TestAlertChannel(c *fiber.Ctx) error {
+	if err := ac.alerts.TestChannel(c.UserContext(), paramID(c)); err != nil {
+		return serviceError(c, err, "Failed to send test notification")
+	}
+
+	return c.JSON(fiber.Map{"message": "Test notification sent"})
+}
@@ -2,108 +2,141 @@ package controllers
 
 import (
 	"fmt"
-	"inventory-backend/config"
-	"inventory-backend/models"
-	"inventory-backend/utils"
+	"inventory-backend/services"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type StockUpdateRequest struct {
-	Type     string `json:"type"`     // "in" atau "out"
-	Quantity int    `json:"quantity"` // jumlah
-	Note     string `json:"note"`     // catatan
+	Type     string  `json:"type"`     // "in" atau "out"
+	Quantity float64 `json:"quantity"` // jumlah
+	Unit     string  `json:"unit"`     // satuan, purchase or sales unit when empty
+	Note     string  `json:"note"`     // catatan
 }
 
-// Update Stock (Stock In / Out)
-func UpdateStock(c *fiber.Ctx) error {
-	productID := c.Params("id")
+type KitOperationRequest struct {
+	Quantity float64 `json:"quantity"` // kits to assemble or disassemble
+	Note     string  `json:"note"`
+}
 
-	var product models.Product
-	if err := config.DB.First(&product, productID).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Product not found"})
-	}
+type StockController struct {
+	stock      *services.StockService
+	reconciler *services.ReconciliationService
+}
+
+func NewStockController(stock *services.StockService, reconciler *services.ReconciliationService) *StockController {
+	return &StockController{stock: stock, reconciler: reconciler}
+}
 
+// Update Stock (Stock In / Out)
+func (sc *StockController) UpdateStock(c *fiber.Ctx) error {
 	req := new(StockUpdateRequest)
 	if err := c.BodyParser(req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	// Validasi
-	if req.Type == "" || req.Quantity <= 0 {
-		return c.Status(400).JSON(fiber.Map{"error": "Type and quantity are required"})
+	userID, _ := c.Locals("userID").(uint)
+	result, err := sc.stock.Move(c.UserContext(), userID, paramID(c), services.StockMovementInput{
+		Type:     req.Type,
+		Quantity: req.Quantity,
+		Unit:     req.Unit,
+		Note:     req.Note,
+	})
+	if err != nil {
+		return serviceError(c, err, "Failed to update stock")
 	}
 
-	if req.Type != "in" && req.Type != "out" {
-		return c.Status(400).JSON(fiber.Map{"error": "Type must be 'in' or 'out'"})
+	response := fiber.Map{
+		"message":      "Stock updated successfully",
+		"stock_before": result.StockBefore,
+		"stock_after":  result.StockAfter,
+		"product":      result.Product,
 	}
-
-	stockBefore := product.Stock
-	var stockAfter int
-
-	if req.Type == "in" {
-		stockAfter = stockBefore + req.Quantity
-	} else {
-		if stockBefore < req.Quantity {
-			return c.Status(400).JSON(fiber.Map{"error": "Insufficient stock"})
-		}
-		stockAfter = stockBefore - req.Quantity
+	// A virtual kit moves its components
+	if len(result.Movements) > 0 {
+		response["movements"] = result.Movements
 	}
+	return c.JSON(response)
+}
 
-	// Update product stock
-	product.Stock = stockAfter
-	if err := config.DB.Save(&product).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to update stock"})
+// AssembleKit builds assembled kits from their components
+func (sc *StockController) AssembleKit(c *fiber.Ctx) error {
+	req := new(KitOperationRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	// Create stock history
-	history := models.StockHistory{
-		ProductID:   product.ID,
-		Type:        req.Type,
-		Quantity:    req.Quantity,
-		Note:        req.Note,
-		StockBefore: stockBefore,
-		StockAfter:  stockAfter,
+	userID, _ := c.Locals("userID").(uint)
+	operation, err := sc.stock.Assemble(c.UserContext(), userID, paramID(c), req.Quantity, req.Note)
+	if err != nil {
+		return serviceError(c, err, "Failed to assemble kit")
 	}
 
-	if err := config.DB.Create(&history).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to create stock history"})
+	return c.JSON(fiber.Map{
+		"message":   "Kit assembled successfully",
+		"operation": operation,
+	})
+}
+
+// DisassembleKit breaks assembled kits up into their components
+func (sc *StockController) DisassembleKit(c *fiber.Ctx) error {
+	req := new(KitOperationRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	// Log Activity
 	userID, _ := c.Locals("userID").(uint)
-	utils.LogActivity(userID, "UPDATE", "Product", product.ID, fmt.Sprintf("Updated stock for %s (%s): %s %d (New Stock: %d)", product.Name, product.SKU, req.Type, req.Quantity, stockAfter))
+	operation, err := sc.stock.Disassemble(c.UserContext(), userID, paramID(c), req.Quantity, req.Note)
+	if err != nil {
+		return serviceError(c, err, "Failed to disassemble kit")
+	}
 
 	return c.JSON(fiber.Map{
-		"message":      "Stock updated successfully",
-		"stock_before": stockBefore,
-		"stock_after":  stockAfter,
-		"product":      product,
+		"message":   "Kit disassembled successfully",
+		"operation": operation,
 	})
 }
 
 // Get Stock History
-func GetStockHistory(c *fiber.Ctx) error {
-	productID := c.Params("id")
-
-	var product models.Product
-	if err := config.DB.First(&product, productID).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Product not found"})
-	}
-
-	var history []models.StockHistory
-	if err := config.DB.Where("product_id = ?", productID).
-		Order("created_at DESC").
-		Find(&history).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch stock history"})
+func (sc *StockController) GetStockHistory(c *fiber.Ctx) error {
+	product, history, err := sc.stock.History(c.UserContext(), paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to fetch stock history")
 	}
 
 	return c.JSON(fiber.Map{
 		"product": fiber.Map{
-			"id":    product.ID,
-			"name":  product.Name,
-			"stock": product.Stock,
+			"id":        product.ID,
+			"name":      product.Name,
+			"stock":     product.Stock,
+			"base_unit": product.BaseUnit,
 		},
 		"history": history,
 	})
 }
+
+// GetReconciliation replays the stock ledger and lists products whose stock
+// does not match it
+func (sc *StockController) GetReconciliation(c *fiber.Ctx) error {
+	report, err := sc.reconciler.Reconcile(c.UserContext(), 0, false)
+	if err != nil {
+		return serviceError(c, err, "Failed to reconcile stock")
+	}
+
+	return c.JSON(report)
+}
+
+// ApplyReconciliation posts corrective movements so the ledger of every
+// mismatched product matches its current stock
+func (sc *StockController) ApplyReconciliation(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(uint)
+	report, err := sc.reconciler.Reconcile(c.UserContext(), userID, true)
+	if err != nil {
+		return serviceError(c, err, "Failed to reconcile stock")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": fmt.Sprintf("Posted %d corrective adjustments", report.Corrected),
+		"report":  report,
+	})
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"bufio"
+	"fmt"
+	"inventory-backend/services"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type StreamController struct {
+	hub *services.StreamHub
+}
+
+func NewStreamController(hub *services.StreamHub) *StreamController {
+	return &StreamController{hub: hub}
+}
+
+// Stream pushes inventory events to the client as Server-Sent Events until it
+// disconnects. ?events=stock.changed,product.updated limits the event types
+// and ?product_id=1,2 the products. A heartbeat event is sent when nothing
+// happened for a while so clients can tell a quiet stream from a dead one.
+func (sc *StreamController) Stream(c *fiber.Ctx) error {
+	filter := services.StreamFilter{}
+	for _, event := range strings.Split(c.Query("events"), ",") {
+		if event = strings.TrimSpace(event); event != "" {
+			filter.Events = append(filter.Events, event)
+		}
+	}
+	for _, value := range strings.Split(c.Query("product_id"), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || id == 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "product_id must be a comma separated list of product IDs"})
+		}
+		filter.ProductIDs = append(filter.ProductIDs, uint(id))
+	}
+
+	sub, err := sc.hub.Subscribe(filter)
+	if err != nil {
+		return serviceError(c, err, "Failed to open stream")
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // Disable proxy buffering
+
+	heartbeat := sc.hub.Heartbeat
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sc.hub.Unsubscribe(sub)
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		fmt.Fprintf(w, "retry: 3000\nevent: ready\ndata: {\"heartbeat_seconds\":%d}\n\n", int(heartbeat.Seconds()))
+		if w.Flush() != nil {
+			return
+		}
+
+		for {
+			select {
+			case msg, ok := <-sub.Events:
+				if !ok {
+					return // Fell behind, the client reconnects
+				}
+				fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", msg.EventID, msg.EventType, msg.Payload)
+			case now := <-ticker.C:
+				fmt.Fprintf(w, "event: heartbeat\ndata: {\"time\":%q}\n\n", now.Format(time.RFC3339))
+			}
+			// Flush fails once the client is gone
+			if w.Flush() != nil {
+				return
+			}
+			ticker.Reset(heartbeat)
+		}
+	})
+
+	return nil
+}
@@ -1,24 +1,44 @@
 package controllers
 
 import (
-	"inventory-backend/config"
-	"inventory-backend/models"
+	"inventory-backend/services"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type SupplierRequest struct {
-	Name        string `json:"name"`
-	ContactName string `json:"contact_name"`
-	Phone       string `json:"phone"`
-	Email       string `json:"email"`
-	Address     string `json:"address"`
+	Name         string   `json:"name"`
+	ContactName  string   `json:"contact_name"`
+	Phone        string   `json:"phone"`
+	Email        string   `json:"email"`
+	Address      string   `json:"address"`
+	LeadTimeDays int      `json:"lead_time_days"`
+	OrderCost    *float64 `json:"order_cost"`
 }
 
-func GetSuppliers(c *fiber.Ctx) error {
-	var suppliers []models.Supplier
-	
-	if err := config.DB.Find(&suppliers).Error; err != nil {
+type SupplierController struct {
+	suppliers *services.SupplierService
+}
+
+func NewSupplierController(suppliers *services.SupplierService) *SupplierController {
+	return &SupplierController{suppliers: suppliers}
+}
+
+func (req *SupplierRequest) input() services.SupplierInput {
+	return services.SupplierInput{
+		Name:         req.Name,
+		ContactName:  req.ContactName,
+		Phone:        req.Phone,
+		Email:        req.Email,
+		Address:      req.Address,
+		LeadTimeDays: req.LeadTimeDays,
+		OrderCost:    req.OrderCost,
+	}
+}
+
+func (sc *SupplierController) GetSuppliers(c *fiber.Ctx) error {
+	suppliers, err := sc.suppliers.List(c.UserContext())
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch suppliers"})
 	}
 
@@ -27,39 +47,27 @@ func GetSuppliers(c *fiber.Ctx) error {
 	})
 }
 
-func GetSupplier(c *fiber.Ctx) error {
-	id := c.Params("id")
-
-	var supplier models.Supplier
-	if err := config.DB.Preload("Products").First(&supplier, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Supplier not found"})
+func (sc *SupplierController) GetSupplier(c *fiber.Ctx) error {
+	supplier, err := sc.suppliers.Get(c.UserContext(), paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to fetch supplier")
 	}
 
+	setETag(c, supplier.Version)
 	return c.JSON(fiber.Map{
 		"supplier": supplier,
 	})
 }
 
-func CreateSupplier(c *fiber.Ctx) error {
+func (sc *SupplierController) CreateSupplier(c *fiber.Ctx) error {
 	req := new(SupplierRequest)
 	if err := c.BodyParser(req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	if req.Name == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "Name is required"})
-	}
-
-	supplier := models.Supplier{
-		Name:        req.Name,
-		ContactName: req.ContactName,
-		Phone:       req.Phone,
-		Email:       req.Email,
-		Address:     req.Address,
-	}
-
-	if err := config.DB.Create(&supplier).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to create supplier"})
+	supplier, err := sc.suppliers.Create(c.UserContext(), req.input())
+	if err != nil {
+		return serviceError(c, err, "Failed to create supplier")
 	}
 
 	return c.Status(201).JSON(fiber.Map{
@@ -68,12 +76,12 @@ func CreateSupplier(c *fiber.Ctx) error {
 	})
 }
 
-func UpdateSupplier(c *fiber.Ctx) error {
-	id := c.Params("id")
-
-	var supplier models.Supplier
-	if err := config.DB.First(&supplier, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Supplier not found"})
+// UpdateSupplier requires the supplier's ETag in If-Match so concurrent edits
+// are not overwritten
+func (sc *SupplierController) UpdateSupplier(c *fiber.Ctx) error {
+	version, err := ifMatch(c)
+	if err != nil {
+		return ifMatchError(c, err)
 	}
 
 	req := new(SupplierRequest)
@@ -81,54 +89,44 @@ func UpdateSupplier(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	if req.Name != "" {
-		supplier.Name = req.Name
-	}
-	if req.ContactName != "" {
-		supplier.ContactName = req.ContactName
-	}
-	if req.Phone != "" {
-		supplier.Phone = req.Phone
-	}
-	if req.Email != "" {
-		supplier.Email = req.Email
-	}
-	if req.Address != "" {
-		supplier.Address = req.Address
-	}
-
-	if err := config.DB.Save(&supplier).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to update supplier"})
+	supplier, err := sc.suppliers.Update(c.UserContext(), paramID(c), version, req.input())
+	if err != nil {
+		if isStale(err) {
+			return sc.staleSupplier(c, err, paramID(c))
+		}
+		return serviceError(c, err, "Failed to update supplier")
 	}
 
+	setETag(c, supplier.Version)
 	return c.JSON(fiber.Map{
 		"message":  "Supplier updated successfully",
 		"supplier": supplier,
 	})
 }
 
-func DeleteSupplier(c *fiber.Ctx) error {
-	id := c.Params("id")
-
-	var supplier models.Supplier
-	if err := config.DB.First(&supplier, id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Supplier not found"})
+func (sc *SupplierController) DeleteSupplier(c *fiber.Ctx) error {
+	version, err := ifMatch(c)
+	if err != nil {
+		return ifMatchError(c, err)
 	}
 
-	// Check if supplier has products
-	var productCount int64
-	config.DB.Model(&models.Product{}).Where("supplier_id = ?", id).Count(&productCount)
-	if productCount > 0 {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Cannot delete supplier with existing products",
-		})
-	}
-
-	if err := config.DB.Delete(&supplier).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to delete supplier"})
+	if err := sc.suppliers.Delete(c.UserContext(), paramID(c), version); err != nil {
+		if isStale(err) {
+			return sc.staleSupplier(c, err, paramID(c))
+		}
+		return serviceError(c, err, "Failed to delete supplier")
 	}
 
 	return c.JSON(fiber.Map{
 		"message": "Supplier deleted successfully",
 	})
-}
\ No newline at end of file
+}
+
+// staleSupplier answers a failed version check with the current supplier
+func (sc *SupplierController) staleSupplier(c *fiber.Ctx, err error, id uint) error {
+	current, findErr := sc.suppliers.Get(c.UserContext(), id)
+	if findErr != nil {
+		return staleVersion(c, err, "supplier", nil, 0)
+	}
+	return staleVersion(c, err, "supplier", current, current.Version)
+}
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"inventory-backend/repositories"
+	"inventory-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WebhookRequest struct {
+	Name    string   `json:"name"`
+	URL     string   `json:"url"`
+	Events  []string `json:"events"` // e.g. product.created, stock.changed or * for all
+	Enabled *bool    `json:"enabled"`
+}
+
+func (req *WebhookRequest) input() services.WebhookInput {
+	return services.WebhookInput{
+		Name:    req.Name,
+		URL:     req.URL,
+		Events:  req.Events,
+		Enabled: req.Enabled,
+	}
+}
+
+type WebhookController struct {
+	webhooks *services.WebhookService
+}
+
+func NewWebhookController(webhooks *services.WebhookService) *WebhookController {
+	return &WebhookController{webhooks: webhooks}
+}
+
+func (wc *WebhookController) GetWebhooks(c *fiber.Ctx) error {
+	subscriptions, err := wc.webhooks.List(c.UserContext())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch webhooks"})
+	}
+
+	return c.JSON(fiber.Map{
+		"webhooks":    subscriptions,
+		"event_types": services.EventTypes,
+	})
+}
+
+func (wc *WebhookController) GetWebhook(c *fiber.Ctx) error {
+	subscription, err := wc.webhooks.Get(c.UserContext(), paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to fetch webhook")
+	}
+
+	return c.JSON(fiber.Map{"webhook": subscription})
+}
+
+// CreateWebhook adds a subscription. The signing secret is only returned here
+// and by RotateWebhookSecret.
+func (wc *WebhookController) CreateWebhook(c *fiber.Ctx) error {
+	req := new(WebhookRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	userID, _ := c.Locals("userID").(uint)
+	subscription, err := wc.webhooks.Create(c.UserContext(), userID, req.input())
+	if err != nil {
+		return serviceError(c, err, "Failed to create webhook")
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"message": "Webhook created successfully",
+		"webhook": subscription,
+		"secret":  subscription.Secret,
+	})
+}
+
+func (wc *WebhookController) UpdateWebhook(c *fiber.Ctx) error {
+	req := new(WebhookRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	subscription, err := wc.webhooks.Update(c.UserContext(), paramID(c), req.input())
+	if err != nil {
+		return serviceError(c, err, "Failed to update webhook")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Webhook updated successfully",
+		"webhook": subscription,
+	})
+}
+
+func (wc *WebhookController) RotateWebhookSecret(c *fiber.Ctx) error {
+	subscription, err := wc.webhooks.RotateSecret(c.UserContext(), paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to rotate webhook secret")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Webhook secret rotated successfully",
+		"webhook": subscription,
+		"secret":  subscription.Secret,
+	})
+}
+
+func (wc *WebhookController) DeleteWebhook(c *fiber.Ctx) error {
+	if err := wc.webhooks.Delete(c.UserContext(), paramID(c)); err != nil {
+		return serviceError(c, err, "Failed to delete webhook")
+	}
+
+	return c.JSON(fiber.Map{"message": "Webhook deleted successfully"})
+}
+
+// TestWebhook sends a webhook.test event immediately and returns the delivery
+// with the receiver's response
+func (wc *WebhookController) TestWebhook(c *fiber.Ctx) error {
+	delivery, err := wc.webhooks.Test(c.UserContext(), paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to send test event")
+	}
+
+	return c.JSON(fiber.Map{"delivery": delivery})
+}
+
+// GetWebhookDeliveries returns the delivery log of a subscription, filtered by
+// status and event
+func (wc *WebhookController) GetWebhookDeliveries(c *fiber.Ctx) error {
+	deliveries, err := wc.webhooks.ListDeliveries(c.UserContext(), repositories.WebhookDeliveryFilter{
+		SubscriptionID: paramID(c),
+		Status:         c.Query("status"),
+		Event:          c.Query("event"),
+		Limit:          c.QueryInt("limit", 50),
+	})
+	if err != nil {
+		return serviceError(c, err, "Failed to fetch webhook deliveries")
+	}
+
+	return c.JSON(fiber.Map{"deliveries": deliveries})
+}
+
+func (wc *WebhookController) GetWebhookDelivery(c *fiber.Ctx) error {
+	delivery, err := wc.webhooks.GetDelivery(c.UserContext(), paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to fetch webhook delivery")
+	}
+
+	return c.JSON(fiber.Map{"delivery": delivery})
+}
+
+func (wc *WebhookController) RetryWebhookDelivery(c *fiber.Ctx) error {
+	delivery, err := wc.webhooks.Retry(c.UserContext(), paramID(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to retry webhook delivery")
+	}
+
+	return c.JSON(fiber.Map{
+		"message":  "Delivery queued for retry",
+		"delivery": delivery,
+	})
+}
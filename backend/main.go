@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"inventory-backend/config"
 	"inventory-backend/models"
+	"inventory-backend/repositories"
 	"inventory-backend/routes"
+	"inventory-backend/services"
 	"inventory-backend/utils"
 	"log"
 	"os"
@@ -20,7 +23,7 @@ func seedCategories() {
 
 	for _, name := range categories {
 		var count int64
-		config.DB.Model(&models.Category{}).Where("name = ?", name).Count(&count)
+		config.DB.Model(&models.Category{}).Where("name = ? AND parent_id IS NULL", name).Count(&count)
 		if count == 0 {
 			config.DB.Create(&models.Category{Name: name, Description: "Default category"})
 			fmt.Printf("Created category: %s\n", name)
@@ -42,9 +45,29 @@ func main() {
 		&models.User{},
 		&models.Supplier{},
 		&models.Product{},
+		&models.VariantAttribute{},
+		&models.VariantOption{},
+		&models.ProductUnit{},
+		&models.BillOfMaterials{},
 		&models.StockHistory{},
 		&models.ActivityLog{},
 		&models.Category{},
+		&models.AttributeDefinition{},
+		&models.ProductAttribute{},
+		&models.ProductSearch{},
+		&models.ExportJob{},
+		&models.ReportSchedule{},
+		&models.StockSnapshot{},
+		&models.ReorderProposal{},
+		&models.PurchaseOrder{},
+		&models.PurchaseOrderItem{},
+		&models.StockAlert{},
+		&models.AlertChannel{},
+		&models.AlertDelivery{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.OutboxEvent{},
+		&models.IdempotencyKey{},
 	); err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
@@ -78,15 +101,20 @@ func main() {
 	// Middleware
 	app.Use(logger.New())
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
+		AllowOrigins:  "*",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, Idempotency-Key, If-Match",
+		ExposeHeaders: "ETag, Idempotent-Replayed",
 	}))
 
 	// Serve static files (uploads)
 	app.Static("/uploads", uploadPath)
 
+	// Wire services
+	svc := services.NewServices(repositories.NewStore(config.DB))
+	svc.Start(context.Background())
+
 	// Setup routes
-	routes.SetupRoutes(app)
+	routes.SetupRoutes(app, svc)
 
 	// Health check
 	app.Get("/", func(c *fiber.Ctx) error {
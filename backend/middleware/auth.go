@@ -59,3 +59,12 @@ func AdminOnly(c *fiber.Ctx) error {
 
 	return c.Next()
 }
+
+// TokenFromQuery accepts the JWT as ?token= for clients that cannot set
+// headers, such as the browser EventSource. It must run before AuthRequired.
+func TokenFromQuery(c *fiber.Ctx) error {
+	if token := c.Query("token"); token != "" && c.Get("Authorization") == "" {
+		c.Request().Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.Next()
+}
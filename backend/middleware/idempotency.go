@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"inventory-backend/services"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// IdempotencyKeyHeader is the request header holding the client chosen key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Idempotency honours the Idempotency-Key header on POST, PUT, PATCH and
+// DELETE requests. The first response to a key is stored for the user, an
+// identical retry gets the stored response and ETag with an
+// Idempotent-Replayed header, and reusing the key for a different request is
+// rejected with 422. Server errors are not stored so the request can be
+// retried. It must run after AuthRequired.
+func Idempotency(idempotency *services.IdempotencyService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		if key == "" {
+			return c.Next()
+		}
+		switch c.Method() {
+		case fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete:
+		default:
+			return c.Next()
+		}
+		if len(key) > 255 {
+			return c.Status(400).JSON(fiber.Map{"error": "Idempotency-Key must be at most 255 characters"})
+		}
+
+		body, err := requestFingerprint(c)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+		}
+
+		userID, _ := c.Locals("userID").(uint)
+		record, replay, err := idempotency.Begin(c.UserContext(), services.IdempotencyRequest{
+			UserID: userID,
+			Key:    key,
+			Method: c.Method(),
+			Path:   c.Path(),
+			URL:    c.OriginalURL(),
+			Body:   body,
+		})
+		switch {
+		case errors.Is(err, services.ErrIdempotencyKeyReused):
+			return c.Status(422).JSON(fiber.Map{"error": "Idempotency-Key was already used for a different request"})
+		case errors.Is(err, services.ErrIdempotencyKeyInFlight):
+			return c.Status(409).JSON(fiber.Map{"error": "A request with this Idempotency-Key is still in progress"})
+		case err != nil:
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to check Idempotency-Key"})
+		case replay:
+			c.Set("Idempotent-Replayed", "true")
+			if record.ContentType != "" {
+				c.Set(fiber.HeaderContentType, record.ContentType)
+			}
+			if record.ETag != "" {
+				c.Set(fiber.HeaderETag, record.ETag)
+			}
+			return c.Status(record.ResponseStatus).SendString(record.ResponseBody)
+		}
+
+		err = c.Next()
+
+		// Handler errors are rendered by the app error handler as a 500 later on
+		status := c.Response().StatusCode()
+		if err != nil || status >= 500 || c.Response().IsBodyStream() {
+			if releaseErr := idempotency.Release(c.UserContext(), record); releaseErr != nil {
+				log.Printf("Failed to release idempotency key %d: %v", record.ID, releaseErr)
+			}
+			return err
+		}
+
+		if err := idempotency.Complete(c.UserContext(), record, status, string(c.Response().Header.ContentType()), c.GetRespHeader(fiber.HeaderETag), c.Response().Body()); err != nil {
+			log.Printf("Failed to store response for idempotency key %d: %v", record.ID, err)
+		}
+		return nil
+	}
+}
+
+// requestFingerprint returns the bytes that identify the request body.
+// Multipart forms are reduced to their fields and file contents because
+// clients pick a new boundary on every retry.
+func requestFingerprint(c *fiber.Ctx) ([]byte, error) {
+	if !strings.HasPrefix(string(c.Request().Header.ContentType()), fiber.MIMEMultipartForm) {
+		return c.Body(), nil
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	names := make([]string, 0, len(form.Value))
+	for name := range form.Value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(h, "%s=%q\n", name, form.Value[name])
+	}
+
+	names = names[:0]
+	for name := range form.File {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, header := range form.File[name] {
+			fmt.Fprintf(h, "%s:%s:%d\n", name, header.Filename, header.Size)
+			f, err := header.Open()
+			if err != nil {
+				return nil, err
+			}
+			_, err = io.Copy(h, f)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return h.Sum(nil), nil
+}
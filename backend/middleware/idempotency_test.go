@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"inventory-backend/services"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newIdempotencyApp serves POST /items behind the middleware on a private
+// in-memory database. Every handled request bumps calls and answers with an
+// ETag, or fails with 500 when failing is set.
+func newIdempotencyApp(t *testing.T) (*fiber.App, *services.IdempotencyService, *int, *bool) {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.IdempotencyKey{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	idempotency := services.NewIdempotencyService(repositories.NewStore(db), 0)
+	calls, failing := new(int), new(bool)
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", uint(1))
+		return c.Next()
+	})
+	app.Use(Idempotency(idempotency))
+	app.Post("/items", func(c *fiber.Ctx) error {
+		*calls++
+		if *failing {
+			return c.Status(500).JSON(fiber.Map{"error": "boom"})
+		}
+		c.Set(fiber.HeaderETag, fmt.Sprintf(`"%d"`, *calls))
+		return c.Status(201).JSON(fiber.Map{"call": *calls})
+	})
+	return app, idempotency, calls, failing
+}
+
+func postItem(t *testing.T, app *fiber.App, key, body string) (int, string, map[string]string) {
+	t.Helper()
+
+	req := httptest.NewRequest(fiber.MethodPost, "/items", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	req.Header.Set(IdempotencyKeyHeader, key)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	headers := map[string]string{
+		"ETag":                resp.Header.Get(fiber.HeaderETag),
+		"Idempotent-Replayed": resp.Header.Get("Idempotent-Replayed"),
+	}
+	return resp.StatusCode, string(data), headers
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	app, _, calls, _ := newIdempotencyApp(t)
+
+	status, body, headers := postItem(t, app, "k1", `{"name":"a"}`)
+	if status != 201 || headers["ETag"] == "" || headers["Idempotent-Replayed"] != "" {
+		t.Fatalf("first request: %d %s %v", status, body, headers)
+	}
+
+	replayStatus, replayBody, replayHeaders := postItem(t, app, "k1", `{"name":"a"}`)
+	if *calls != 1 {
+		t.Errorf("handler ran %d times, want once", *calls)
+	}
+	if replayStatus != status || replayBody != body {
+		t.Errorf("replay = %d %s, want %d %s", replayStatus, replayBody, status, body)
+	}
+	if replayHeaders["Idempotent-Replayed"] != "true" || replayHeaders["ETag"] != headers["ETag"] {
+		t.Errorf("replay headers = %v, want the stored ETag %s", replayHeaders, headers["ETag"])
+	}
+}
+
+func TestIdempotencyRejectsReusedKey(t *testing.T) {
+	app, _, calls, _ := newIdempotencyApp(t)
+
+	postItem(t, app, "k1", `{"name":"a"}`)
+	if status, _, _ := postItem(t, app, "k1", `{"name":"b"}`); status != 422 {
+		t.Errorf("key reused for another body: status %d, want 422", status)
+	}
+	if *calls != 1 {
+		t.Errorf("handler ran %d times, want once", *calls)
+	}
+}
+
+func TestIdempotencyRejectsKeyInFlight(t *testing.T) {
+	app, idempotency, calls, _ := newIdempotencyApp(t)
+
+	// The first request holds the key without a response yet
+	_, _, err := idempotency.Begin(context.Background(), services.IdempotencyRequest{
+		UserID: 1, Key: "k1", Method: fiber.MethodPost, Path: "/items", URL: "/items", Body: []byte(`{"name":"a"}`),
+	})
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+
+	if status, _, _ := postItem(t, app, "k1", `{"name":"a"}`); status != 409 {
+		t.Errorf("key in flight: status %d, want 409", status)
+	}
+	if *calls != 0 {
+		t.Errorf("handler ran %d times, want never", *calls)
+	}
+}
+
+func TestIdempotencyReleasesKeyOnServerError(t *testing.T) {
+	app, _, calls, failing := newIdempotencyApp(t)
+
+	*failing = true
+	if status, _, _ := postItem(t, app, "k1", `{"name":"a"}`); status != 500 {
+		t.Fatalf("failing request: status %d, want 500", status)
+	}
+
+	*failing = false
+	status, _, headers := postItem(t, app, "k1", `{"name":"a"}`)
+	if status != 201 || headers["Idempotent-Replayed"] != "" || *calls != 2 {
+		t.Errorf("retry after a server error: status %d, headers %v, %d calls, want a fresh 201", status, headers, *calls)
+	}
+}
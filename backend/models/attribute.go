@@ -0,0 +1,60 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Attribute types
+const (
+	AttributeString = "string"
+	AttributeNumber = "number"
+	AttributeBool   = "bool"
+	AttributeEnum   = "enum"
+	AttributeDate   = "date" // YYYY-MM-DD
+)
+
+// AttributeDefinition is a custom product field defined on a category. It
+// applies to the products of the category and of its subcategories.
+type AttributeDefinition struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CategoryID uint      `gorm:"not null;uniqueIndex:idx_attribute_category_key" json:"category_id"`
+	Key        string    `gorm:"column:attribute_key;type:varchar(50);not null;uniqueIndex:idx_attribute_category_key" json:"key"`
+	Label      string    `gorm:"type:varchar(100);not null" json:"label"`
+	Type       string    `gorm:"type:varchar(10);not null" json:"type"`
+	Required   bool      `gorm:"default:false" json:"required"`
+	Options    string    `gorm:"type:text" json:"options,omitempty"` // Enum values, comma separated
+	Position   int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ProductAttribute is the value of a custom attribute on one product. Key and
+// Type are copied from the definition so values can be read and filtered
+// without it.
+type ProductAttribute struct {
+	ID          uint     `gorm:"primaryKey" json:"-"`
+	ProductID   uint     `gorm:"not null;uniqueIndex:idx_product_attribute" json:"-"`
+	AttributeID uint     `gorm:"not null;uniqueIndex:idx_product_attribute;index" json:"attribute_id"`
+	Key         string   `gorm:"column:attribute_key;type:varchar(50);not null;index:idx_attribute_key_value" json:"key"`
+	Type        string   `gorm:"type:varchar(10);not null" json:"type"`
+	Value       string   `gorm:"type:varchar(255);not null;index:idx_attribute_key_value" json:"value"` // Canonical text form
+	Number      *float64 `gorm:"type:decimal(20,6);index" json:"-"`                                     // Numbers only, for range filters
+}
+
+// MarshalJSON renders Value as a JSON number or boolean for those types
+func (a ProductAttribute) MarshalJSON() ([]byte, error) {
+	var value interface{} = a.Value
+	switch {
+	case a.Type == AttributeNumber && a.Number != nil:
+		value = *a.Number
+	case a.Type == AttributeBool:
+		value = a.Value == "true"
+	}
+	return json.Marshal(struct {
+		AttributeID uint        `json:"attribute_id"`
+		Key         string      `json:"key"`
+		Type        string      `json:"type"`
+		Value       interface{} `json:"value"`
+	}{a.AttributeID, a.Key, a.Type, value})
+}
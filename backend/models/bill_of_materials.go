@@ -0,0 +1,19 @@
+package models
+
+// Kit types
+const (
+	KitAssembled = "assembled" // Built ahead from its components and stocked itself
+	KitVirtual   = "virtual"   // Holds no stock, movements are applied to its components
+)
+
+// BillOfMaterials is one component of a kit and the quantity of it, in the
+// component's base unit, that goes into one kit
+type BillOfMaterials struct {
+	ID          uint    `gorm:"primaryKey" json:"id"`
+	KitID       uint    `gorm:"not null;uniqueIndex:idx_bom_kit_component" json:"kit_id"`
+	ComponentID uint    `gorm:"not null;uniqueIndex:idx_bom_kit_component;index" json:"component_id"`
+	Quantity    float64 `gorm:"type:decimal(15,3);not null" json:"quantity"`
+
+	// Relations
+	Component Product `gorm:"foreignKey:ComponentID" json:"component,omitempty"`
+}
@@ -4,7 +4,11 @@ import "gorm.io/gorm"
 
 type Category struct {
 	ID          uint           `gorm:"primaryKey" json:"id"`
-	Name        string         `gorm:"type:varchar(100);unique;not null" json:"name"`
+	Name        string         `gorm:"type:varchar(100);not null;uniqueIndex:idx_category_parent_name,priority:2" json:"name"` // Unique among its siblings
 	Description string         `gorm:"type:text" json:"description"`
+	ParentID    *uint          `gorm:"index;uniqueIndex:idx_category_parent_name,priority:1" json:"parent_id"` // Empty for a top level category
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Relations
+	Children []Category `gorm:"foreignKey:ParentID" json:"children,omitempty"`
 }
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Export job statuses
+const (
+	ExportJobPending   = "pending"
+	ExportJobRunning   = "running"
+	ExportJobCompleted = "completed"
+	ExportJobFailed    = "failed"
+	ExportJobExpired   = "expired"
+)
+
+type ExportJob struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index" json:"user_id"`
+	Type       string     `gorm:"type:varchar(50);not null" json:"type"`   // products, activity_logs
+	Format     string     `gorm:"type:varchar(10);not null" json:"format"` // csv, json, xlsx, pdf
+	Params     string     `gorm:"type:text" json:"params"`                 // JSON encoded filters
+	Status     string     `gorm:"type:varchar(20);index;not null;default:'pending'" json:"status"`
+	Progress   int        `gorm:"default:0" json:"progress"` // 0 - 100
+	Processed  int        `gorm:"default:0" json:"processed"`
+	Total      int        `gorm:"default:0" json:"total"`
+	FileName   string     `gorm:"type:varchar(255)" json:"file_name"`
+	FilePath   string     `gorm:"type:varchar(500)" json:"-"`
+	FileSize   int64      `json:"file_size"`
+	Error      string     `gorm:"type:text" json:"error,omitempty"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	ExpiresAt  *time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
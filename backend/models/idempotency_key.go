@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// IdempotencyKey stores the response to a mutating request sent with an
+// Idempotency-Key header so a retry of the same request can be answered
+// without running it again
+type IdempotencyKey struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	UserID         uint      `gorm:"not null;uniqueIndex:idx_idempotency_user_key,priority:1" json:"user_id"`
+	Key            string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_user_key,priority:2" json:"key"`
+	Method         string    `gorm:"type:varchar(10);not null" json:"method"`
+	Path           string    `gorm:"type:varchar(500);not null" json:"path"`
+	RequestHash    string    `gorm:"type:varchar(64);not null" json:"request_hash"` // SHA-256 of method, URL and body
+	ResponseStatus int       `json:"response_status"`                               // 0 while the first request is running
+	ContentType    string    `gorm:"type:varchar(100)" json:"content_type"`
+	ETag           string    `gorm:"column:etag;type:varchar(100)" json:"etag"` // Replayed so the client can go on with If-Match
+	ResponseBody   string    `gorm:"type:mediumtext" json:"response_body"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `gorm:"index" json:"expires_at"`
+}
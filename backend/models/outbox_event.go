@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// OutboxEvent is a domain event written in the same transaction as the change
+// it describes. The relay publishes it to the configured sinks afterwards.
+type OutboxEvent struct {
+	ID            uint       `gorm:"primaryKey" json:"id"` // Publishing order
+	EventID       string     `gorm:"type:varchar(40);not null;uniqueIndex" json:"event_id"`
+	AggregateType string     `gorm:"type:varchar(50);not null;index:idx_outbox_aggregate,priority:1" json:"aggregate_type"`
+	AggregateID   uint       `gorm:"not null;index:idx_outbox_aggregate,priority:2" json:"aggregate_id"`
+	EventType     string     `gorm:"type:varchar(50);not null" json:"event_type"`
+	Payload       string     `gorm:"type:mediumtext;not null" json:"payload"` // Event envelope as JSON
+	Attempts      int        `json:"attempts"`
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	PublishedAt   *time.Time `gorm:"index" json:"published_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
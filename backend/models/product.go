@@ -7,22 +7,44 @@ import (
 )
 
 type Product struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	SKU         string         `gorm:"type:varchar(50);unique;not null" json:"sku"`
-	Name        string         `gorm:"type:varchar(200);not null" json:"name"`
-	Description string         `gorm:"type:text" json:"description"`
-	Price       float64        `gorm:"type:decimal(15,2);not null" json:"price"`
-	Stock       int            `gorm:"default:0" json:"stock"`
-	MinStock    int            `gorm:"default:10" json:"min_stock"` // Alert jika stock < min_stock
-	ImageURL    string         `gorm:"type:varchar(255)" json:"image_url"`
-	SupplierID  uint           `gorm:"not null" json:"supplier_id"`
-	CategoryID  *uint          `json:"category_id"` // Pointer to allow null initially
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	SKU           string         `gorm:"type:varchar(50);unique;not null" json:"sku"`
+	Name          string         `gorm:"type:varchar(200);not null" json:"name"`
+	Description   string         `gorm:"type:text" json:"description"`
+	Price         float64        `gorm:"type:decimal(15,2);not null" json:"price"`
+	Stock         float64        `gorm:"type:decimal(15,3);default:0" json:"stock"`                // In the base unit
+	MinStock      float64        `gorm:"type:decimal(15,3);default:10" json:"min_stock"`           // Alert jika stock < min_stock
+	MaxStock      float64        `gorm:"type:decimal(15,3);default:0" json:"max_stock"`            // Replenish up to this level, 0 uses the economic order quantity
+	MinOrderQty   float64        `gorm:"type:decimal(15,3);default:0" json:"min_order_qty"`        // Supplier minimum order quantity
+	PackSize      float64        `gorm:"type:decimal(15,3);default:1" json:"pack_size"`            // Supplier sells in multiples of this
+	BaseUnit      string         `gorm:"type:varchar(20);not null;default:'pcs'" json:"base_unit"` // Unit of every stored quantity
+	Fractional    bool           `gorm:"default:false" json:"fractional"`                          // Quantities may have decimals, e.g. kg or m
+	PurchaseUnit  string         `gorm:"type:varchar(20)" json:"purchase_unit"`                    // Default unit of purchase orders, empty for the base unit
+	SalesUnit     string         `gorm:"type:varchar(20)" json:"sales_unit"`                       // Default unit of sales, empty for the base unit
+	ImageURL      string         `gorm:"type:varchar(255)" json:"image_url"`
+	SupplierID    uint           `gorm:"not null" json:"supplier_id"`
+	CategoryID    *uint          `json:"category_id"`                              // Pointer to allow null initially
+	AbcClass      string         `gorm:"type:varchar(1);index" json:"abc_class"`   // A, B or C from the last ABC analysis
+	DeadStock     bool           `gorm:"default:false;index" json:"dead_stock"`    // No outbound movement in the last dead stock check
+	Version       uint           `gorm:"not null;default:1" json:"version"`        // Bumped on every save, used as the ETag
+	ParentID      *uint          `gorm:"index" json:"parent_id"`                   // Set on variants
+	HasVariants   bool           `gorm:"default:false;index" json:"has_variants"`  // Parent product, stock is kept on the variants
+	PriceOverride *float64       `gorm:"type:decimal(15,2)" json:"price_override"` // Variant price, nil follows the parent price
+	KitType       string         `gorm:"type:varchar(20);index" json:"kit_type"`   // KitAssembled or KitVirtual, empty for other products
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Relations
-	Supplier     Supplier       `gorm:"foreignKey:SupplierID" json:"supplier,omitempty"`
-	Category     Category       `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
-	StockHistory []StockHistory `gorm:"foreignKey:ProductID" json:"stock_history,omitempty"`
+	Supplier     Supplier           `gorm:"foreignKey:SupplierID" json:"supplier,omitempty"`
+	Category     Category           `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
+	StockHistory []StockHistory     `gorm:"foreignKey:ProductID" json:"stock_history,omitempty"`
+	Units        []ProductUnit      `gorm:"foreignKey:ProductID" json:"units,omitempty"`      // Alternative units
+	Components   []BillOfMaterials  `gorm:"foreignKey:KitID" json:"components,omitempty"`     // Of a kit
+	Attributes   []ProductAttribute `gorm:"foreignKey:ProductID" json:"attributes,omitempty"` // Custom attributes of its category
+
+	// Variant relations
+	VariantAttributes []VariantAttribute `gorm:"foreignKey:ProductID" json:"variant_attributes,omitempty"` // Of a parent
+	VariantOptions    []VariantOption    `gorm:"foreignKey:ProductID" json:"variant_options,omitempty"`    // Of a variant
+	Variants          []Product          `gorm:"foreignKey:ParentID" json:"variants,omitempty"`
 }
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ProductSearch is the search document of a product. It copies the searchable
+// text of the product, its supplier and its category path into one row so a
+// single set of FULLTEXT indexes covers all of it.
+type ProductSearch struct {
+	ProductID   uint      `gorm:"primaryKey;autoIncrement:false" json:"product_id"`
+	SKU         string    `gorm:"type:varchar(50);not null;index:idx_search_title,class:FULLTEXT;index:idx_search_words,class:FULLTEXT" json:"sku"`
+	Name        string    `gorm:"type:varchar(200);not null;index:idx_search_title,class:FULLTEXT;index:idx_search_words,class:FULLTEXT" json:"name"`
+	Description string    `gorm:"type:text;index:idx_search_words,class:FULLTEXT" json:"description"`
+	Supplier    string    `gorm:"type:varchar(100);index:idx_search_words,class:FULLTEXT" json:"supplier"`
+	Category    string    `gorm:"type:varchar(500);index:idx_search_words,class:FULLTEXT" json:"category"`           // Path, e.g. "Electronics > Phones"
+	Terms       string    `gorm:"type:text;index:idx_search_grams,class:FULLTEXT,option:WITH PARSER ngram" json:"-"` // SKU, name, supplier and category for typo tolerant matching
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// ProductUnit is an alternative unit of a product, e.g. a carton of 24 when
+// the base unit is pcs
+type ProductUnit struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ProductID uint      `gorm:"not null;uniqueIndex:idx_product_unit_name" json:"product_id"`
+	Name      string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_product_unit_name" json:"name"`
+	Factor    float64   `gorm:"type:decimal(15,6);not null" json:"factor"` // Base units in one of this unit
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+// VariantAttribute is a dimension the variants of a parent product differ
+// in, e.g. size or colour
+type VariantAttribute struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	ProductID uint   `gorm:"not null;index" json:"product_id"` // Parent product
+	Name      string `gorm:"type:varchar(50);not null" json:"name"`
+	Values    string `gorm:"type:text;not null" json:"values"` // Comma separated, in display order
+	Position  int    `gorm:"not null" json:"position"`
+}
+
+// VariantOption is the value a variant has for one attribute of its parent
+type VariantOption struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	ProductID uint   `gorm:"not null;index" json:"product_id"` // Variant
+	Name      string `gorm:"type:varchar(50);not null" json:"name"`
+	Value     string `gorm:"type:varchar(100);not null" json:"value"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Purchase order statuses
+const (
+	PurchaseOrderDraft     = "draft"
+	PurchaseOrderCancelled = "cancelled"
+)
+
+type PurchaseOrder struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	Number        string    `gorm:"type:varchar(30);index" json:"number"` // PO-YYYYMMDD-ID
+	SupplierID    uint      `gorm:"not null;index" json:"supplier_id"`
+	Status        string    `gorm:"type:varchar(20);not null;index" json:"status"`
+	Note          string    `gorm:"type:text" json:"note"`
+	TotalQuantity float64   `gorm:"type:decimal(15,3)" json:"total_quantity"`
+	TotalAmount   float64   `gorm:"type:decimal(15,2)" json:"total_amount"`
+	CreatedBy     uint      `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	// Relations
+	Supplier Supplier            `gorm:"foreignKey:SupplierID" json:"supplier,omitempty"`
+	Items    []PurchaseOrderItem `gorm:"foreignKey:PurchaseOrderID" json:"items,omitempty"`
+}
+
+type PurchaseOrderItem struct {
+	ID              uint    `gorm:"primaryKey" json:"id"`
+	PurchaseOrderID uint    `gorm:"not null;index" json:"purchase_order_id"`
+	ProductID       uint    `gorm:"not null;index" json:"product_id"`
+	Quantity        float64 `gorm:"type:decimal(15,3);not null" json:"quantity"` // In the base unit
+	Unit            string  `gorm:"type:varchar(20)" json:"unit"`                // Purchase unit the quantity is ordered in
+	UnitQuantity    float64 `gorm:"type:decimal(15,3)" json:"unit_quantity"`     // Quantity in Unit
+	UnitPrice       float64 `gorm:"type:decimal(15,2)" json:"unit_price"`        // Estimated from the product price
+	Subtotal        float64 `gorm:"type:decimal(15,2)" json:"subtotal"`
+
+	// Relations
+	Product Product `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Reorder proposal statuses
+const (
+	ReorderProposalPending    = "pending"
+	ReorderProposalApplied    = "applied"
+	ReorderProposalRejected   = "rejected"
+	ReorderProposalSuperseded = "superseded" // Replaced by a newer proposal before review
+)
+
+// ReorderProposal is a forecast based MinStock value waiting for approval
+type ReorderProposal struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	ProductID        uint       `gorm:"not null;index" json:"product_id"`
+	CurrentMinStock  float64    `gorm:"type:decimal(15,3)" json:"current_min_stock"`
+	ProposedMinStock float64    `gorm:"type:decimal(15,3)" json:"proposed_min_stock"` // Reorder point
+	SafetyStock      float64    `gorm:"type:decimal(15,3)" json:"safety_stock"`
+	AvgDailyDemand   float64    `json:"avg_daily_demand"`
+	DemandStdDev     float64    `json:"demand_std_dev"`
+	LeadTimeDays     int        `json:"lead_time_days"`
+	ServiceLevel     float64    `json:"service_level"`
+	WindowDays       int        `json:"window_days"`
+	Status           string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	CreatedBy        uint       `json:"created_by"` // 0 for the background job
+	ReviewedBy       *uint      `json:"reviewed_by"`
+	ReviewedAt       *time.Time `json:"reviewed_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// Relations
+	Product Product `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+}
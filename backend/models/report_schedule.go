@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReportSchedule struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	Name       string         `gorm:"type:varchar(100);not null" json:"name"`
+	ReportType string         `gorm:"type:varchar(50);not null" json:"report_type"` // low_stock, valuation, stock_movements
+	Format     string         `gorm:"type:varchar(10);not null" json:"format"`      // pdf, xlsx
+	CronExpr   string         `gorm:"type:varchar(100);not null" json:"cron_expr"`
+	PeriodDays int            `gorm:"default:7" json:"period_days"`              // Look-back window for movement reports
+	Delivery   string         `gorm:"type:varchar(20);not null" json:"delivery"` // email, disk
+	Recipients string         `gorm:"type:text" json:"recipients"`               // Comma separated email addresses
+	Enabled    bool           `gorm:"not null" json:"enabled"`
+	NextRunAt  *time.Time     `gorm:"index" json:"next_run_at"`
+	LastRunAt  *time.Time     `json:"last_run_at"`
+	LastStatus string         `gorm:"type:varchar(20)" json:"last_status"` // success, failed
+	LastError  string         `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedBy  uint           `json:"created_by"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Stock alert statuses
+const (
+	StockAlertOpen         = "open"
+	StockAlertAcknowledged = "acknowledged"
+	StockAlertResolved     = "resolved"
+)
+
+// StockAlert is raised when a product drops below its MinStock and resolved
+// when it climbs back. A product has at most one unresolved alert.
+type StockAlert struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	ProductID      uint       `gorm:"not null;index" json:"product_id"`
+	Status         string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	Stock          float64    `gorm:"type:decimal(15,3)" json:"stock"`     // Stock when the alert was raised
+	MinStock       float64    `gorm:"type:decimal(15,3)" json:"min_stock"` // Threshold when the alert was raised
+	Occurrences    int        `gorm:"default:1" json:"occurrences"`        // Raised again within the cooldown
+	TriggeredAt    time.Time  `json:"triggered_at"`
+	AcknowledgedBy *uint      `json:"acknowledged_by"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+	ResolvedAt     *time.Time `gorm:"index" json:"resolved_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Relations
+	Product    Product         `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	Deliveries []AlertDelivery `gorm:"foreignKey:AlertID" json:"deliveries,omitempty"`
+}
+
+// Alert channel types
+const (
+	AlertChannelWebhook = "webhook"
+	AlertChannelEmail   = "email"
+)
+
+// AlertChannel is a destination for stock alert notifications
+type AlertChannel struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Name      string         `gorm:"type:varchar(100);not null" json:"name"`
+	Type      string         `gorm:"type:varchar(20);not null" json:"type"` // webhook, email
+	Target    string         `gorm:"type:text;not null" json:"target"`      // URL or comma separated email addresses
+	Events    string         `gorm:"type:varchar(255)" json:"events"`       // Comma separated, empty for all events
+	Enabled   bool           `gorm:"not null" json:"enabled"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Alert delivery statuses
+const (
+	AlertDeliveryPending   = "pending"
+	AlertDeliverySent      = "sent"
+	AlertDeliveryFailed    = "failed"    // Gave up after the last retry
+	AlertDeliveryCancelled = "cancelled" // Withdrawn before it was sent
+)
+
+// AlertDelivery is one notification of an alert event to one channel
+type AlertDelivery struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	AlertID       uint       `gorm:"not null;index" json:"alert_id"`
+	ChannelID     uint       `gorm:"not null;index" json:"channel_id"`
+	Event         string     `gorm:"type:varchar(50);not null" json:"event"`
+	Payload       string     `gorm:"type:text" json:"payload"` // JSON snapshot of the event
+	Status        string     `gorm:"type:varchar(20);not null;index:idx_alert_delivery_due,priority:1" json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"index:idx_alert_delivery_due,priority:2" json:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
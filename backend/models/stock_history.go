@@ -3,15 +3,18 @@ package models
 import "time"
 
 type StockHistory struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	ProductID   uint      `gorm:"not null" json:"product_id"`
-	Type        string    `gorm:"type:enum('in','out');not null" json:"type"` // in = stock masuk, out = stock keluar
-	Quantity    int       `gorm:"not null" json:"quantity"`
-	Note        string    `gorm:"type:text" json:"note"`
-	StockBefore int       `gorm:"not null" json:"stock_before"`
-	StockAfter  int       `gorm:"not null" json:"stock_after"`
-	CreatedAt   time.Time `json:"created_at"`
-	
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ProductID    uint      `gorm:"not null;index:idx_stock_history_product_created" json:"product_id"`
+	Type         string    `gorm:"type:enum('in','out');not null" json:"type"`  // in = stock masuk, out = stock keluar
+	Quantity     float64   `gorm:"type:decimal(15,3);not null" json:"quantity"` // In the base unit
+	Unit         string    `gorm:"type:varchar(20)" json:"unit"`                // Unit the movement was entered in
+	UnitQuantity float64   `gorm:"type:decimal(15,3)" json:"unit_quantity"`     // Quantity as entered, in Unit
+	Note         string    `gorm:"type:text" json:"note"`
+	Reference    string    `gorm:"type:varchar(40);index" json:"reference,omitempty"` // Groups the movements of one kit operation
+	StockBefore  float64   `gorm:"type:decimal(15,3);not null" json:"stock_before"`
+	StockAfter   float64   `gorm:"type:decimal(15,3);not null" json:"stock_after"`
+	CreatedAt    time.Time `gorm:"index:idx_stock_history_product_created" json:"created_at"`
+
 	// Relations
 	Product Product `gorm:"foreignKey:ProductID" json:"product,omitempty"`
-}
\ No newline at end of file
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// StockSnapshot records the stock of a product at SnapshotAt. Snapshots are
+// taken nightly so historical stock lookups only replay recent movements.
+type StockSnapshot struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ProductID  uint      `gorm:"not null;uniqueIndex:idx_stock_snapshot_product_at" json:"product_id"`
+	SnapshotAt time.Time `gorm:"not null;uniqueIndex:idx_stock_snapshot_product_at;index" json:"snapshot_at"`
+	Quantity   float64   `gorm:"type:decimal(15,3);not null" json:"quantity"`
+	CreatedAt  time.Time `json:"created_at"`
+}
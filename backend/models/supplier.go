@@ -7,16 +7,19 @@ import (
 )
 
 type Supplier struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	Name        string         `gorm:"type:varchar(100);not null" json:"name"`
-	ContactName string         `gorm:"type:varchar(100)" json:"contact_name"`
-	Phone       string         `gorm:"type:varchar(20)" json:"phone"`
-	Email       string         `gorm:"type:varchar(100)" json:"email"`
-	Address     string         `gorm:"type:text" json:"address"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
-	
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	Name         string         `gorm:"type:varchar(100);not null" json:"name"`
+	ContactName  string         `gorm:"type:varchar(100)" json:"contact_name"`
+	Phone        string         `gorm:"type:varchar(20)" json:"phone"`
+	Email        string         `gorm:"type:varchar(100)" json:"email"`
+	Address      string         `gorm:"type:text" json:"address"`
+	LeadTimeDays int            `gorm:"default:7" json:"lead_time_days"`                // Days between ordering and receiving goods
+	OrderCost    float64        `gorm:"type:decimal(15,2);default:0" json:"order_cost"` // Fixed cost of placing one order, used for the economic order quantity
+	Version      uint           `gorm:"not null;default:1" json:"version"`              // Bumped on every save, used as the ETag
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+
 	// Relations
 	Products []Product `gorm:"foreignKey:SupplierID" json:"products,omitempty"`
-}
\ No newline at end of file
+}
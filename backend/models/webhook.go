@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookSubscription posts inventory events to an external URL
+type WebhookSubscription struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Name      string         `gorm:"type:varchar(100);not null" json:"name"`
+	URL       string         `gorm:"type:varchar(500);not null" json:"url"`
+	Secret    string         `gorm:"type:varchar(100);not null" json:"-"` // HMAC-SHA256 key, only shown on creation
+	Events    string         `gorm:"type:text;not null" json:"events"`    // Comma separated event types, * for all
+	Enabled   bool           `gorm:"not null" json:"enabled"`
+	CreatedBy uint           `json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed" // Gave up after the last retry
+)
+
+// WebhookDelivery is one event queued for one subscription, together with
+// the outcome of the last attempt
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	SubscriptionID uint       `gorm:"not null;index" json:"subscription_id"`
+	EventID        string     `gorm:"type:varchar(40);not null;index" json:"event_id"`
+	Event          string     `gorm:"type:varchar(50);not null" json:"event"`
+	Payload        string     `gorm:"type:mediumtext" json:"payload"` // Signed request body
+	Status         string     `gorm:"type:varchar(20);not null;index:idx_webhook_delivery_due,priority:1" json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"response_status"`
+	ResponseBody   string     `gorm:"type:text" json:"response_body,omitempty"` // Truncated
+	LastError      string     `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt  time.Time  `gorm:"index:idx_webhook_delivery_due,priority:2" json:"next_attempt_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
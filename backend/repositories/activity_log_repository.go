@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"context"
+	"inventory-backend/models"
+
+	"gorm.io/gorm"
+)
+
+type ActivityLogRepository interface {
+	Create(ctx context.Context, log *models.ActivityLog) error
+	// ListRecent returns the newest logs with their user, newest first
+	ListRecent(ctx context.Context, limit int) ([]models.ActivityLog, error)
+}
+
+type activityLogRepository struct {
+	db *gorm.DB
+}
+
+func (r *activityLogRepository) Create(ctx context.Context, log *models.ActivityLog) error {
+	return r.db.WithContext(ctx).Create(log).Error
+}
+
+func (r *activityLogRepository) ListRecent(ctx context.Context, limit int) ([]models.ActivityLog, error) {
+	var logs []models.ActivityLog
+	if err := r.db.WithContext(ctx).Preload("User").Order("created_at desc").Limit(limit).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
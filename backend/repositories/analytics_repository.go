@@ -0,0 +1,221 @@
+package repositories
+
+import (
+	"context"
+	"inventory-backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InventoryTotals summarises the whole catalog
+type InventoryTotals struct {
+	TotalSKUs       int64   `json:"total_skus"`
+	TotalUnits      float64 `json:"total_units"`
+	InventoryValue  float64 `json:"inventory_value"`
+	LowStockCount   int64   `json:"low_stock_count"`    // 0 < stock < min_stock
+	OutOfStockCount int64   `json:"out_of_stock_count"` // stock <= 0
+}
+
+// ProductMovement totals the movements of one product
+type ProductMovement struct {
+	ProductID uint    `json:"product_id"`
+	SKU       string  `json:"sku"`
+	Name      string  `json:"name"`
+	Stock     float64 `json:"stock"`
+	QtyIn     float64 `json:"qty_in"`
+	QtyOut    float64 `json:"qty_out"`
+	Movements int     `json:"movements"`
+}
+
+// DailyMovement totals the movements of one day
+type DailyMovement struct {
+	Day       string  `json:"day"` // YYYY-MM-DD
+	QtyIn     float64 `json:"qty_in"`
+	QtyOut    float64 `json:"qty_out"`
+	Movements int     `json:"movements"`
+}
+
+// InventoryBreakdown summarises the products of one category or supplier
+type InventoryBreakdown struct {
+	ID              *uint   `json:"id"`
+	Name            string  `json:"name"`
+	Products        int64   `json:"products"`
+	Units           float64 `json:"units"`
+	Value           float64 `json:"value"`
+	LowStockCount   int64   `json:"low_stock_count"`
+	OutOfStockCount int64   `json:"out_of_stock_count"`
+}
+
+// ProductConsumption is the outbound quantity and value of one product
+type ProductConsumption struct {
+	ProductID uint    `json:"product_id"`
+	SKU       string  `json:"sku"`
+	Name      string  `json:"name"`
+	Price     float64 `json:"price"`
+	Stock     float64 `json:"stock"`
+	QtyOut    float64 `json:"qty_out"`
+	Value     float64 `json:"consumption_value"` // qty_out valued at the current price
+}
+
+// IdleProduct is a product holding stock without recent outbound movements
+type IdleProduct struct {
+	ProductID uint       `json:"product_id"`
+	SKU       string     `json:"sku"`
+	Name      string     `json:"name"`
+	Stock     float64    `json:"stock"`
+	Price     float64    `json:"price"`
+	Value     float64    `json:"stock_value"`
+	LastOutAt *time.Time `json:"last_out_at"`
+}
+
+// DemandStats summarises the daily outbound quantities of one product. Days
+// without movements are not counted in ActiveDays and add nothing to the sums.
+type DemandStats struct {
+	ProductID  uint    `json:"product_id"`
+	Total      float64 `json:"total"`
+	SumSquares float64 `json:"sum_squares"` // sum of the squared daily totals
+	ActiveDays int     `json:"active_days"`
+}
+
+// AnalyticsRepository computes catalog wide aggregates in SQL so callers
+// never need to load every product. Parent products hold no stock of their
+// own and are left out; their variants count as products.
+type AnalyticsRepository interface {
+	InventoryTotals(ctx context.Context) (*InventoryTotals, error)
+	// TopMovers returns the products with the most units moved in [from, to)
+	TopMovers(ctx context.Context, from, to time.Time, limit int) ([]ProductMovement, error)
+	// DailyMovements returns one row per day in [from, to) that has movements
+	DailyMovements(ctx context.Context, from, to time.Time) ([]DailyMovement, error)
+	CategoryBreakdown(ctx context.Context) ([]InventoryBreakdown, error)
+	SupplierBreakdown(ctx context.Context) ([]InventoryBreakdown, error)
+	// Consumption returns every product with its outbound movements in
+	// [from, to), highest consumption value first
+	Consumption(ctx context.Context, from, to time.Time) ([]ProductConsumption, error)
+	// IdleProducts returns products in stock that existed before since and
+	// had no outbound movement after it, highest stock value first
+	IdleProducts(ctx context.Context, since time.Time) ([]IdleProduct, error)
+	// Demand returns the daily outbound statistics in [from, to) of one
+	// product, or of every product with movements when productID is 0
+	Demand(ctx context.Context, from, to time.Time, productID uint) ([]DemandStats, error)
+}
+
+type analyticsRepository struct {
+	db *gorm.DB
+}
+
+const inventoryAggregates = `COUNT(p.id) AS products,
+	COALESCE(SUM(p.stock), 0) AS units,
+	COALESCE(SUM(p.stock * p.price), 0) AS value,
+	COALESCE(SUM(CASE WHEN p.stock > 0 AND p.stock < p.min_stock THEN 1 ELSE 0 END), 0) AS low_stock_count,
+	COALESCE(SUM(CASE WHEN p.stock <= 0 THEN 1 ELSE 0 END), 0) AS out_of_stock_count`
+
+func (r *analyticsRepository) InventoryTotals(ctx context.Context) (*InventoryTotals, error) {
+	var totals InventoryTotals
+	err := r.db.WithContext(ctx).Table("products AS p").
+		Select(`COUNT(*) AS total_skus,
+			COALESCE(SUM(p.stock), 0) AS total_units,
+			COALESCE(SUM(p.stock * p.price), 0) AS inventory_value,
+			COALESCE(SUM(CASE WHEN p.stock > 0 AND p.stock < p.min_stock THEN 1 ELSE 0 END), 0) AS low_stock_count,
+			COALESCE(SUM(CASE WHEN p.stock <= 0 THEN 1 ELSE 0 END), 0) AS out_of_stock_count`).
+		Where("p.deleted_at IS NULL AND p.has_variants = ? AND p.kit_type <> ?", false, models.KitVirtual).
+		Scan(&totals).Error
+	return &totals, err
+}
+
+func (r *analyticsRepository) TopMovers(ctx context.Context, from, to time.Time, limit int) ([]ProductMovement, error) {
+	var movers []ProductMovement
+	err := r.db.WithContext(ctx).Table("stock_histories AS h").
+		Select(`p.id AS product_id, p.sku, p.name, p.stock,
+			COALESCE(SUM(CASE WHEN h.type = 'in' THEN h.quantity ELSE 0 END), 0) AS qty_in,
+			COALESCE(SUM(CASE WHEN h.type = 'out' THEN h.quantity ELSE 0 END), 0) AS qty_out,
+			COUNT(*) AS movements`).
+		Joins("JOIN products p ON p.id = h.product_id AND p.deleted_at IS NULL").
+		Where("h.created_at >= ? AND h.created_at < ?", from, to).
+		Group("p.id, p.sku, p.name, p.stock").
+		Order("SUM(h.quantity) DESC").
+		Limit(limit).
+		Scan(&movers).Error
+	return movers, err
+}
+
+func (r *analyticsRepository) DailyMovements(ctx context.Context, from, to time.Time) ([]DailyMovement, error) {
+	var days []DailyMovement
+	err := r.db.WithContext(ctx).Table("stock_histories AS h").
+		Select(`DATE_FORMAT(h.created_at, '%Y-%m-%d') AS day,
+			COALESCE(SUM(CASE WHEN h.type = 'in' THEN h.quantity ELSE 0 END), 0) AS qty_in,
+			COALESCE(SUM(CASE WHEN h.type = 'out' THEN h.quantity ELSE 0 END), 0) AS qty_out,
+			COUNT(*) AS movements`).
+		Where("h.created_at >= ? AND h.created_at < ?", from, to).
+		Group("day").
+		Order("day").
+		Scan(&days).Error
+	return days, err
+}
+
+func (r *analyticsRepository) CategoryBreakdown(ctx context.Context) ([]InventoryBreakdown, error) {
+	var rows []InventoryBreakdown
+	err := r.db.WithContext(ctx).Table("products AS p").
+		Select("c.id AS id, COALESCE(c.name, 'Uncategorized') AS name, "+inventoryAggregates).
+		Joins("LEFT JOIN categories c ON c.id = p.category_id AND c.deleted_at IS NULL").
+		Where("p.deleted_at IS NULL AND p.has_variants = ? AND p.kit_type <> ?", false, models.KitVirtual).
+		Group("c.id, c.name").
+		Order("value DESC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (r *analyticsRepository) SupplierBreakdown(ctx context.Context) ([]InventoryBreakdown, error) {
+	var rows []InventoryBreakdown
+	err := r.db.WithContext(ctx).Table("products AS p").
+		Select("s.id AS id, COALESCE(s.name, 'Unknown') AS name, "+inventoryAggregates).
+		Joins("LEFT JOIN suppliers s ON s.id = p.supplier_id AND s.deleted_at IS NULL").
+		Where("p.deleted_at IS NULL AND p.has_variants = ? AND p.kit_type <> ?", false, models.KitVirtual).
+		Group("s.id, s.name").
+		Order("value DESC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (r *analyticsRepository) Consumption(ctx context.Context, from, to time.Time) ([]ProductConsumption, error) {
+	var rows []ProductConsumption
+	err := r.db.WithContext(ctx).Table("products AS p").
+		Select(`p.id AS product_id, p.sku, p.name, p.price, p.stock,
+			COALESCE(SUM(h.quantity), 0) AS qty_out,
+			COALESCE(SUM(h.quantity), 0) * p.price AS value`).
+		Joins("LEFT JOIN stock_histories h ON h.product_id = p.id AND h.type = 'out' AND h.created_at >= ? AND h.created_at < ?", from, to).
+		Where("p.deleted_at IS NULL AND p.has_variants = ? AND p.kit_type <> ?", false, models.KitVirtual).
+		Group("p.id, p.sku, p.name, p.price, p.stock").
+		Order("value DESC, p.id").
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (r *analyticsRepository) IdleProducts(ctx context.Context, since time.Time) ([]IdleProduct, error) {
+	var rows []IdleProduct
+	err := r.db.WithContext(ctx).Table("products AS p").
+		Select(`p.id AS product_id, p.sku, p.name, p.stock, p.price, p.stock * p.price AS value,
+			(SELECT MAX(h.created_at) FROM stock_histories h WHERE h.product_id = p.id AND h.type = 'out') AS last_out_at`).
+		Where("p.deleted_at IS NULL AND p.stock > 0 AND p.created_at < ?", since).
+		Where("NOT EXISTS (SELECT 1 FROM stock_histories h WHERE h.product_id = p.id AND h.type = 'out' AND h.created_at >= ?)", since).
+		Order("value DESC, p.id").
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (r *analyticsRepository) Demand(ctx context.Context, from, to time.Time, productID uint) ([]DemandStats, error) {
+	daily := r.db.WithContext(ctx).Table("stock_histories").
+		Select("product_id, DATE(created_at) AS day, SUM(quantity) AS qty").
+		Where("type = 'out' AND created_at >= ? AND created_at < ?", from, to).
+		Group("product_id, DATE(created_at)")
+	if productID != 0 {
+		daily = daily.Where("product_id = ?", productID)
+	}
+
+	var rows []DemandStats
+	err := r.db.WithContext(ctx).Table("(?) AS d", daily).
+		Select("d.product_id, SUM(d.qty) AS total, SUM(d.qty * d.qty) AS sum_squares, COUNT(*) AS active_days").
+		Group("d.product_id").
+		Scan(&rows).Error
+	return rows, err
+}
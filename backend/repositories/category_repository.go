@@ -0,0 +1,177 @@
+package repositories
+
+import (
+	"context"
+	"inventory-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// categorySubtreeSQL selects the ids of a category and all of its live
+// descendants
+const categorySubtreeSQL = `WITH RECURSIVE subtree (id) AS (
+	SELECT id FROM categories WHERE id = ? AND deleted_at IS NULL
+	UNION ALL
+	SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id WHERE c.deleted_at IS NULL
+) SELECT id FROM subtree`
+
+// categoryAncestorsSQL selects the ids of a category and all of its live
+// ancestors
+const categoryAncestorsSQL = `WITH RECURSIVE ancestors (id, parent_id) AS (
+	SELECT id, parent_id FROM categories WHERE id = ? AND deleted_at IS NULL
+	UNION ALL
+	SELECT c.id, c.parent_id FROM categories c JOIN ancestors a ON c.id = a.parent_id WHERE c.deleted_at IS NULL
+) SELECT id FROM ancestors`
+
+type CategoryRepository interface {
+	List(ctx context.Context) ([]models.Category, error)
+	// ListForUpdate returns every category and locks them until the
+	// surrounding transaction ends, so the tree cannot change underneath
+	ListForUpdate(ctx context.Context) ([]models.Category, error)
+	FindByID(ctx context.Context, id uint) (*models.Category, error)
+	// FindByName returns the child of parentID, nil for the top level, named
+	// name in any case. It also matches soft-deleted categories so name
+	// collisions with deleted rows can be detected, live ones come first.
+	FindByName(ctx context.Context, parentID *uint, name string) (*models.Category, error)
+	// Rename changes the name of a category, including soft-deleted ones
+	Rename(ctx context.Context, id uint, name string) error
+	CountChildren(ctx context.Context, parentID uint) (int64, error)
+	// MoveChildren gives the children of a category a new parent, nil for
+	// the top level
+	MoveChildren(ctx context.Context, fromParentID uint, toParentID *uint) error
+	Create(ctx context.Context, category *models.Category) error
+	Save(ctx context.Context, category *models.Category) error
+	Delete(ctx context.Context, category *models.Category) error
+
+	// ListAttributes returns the attribute definitions that apply to the
+	// products of a category, its own and those of its ancestors
+	ListAttributes(ctx context.Context, categoryID uint) ([]models.AttributeDefinition, error)
+	// ListSubtreeAttributes returns the attribute definitions of a category
+	// and of its descendants
+	ListSubtreeAttributes(ctx context.Context, categoryID uint) ([]models.AttributeDefinition, error)
+	// ListAllAttributes returns the attribute definitions of every category
+	ListAllAttributes(ctx context.Context) ([]models.AttributeDefinition, error)
+	FindAttribute(ctx context.Context, id uint) (*models.AttributeDefinition, error)
+	CreateAttribute(ctx context.Context, attribute *models.AttributeDefinition) error
+	SaveAttribute(ctx context.Context, attribute *models.AttributeDefinition) error
+	DeleteAttribute(ctx context.Context, attribute *models.AttributeDefinition) error
+}
+
+type categoryRepository struct {
+	db *gorm.DB
+}
+
+func (r *categoryRepository) List(ctx context.Context) ([]models.Category, error) {
+	var categories []models.Category
+	if err := r.db.WithContext(ctx).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (r *categoryRepository) ListForUpdate(ctx context.Context) ([]models.Category, error) {
+	var categories []models.Category
+	if err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Order("id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (r *categoryRepository) FindByID(ctx context.Context, id uint) (*models.Category, error) {
+	var category models.Category
+	if err := r.db.WithContext(ctx).First(&category, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &category, nil
+}
+
+func (r *categoryRepository) FindByName(ctx context.Context, parentID *uint, name string) (*models.Category, error) {
+	query := r.db.WithContext(ctx).Unscoped().Where("LOWER(name) = LOWER(?)", name)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+
+	var category models.Category
+	// NULL sorts first, so a live category wins over deleted ones
+	if err := query.Order("deleted_at").Order("id").First(&category).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &category, nil
+}
+
+func (r *categoryRepository) Rename(ctx context.Context, id uint, name string) error {
+	return r.db.WithContext(ctx).Unscoped().Model(&models.Category{}).Where("id = ?", id).Update("name", name).Error
+}
+
+func (r *categoryRepository) CountChildren(ctx context.Context, parentID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Category{}).Where("parent_id = ?", parentID).Count(&count).Error
+	return count, err
+}
+
+func (r *categoryRepository) MoveChildren(ctx context.Context, fromParentID uint, toParentID *uint) error {
+	return r.db.WithContext(ctx).Model(&models.Category{}).Where("parent_id = ?", fromParentID).Update("parent_id", toParentID).Error
+}
+
+func (r *categoryRepository) Create(ctx context.Context, category *models.Category) error {
+	return r.db.WithContext(ctx).Create(category).Error
+}
+
+func (r *categoryRepository) Save(ctx context.Context, category *models.Category) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(category).Error
+}
+
+func (r *categoryRepository) Delete(ctx context.Context, category *models.Category) error {
+	return r.db.WithContext(ctx).Delete(category).Error
+}
+
+func (r *categoryRepository) ListAttributes(ctx context.Context, categoryID uint) ([]models.AttributeDefinition, error) {
+	var attributes []models.AttributeDefinition
+	err := r.db.WithContext(ctx).Where("category_id IN ("+categoryAncestorsSQL+")", categoryID).
+		Order("position").Order("id").Find(&attributes).Error
+	if err != nil {
+		return nil, err
+	}
+	return attributes, nil
+}
+
+func (r *categoryRepository) ListSubtreeAttributes(ctx context.Context, categoryID uint) ([]models.AttributeDefinition, error) {
+	var attributes []models.AttributeDefinition
+	err := r.db.WithContext(ctx).Where("category_id IN ("+categorySubtreeSQL+")", categoryID).
+		Order("position").Order("id").Find(&attributes).Error
+	if err != nil {
+		return nil, err
+	}
+	return attributes, nil
+}
+
+func (r *categoryRepository) ListAllAttributes(ctx context.Context) ([]models.AttributeDefinition, error) {
+	var attributes []models.AttributeDefinition
+	if err := r.db.WithContext(ctx).Order("position").Order("id").Find(&attributes).Error; err != nil {
+		return nil, err
+	}
+	return attributes, nil
+}
+
+func (r *categoryRepository) FindAttribute(ctx context.Context, id uint) (*models.AttributeDefinition, error) {
+	var attribute models.AttributeDefinition
+	if err := r.db.WithContext(ctx).First(&attribute, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &attribute, nil
+}
+
+func (r *categoryRepository) CreateAttribute(ctx context.Context, attribute *models.AttributeDefinition) error {
+	return r.db.WithContext(ctx).Create(attribute).Error
+}
+
+func (r *categoryRepository) SaveAttribute(ctx context.Context, attribute *models.AttributeDefinition) error {
+	return r.db.WithContext(ctx).Save(attribute).Error
+}
+
+func (r *categoryRepository) DeleteAttribute(ctx context.Context, attribute *models.AttributeDefinition) error {
+	return r.db.WithContext(ctx).Delete(attribute).Error
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when a lookup does not match any row
+var ErrNotFound = errors.New("record not found")
+
+// ErrConflict is returned when a versioned row was changed since it was loaded
+var ErrConflict = errors.New("record was modified concurrently")
+
+// translate maps GORM errors to repository errors
+func translate(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
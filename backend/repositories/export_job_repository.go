@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"context"
+	"inventory-backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ExportJobRepository interface {
+	Create(ctx context.Context, job *models.ExportJob) error
+	FindByID(ctx context.Context, id uint) (*models.ExportJob, error)
+	ListRecent(ctx context.Context, limit int) ([]models.ExportJob, error)
+	ListByStatus(ctx context.Context, status string) ([]models.ExportJob, error)
+	// ListExpired returns completed jobs whose artifact expired before now
+	ListExpired(ctx context.Context, now time.Time) ([]models.ExportJob, error)
+	// Claim moves a job from pending to running. It reports false when
+	// another worker already claimed the job.
+	Claim(ctx context.Context, id uint, startedAt time.Time) (bool, error)
+	UpdateProgress(ctx context.Context, id uint, processed, total int) error
+	// Finish stores the outcome of a run: the status, the artifact, the
+	// timestamps and the error. The counts written by UpdateProgress are
+	// kept, only a completed job gets its progress set.
+	Finish(ctx context.Context, job *models.ExportJob) error
+	Save(ctx context.Context, job *models.ExportJob) error
+}
+
+type exportJobRepository struct {
+	db *gorm.DB
+}
+
+func (r *exportJobRepository) Create(ctx context.Context, job *models.ExportJob) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+func (r *exportJobRepository) FindByID(ctx context.Context, id uint) (*models.ExportJob, error) {
+	var job models.ExportJob
+	if err := r.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &job, nil
+}
+
+func (r *exportJobRepository) ListRecent(ctx context.Context, limit int) ([]models.ExportJob, error) {
+	var jobs []models.ExportJob
+	if err := r.db.WithContext(ctx).Order("created_at DESC").Limit(limit).Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *exportJobRepository) ListByStatus(ctx context.Context, status string) ([]models.ExportJob, error) {
+	var jobs []models.ExportJob
+	if err := r.db.WithContext(ctx).Where("status = ?", status).Order("id").Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *exportJobRepository) ListExpired(ctx context.Context, now time.Time) ([]models.ExportJob, error) {
+	var jobs []models.ExportJob
+	if err := r.db.WithContext(ctx).
+		Where("status = ? AND expires_at < ?", models.ExportJobCompleted, now).
+		Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *exportJobRepository) Claim(ctx context.Context, id uint, startedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.ExportJob{}).
+		Where("id = ? AND status = ?", id, models.ExportJobPending).
+		Updates(map[string]interface{}{"status": models.ExportJobRunning, "started_at": startedAt})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *exportJobRepository) UpdateProgress(ctx context.Context, id uint, processed, total int) error {
+	progress := 0
+	if total > 0 {
+		progress = processed * 100 / total
+	}
+	return r.db.WithContext(ctx).Model(&models.ExportJob{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"processed": processed, "total": total, "progress": progress}).Error
+}
+
+func (r *exportJobRepository) Finish(ctx context.Context, job *models.ExportJob) error {
+	columns := []string{"status", "file_name", "file_path", "file_size", "finished_at", "expires_at", "error", "updated_at"}
+	if job.Status == models.ExportJobCompleted {
+		columns = append(columns, "progress")
+	}
+	return r.db.WithContext(ctx).Model(job).Select(columns).Updates(job).Error
+}
+
+func (r *exportJobRepository) Save(ctx context.Context, job *models.ExportJob) error {
+	return r.db.WithContext(ctx).Save(job).Error
+}
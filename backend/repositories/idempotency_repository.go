@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"context"
+	"inventory-backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type IdempotencyKeyRepository interface {
+	Find(ctx context.Context, userID uint, key string) (*models.IdempotencyKey, error)
+	Create(ctx context.Context, record *models.IdempotencyKey) error
+	Save(ctx context.Context, record *models.IdempotencyKey) error
+	Delete(ctx context.Context, record *models.IdempotencyKey) error
+	// DeleteExpired removes keys that expired before now
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type idempotencyKeyRepository struct {
+	db *gorm.DB
+}
+
+func (r *idempotencyKeyRepository) Find(ctx context.Context, userID uint, key string) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND `key` = ?", userID, key).First(&record).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &record, nil
+}
+
+func (r *idempotencyKeyRepository) Create(ctx context.Context, record *models.IdempotencyKey) error {
+	return r.db.WithContext(ctx).Create(record).Error
+}
+
+func (r *idempotencyKeyRepository) Save(ctx context.Context, record *models.IdempotencyKey) error {
+	return r.db.WithContext(ctx).Save(record).Error
+}
+
+func (r *idempotencyKeyRepository) Delete(ctx context.Context, record *models.IdempotencyKey) error {
+	return r.db.WithContext(ctx).Delete(record).Error
+}
+
+func (r *idempotencyKeyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"inventory-backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OutboxStats summarises the relay backlog
+type OutboxStats struct {
+	Pending       int64      `json:"pending"`
+	Failing       int64      `json:"failing"` // Pending with at least one failed attempt
+	OldestPending *time.Time `json:"oldest_pending"`
+}
+
+type OutboxRepository interface {
+	Create(ctx context.Context, event *models.OutboxEvent) error
+	// ListUnpublished returns unpublished events in publishing order
+	ListUnpublished(ctx context.Context, limit int) ([]models.OutboxEvent, error)
+	// ListRecent returns the newest events, only unpublished ones when pending is set
+	ListRecent(ctx context.Context, pending bool, limit int) ([]models.OutboxEvent, error)
+	Stats(ctx context.Context) (*OutboxStats, error)
+	Save(ctx context.Context, event *models.OutboxEvent) error
+	// DeletePublishedBefore removes events published before cutoff
+	DeletePublishedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func (r *outboxRepository) Create(ctx context.Context, event *models.OutboxEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *outboxRepository) ListUnpublished(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	if err := r.db.WithContext(ctx).Where("published_at IS NULL").Order("id").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *outboxRepository) ListRecent(ctx context.Context, pending bool, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	query := r.db.WithContext(ctx).Order("id DESC").Limit(limit)
+	if pending {
+		query = query.Where("published_at IS NULL")
+	}
+	if err := query.Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *outboxRepository) Stats(ctx context.Context) (*OutboxStats, error) {
+	var stats OutboxStats
+	var oldest sql.NullTime
+	if err := r.db.WithContext(ctx).Model(&models.OutboxEvent{}).
+		Select("COUNT(*), COALESCE(SUM(CASE WHEN attempts > 0 THEN 1 ELSE 0 END), 0), MIN(created_at)").
+		Where("published_at IS NULL").
+		Row().Scan(&stats.Pending, &stats.Failing, &oldest); err != nil {
+		return nil, err
+	}
+	if oldest.Valid {
+		stats.OldestPending = &oldest.Time
+	}
+	return &stats, nil
+}
+
+func (r *outboxRepository) Save(ctx context.Context, event *models.OutboxEvent) error {
+	return r.db.WithContext(ctx).Save(event).Error
+}
+
+func (r *outboxRepository) DeletePublishedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("published_at < ?", cutoff).Delete(&models.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
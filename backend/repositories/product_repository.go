@@ -0,0 +1,477 @@
+package repositories
+
+import (
+	"context"
+	"inventory-backend/models"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Product list views of variants
+const (
+	// VariantsNested lists standalone and parent products, parents with their
+	// variants nested
+	VariantsNested = "nested"
+	// VariantsFlat lists standalone products and variants, i.e. everything
+	// that holds stock, without the parents
+	VariantsFlat = "flat"
+)
+
+// AttributeFilter matches products by a custom attribute. Value matches the
+// canonical value exactly; Min and Max bound numbers, or compare the text of
+// other values such as dates.
+type AttributeFilter struct {
+	Key   string
+	Value string
+	Min   string
+	Max   string
+}
+
+// ProductFilter holds the search and pagination options for listing products
+type ProductFilter struct {
+	Search string
+	// Matches holds the search hits for Search, most relevant first. A
+	// search lists only them, in that order.
+	Matches    []uint
+	SupplierID uint
+	CategoryID uint // Includes the descendants of the category
+	AbcClass   string
+	DeadStock  *bool
+	Attributes []AttributeFilter
+	Variants   string // VariantsNested, VariantsFlat or empty for every product
+	Offset     int
+	Limit      int
+}
+
+type ProductRepository interface {
+	List(ctx context.Context, filter ProductFilter) ([]models.Product, int64, error)
+	Count(ctx context.Context, filter ProductFilter) (int64, error)
+	// EachBatch streams the products matching filter to fn in primary key
+	// order, batchSize rows at a time. Offset and Limit are ignored.
+	EachBatch(ctx context.Context, filter ProductFilter, batchSize int, fn func([]models.Product) error) error
+	// ListIDs returns the ids of the products matching filter. Offset and
+	// Limit are ignored.
+	ListIDs(ctx context.Context, filter ProductFilter) ([]uint, error)
+	// ListByIDs loads the given products with their supplier and category
+	ListByIDs(ctx context.Context, ids []uint) ([]models.Product, error)
+	FindByID(ctx context.Context, id uint) (*models.Product, error)
+	FindWithHistory(ctx context.Context, id uint) (*models.Product, error)
+	// FindByIDForUpdate loads a product and locks its row until the
+	// surrounding transaction ends.
+	FindByIDForUpdate(ctx context.Context, id uint) (*models.Product, error)
+	// FindBySKU also matches soft-deleted products so SKU collisions with
+	// deleted rows can be detected.
+	FindBySKU(ctx context.Context, sku string) (*models.Product, error)
+	// RenameSKU changes the SKU of a product, including soft-deleted ones
+	RenameSKU(ctx context.Context, id uint, sku string) error
+	// FindWithVariants loads a parent product with its attributes and its
+	// variants
+	FindWithVariants(ctx context.Context, id uint) (*models.Product, error)
+	// ListVariants returns the variants of a parent with their options
+	ListVariants(ctx context.Context, parentID uint) ([]models.Product, error)
+	// ReplaceVariantAttributes stores attributes as the variant attributes of
+	// a parent, replacing the previous ones
+	ReplaceVariantAttributes(ctx context.Context, parentID uint, attributes []models.VariantAttribute) error
+	// ListComponents returns the bill of materials of a kit with the
+	// component products
+	ListComponents(ctx context.Context, kitID uint) ([]models.BillOfMaterials, error)
+	// ReplaceComponents stores components as the bill of materials of a kit,
+	// replacing the previous one
+	ReplaceComponents(ctx context.Context, kitID uint, components []models.BillOfMaterials) error
+	// CountKitsUsing counts the live kits that have the product as a component
+	CountKitsUsing(ctx context.Context, componentID uint) (int64, error)
+	// ListUnits returns the alternative units of a product by factor
+	ListUnits(ctx context.Context, productID uint) ([]models.ProductUnit, error)
+	// ListAttributes returns the custom attribute values of a product
+	ListAttributes(ctx context.Context, productID uint) ([]models.ProductAttribute, error)
+	// ReplaceAttributes stores attributes as the custom attribute values of a
+	// product, replacing the previous ones
+	ReplaceAttributes(ctx context.Context, productID uint, attributes []models.ProductAttribute) error
+	// CountAttributeValues counts the values of an attribute, only those not
+	// in except when given
+	CountAttributeValues(ctx context.Context, attributeID uint, except []string) (int64, error)
+	// DeleteAttributeValues removes every value of an attribute
+	DeleteAttributeValues(ctx context.Context, attributeID uint) error
+	// ReplaceUnits stores units as the alternative units of a product,
+	// replacing the previous ones
+	ReplaceUnits(ctx context.Context, productID uint, units []models.ProductUnit) error
+	ListLowStock(ctx context.Context) ([]models.Product, error)
+	CountBySupplier(ctx context.Context, supplierID uint) (int64, error)
+	CountByCategory(ctx context.Context, categoryID uint) (int64, error)
+	// MoveCategory moves the products of a category to another one, nil for
+	// none, bumping their versions
+	MoveCategory(ctx context.Context, fromCategoryID uint, toCategoryID *uint) error
+	// SetAbcClass stores class on the given products, bumping the versions
+	// of those whose class changes
+	SetAbcClass(ctx context.Context, class string, ids []uint) error
+	// SetDeadStock flags the given products as dead stock and clears the
+	// flag on every other product, bumping the versions of those whose flag
+	// changes
+	SetDeadStock(ctx context.Context, ids []uint) error
+	Create(ctx context.Context, product *models.Product) error
+	// Save writes the product and bumps its version. It fails with
+	// ErrConflict when the row was saved by someone else since it was loaded.
+	Save(ctx context.Context, product *models.Product) error
+	// Delete fails with ErrConflict when the version is stale
+	Delete(ctx context.Context, product *models.Product) error
+}
+
+type productRepository struct {
+	db *gorm.DB
+}
+
+func (r *productRepository) List(ctx context.Context, filter ProductFilter) ([]models.Product, int64, error) {
+	var products []models.Product
+
+	query := r.filtered(ctx, filter)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Limit > 0 {
+		query = query.Offset(filter.Offset).Limit(filter.Limit)
+	}
+	if filter.Search != "" && len(filter.Matches) > 0 {
+		// Most relevant first, parents found through a variant last
+		query = query.Order(clause.Expr{SQL: "FIELD(id, ?) = 0, FIELD(id, ?)", Vars: []interface{}{filter.Matches, filter.Matches}, WithoutParentheses: true})
+	}
+	query = query.Preload("Supplier").Preload("Category").Preload("Attributes")
+	if filter.Variants == VariantsNested {
+		query = query.Preload("VariantAttributes", orderedAttributes).
+			Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("sku") }).
+			Preload("Variants.VariantOptions")
+	}
+	if err := query.Find(&products).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
+
+func (r *productRepository) Count(ctx context.Context, filter ProductFilter) (int64, error) {
+	var total int64
+	err := r.filtered(ctx, filter).Count(&total).Error
+	return total, err
+}
+
+func (r *productRepository) EachBatch(ctx context.Context, filter ProductFilter, batchSize int, fn func([]models.Product) error) error {
+	var batch []models.Product
+	return r.filtered(ctx, filter).Preload("Supplier").Preload("Category").Preload("Attributes").
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
+
+func (r *productRepository) ListIDs(ctx context.Context, filter ProductFilter) ([]uint, error) {
+	var ids []uint
+	err := r.filtered(ctx, filter).Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *productRepository) ListByIDs(ctx context.Context, ids []uint) ([]models.Product, error) {
+	var products []models.Product
+	if err := r.db.WithContext(ctx).Preload("Supplier").Where("id IN ?", ids).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// filtered applies the filters shared by List and EachBatch
+func (r *productRepository) filtered(ctx context.Context, filter ProductFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&models.Product{})
+	if filter.Search != "" {
+		if filter.Variants == VariantsNested {
+			// Also find parents through a matching variant
+			variants := r.db.Model(&models.Product{}).Select("parent_id").Where("parent_id IS NOT NULL AND id IN ?", filter.Matches)
+			query = query.Where("id IN ? OR id IN (?)", filter.Matches, variants)
+		} else {
+			query = query.Where("id IN ?", filter.Matches)
+		}
+	}
+	switch filter.Variants {
+	case VariantsNested:
+		query = query.Where("parent_id IS NULL")
+	case VariantsFlat:
+		query = query.Where("has_variants = ?", false)
+	}
+	if filter.SupplierID != 0 {
+		query = query.Where("supplier_id = ?", filter.SupplierID)
+	}
+	if filter.CategoryID != 0 {
+		query = query.Where("category_id IN ("+categorySubtreeSQL+")", filter.CategoryID)
+	}
+	if filter.AbcClass != "" {
+		query = query.Where("abc_class = ?", filter.AbcClass)
+	}
+	if filter.DeadStock != nil {
+		query = query.Where("dead_stock = ?", *filter.DeadStock)
+	}
+	for _, attribute := range filter.Attributes {
+		query = query.Where("id IN (?)", r.attributeMatches(attribute))
+	}
+	return query
+}
+
+// attributeMatches selects the ids of the products matching filter
+func (r *productRepository) attributeMatches(filter AttributeFilter) *gorm.DB {
+	query := r.db.Model(&models.ProductAttribute{}).Select("product_id").Where("attribute_key = ?", filter.Key)
+	if filter.Value != "" {
+		query = query.Where("value = ?", filter.Value)
+	}
+	for _, bound := range []struct {
+		value string
+		op    string
+	}{{filter.Min, ">="}, {filter.Max, "<="}} {
+		if bound.value == "" {
+			continue
+		}
+		if number, err := strconv.ParseFloat(bound.value, 64); err == nil {
+			query = query.Where("number "+bound.op+" ?", number)
+		} else {
+			query = query.Where("value "+bound.op+" ?", bound.value)
+		}
+	}
+	return query
+}
+
+func (r *productRepository) FindByID(ctx context.Context, id uint) (*models.Product, error) {
+	var product models.Product
+	if err := r.db.WithContext(ctx).Preload("Supplier").Preload("Category").Preload("Attributes").First(&product, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &product, nil
+}
+
+func (r *productRepository) FindWithHistory(ctx context.Context, id uint) (*models.Product, error) {
+	var product models.Product
+	if err := r.db.WithContext(ctx).Preload("Supplier").Preload("Category").Preload("Attributes").Preload("StockHistory").First(&product, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &product, nil
+}
+
+func (r *productRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.Product, error) {
+	var product models.Product
+	if err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &product, nil
+}
+
+func (r *productRepository) FindBySKU(ctx context.Context, sku string) (*models.Product, error) {
+	var product models.Product
+	if err := r.db.WithContext(ctx).Unscoped().Where("sku = ?", sku).First(&product).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &product, nil
+}
+
+func (r *productRepository) RenameSKU(ctx context.Context, id uint, sku string) error {
+	return r.db.WithContext(ctx).Unscoped().Model(&models.Product{}).Where("id = ?", id).Update("sku", sku).Error
+}
+
+func (r *productRepository) FindWithVariants(ctx context.Context, id uint) (*models.Product, error) {
+	var product models.Product
+	err := r.db.WithContext(ctx).Preload("Supplier").Preload("Category").
+		Preload("VariantAttributes", orderedAttributes).
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("sku") }).
+		Preload("Variants.VariantOptions").
+		First(&product, id).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &product, nil
+}
+
+func (r *productRepository) ListVariants(ctx context.Context, parentID uint) ([]models.Product, error) {
+	var variants []models.Product
+	if err := r.db.WithContext(ctx).Preload("VariantOptions").Where("parent_id = ?", parentID).Order("id").Find(&variants).Error; err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
+func (r *productRepository) ReplaceVariantAttributes(ctx context.Context, parentID uint, attributes []models.VariantAttribute) error {
+	if err := r.db.WithContext(ctx).Where("product_id = ?", parentID).Delete(&models.VariantAttribute{}).Error; err != nil {
+		return err
+	}
+	if len(attributes) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&attributes).Error
+}
+
+func (r *productRepository) ListComponents(ctx context.Context, kitID uint) ([]models.BillOfMaterials, error) {
+	var components []models.BillOfMaterials
+	if err := r.db.WithContext(ctx).Preload("Component").Where("kit_id = ?", kitID).Order("id").Find(&components).Error; err != nil {
+		return nil, err
+	}
+	return components, nil
+}
+
+func (r *productRepository) ReplaceComponents(ctx context.Context, kitID uint, components []models.BillOfMaterials) error {
+	if err := r.db.WithContext(ctx).Where("kit_id = ?", kitID).Delete(&models.BillOfMaterials{}).Error; err != nil {
+		return err
+	}
+	if len(components) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(&components).Error
+}
+
+func (r *productRepository) CountKitsUsing(ctx context.Context, componentID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.BillOfMaterials{}).
+		Where("component_id = ?", componentID).
+		Where("kit_id IN (?)", r.db.Model(&models.Product{}).Select("id")).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *productRepository) ListAttributes(ctx context.Context, productID uint) ([]models.ProductAttribute, error) {
+	var attributes []models.ProductAttribute
+	if err := r.db.WithContext(ctx).Where("product_id = ?", productID).Order("id").Find(&attributes).Error; err != nil {
+		return nil, err
+	}
+	return attributes, nil
+}
+
+func (r *productRepository) ReplaceAttributes(ctx context.Context, productID uint, attributes []models.ProductAttribute) error {
+	if err := r.db.WithContext(ctx).Where("product_id = ?", productID).Delete(&models.ProductAttribute{}).Error; err != nil {
+		return err
+	}
+	if len(attributes) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&attributes).Error
+}
+
+func (r *productRepository) CountAttributeValues(ctx context.Context, attributeID uint, except []string) (int64, error) {
+	var count int64
+	query := r.db.WithContext(ctx).Model(&models.ProductAttribute{}).Where("attribute_id = ?", attributeID)
+	if len(except) > 0 {
+		query = query.Where("value NOT IN ?", except)
+	}
+	err := query.Count(&count).Error
+	return count, err
+}
+
+func (r *productRepository) DeleteAttributeValues(ctx context.Context, attributeID uint) error {
+	return r.db.WithContext(ctx).Where("attribute_id = ?", attributeID).Delete(&models.ProductAttribute{}).Error
+}
+
+func (r *productRepository) ListUnits(ctx context.Context, productID uint) ([]models.ProductUnit, error) {
+	var units []models.ProductUnit
+	if err := r.db.WithContext(ctx).Where("product_id = ?", productID).Order("factor").Find(&units).Error; err != nil {
+		return nil, err
+	}
+	return units, nil
+}
+
+func (r *productRepository) ReplaceUnits(ctx context.Context, productID uint, units []models.ProductUnit) error {
+	if err := r.db.WithContext(ctx).Where("product_id = ?", productID).Delete(&models.ProductUnit{}).Error; err != nil {
+		return err
+	}
+	if len(units) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&units).Error
+}
+
+func orderedAttributes(db *gorm.DB) *gorm.DB {
+	return db.Order("position")
+}
+
+// ListLowStock skips parent products and virtual kits, their stock is kept
+// on the variants and components. Units are loaded to express order
+// quantities in the purchase unit.
+func (r *productRepository) ListLowStock(ctx context.Context) ([]models.Product, error) {
+	var products []models.Product
+	if err := r.db.WithContext(ctx).Preload("Supplier").Preload("Units").Where("stock < min_stock AND has_variants = ? AND kit_type <> ?", false, models.KitVirtual).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (r *productRepository) CountBySupplier(ctx context.Context, supplierID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Product{}).Where("supplier_id = ?", supplierID).Count(&count).Error
+	return count, err
+}
+
+func (r *productRepository) CountByCategory(ctx context.Context, categoryID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Product{}).Where("category_id = ?", categoryID).Count(&count).Error
+	return count, err
+}
+
+func (r *productRepository) MoveCategory(ctx context.Context, fromCategoryID uint, toCategoryID *uint) error {
+	return r.db.WithContext(ctx).Model(&models.Product{}).Where("category_id = ?", fromCategoryID).
+		Updates(map[string]interface{}{"category_id": toCategoryID, "version": gorm.Expr("version + 1")}).Error
+}
+
+// classifyChunk bounds the number of ids in a single IN clause
+const classifyChunk = 1000
+
+func (r *productRepository) SetAbcClass(ctx context.Context, class string, ids []uint) error {
+	for start := 0; start < len(ids); start += classifyChunk {
+		end := min(start+classifyChunk, len(ids))
+		err := r.db.WithContext(ctx).Model(&models.Product{}).Where("id IN ? AND abc_class <> ?", ids[start:end], class).
+			Updates(map[string]interface{}{"abc_class": class, "version": gorm.Expr("version + 1")}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *productRepository) SetDeadStock(ctx context.Context, ids []uint) error {
+	// Only the products whose flag changes are written, so the others keep
+	// their version
+	var flagged []uint
+	if err := r.db.WithContext(ctx).Model(&models.Product{}).Where("dead_stock = ?", true).Pluck("id", &flagged).Error; err != nil {
+		return err
+	}
+	dead := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		dead[id] = true
+	}
+	var cleared []uint
+	for _, id := range flagged {
+		if !dead[id] {
+			cleared = append(cleared, id)
+		}
+	}
+
+	for _, change := range []struct {
+		ids  []uint
+		dead bool
+	}{{cleared, false}, {ids, true}} {
+		for start := 0; start < len(change.ids); start += classifyChunk {
+			end := min(start+classifyChunk, len(change.ids))
+			err := r.db.WithContext(ctx).Model(&models.Product{}).Where("id IN ? AND dead_stock <> ?", change.ids[start:end], change.dead).
+				Updates(map[string]interface{}{"dead_stock": change.dead, "version": gorm.Expr("version + 1")}).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *productRepository) Create(ctx context.Context, product *models.Product) error {
+	product.Version = 1
+	return r.db.WithContext(ctx).Create(product).Error
+}
+
+func (r *productRepository) Save(ctx context.Context, product *models.Product) error {
+	return saveVersioned(r.db.WithContext(ctx), product, &product.Version)
+}
+
+func (r *productRepository) Delete(ctx context.Context, product *models.Product) error {
+	return deleteVersioned(r.db.WithContext(ctx), product, product.Version)
+}
@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"inventory-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OpenQuantity is the quantity of a product on open purchase orders
+type OpenQuantity struct {
+	ProductID uint    `json:"product_id"`
+	Quantity  float64 `json:"quantity"`
+}
+
+type PurchaseOrderFilter struct {
+	Status     string
+	SupplierID uint
+	Limit      int
+}
+
+type PurchaseOrderRepository interface {
+	List(ctx context.Context, filter PurchaseOrderFilter) ([]models.PurchaseOrder, error)
+	FindByID(ctx context.Context, id uint) (*models.PurchaseOrder, error)
+	// OpenQuantities sums the items of draft purchase orders per product
+	OpenQuantities(ctx context.Context) ([]OpenQuantity, error)
+	// Create inserts an order together with its items
+	Create(ctx context.Context, order *models.PurchaseOrder) error
+	Save(ctx context.Context, order *models.PurchaseOrder) error
+}
+
+type purchaseOrderRepository struct {
+	db *gorm.DB
+}
+
+func (r *purchaseOrderRepository) List(ctx context.Context, filter PurchaseOrderFilter) ([]models.PurchaseOrder, error) {
+	var orders []models.PurchaseOrder
+	query := r.db.WithContext(ctx).Preload("Supplier").Order("id DESC")
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.SupplierID != 0 {
+		query = query.Where("supplier_id = ?", filter.SupplierID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (r *purchaseOrderRepository) FindByID(ctx context.Context, id uint) (*models.PurchaseOrder, error) {
+	var order models.PurchaseOrder
+	if err := r.db.WithContext(ctx).Preload("Supplier").Preload("Items.Product").First(&order, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &order, nil
+}
+
+func (r *purchaseOrderRepository) OpenQuantities(ctx context.Context) ([]OpenQuantity, error) {
+	var rows []OpenQuantity
+	err := r.db.WithContext(ctx).Table("purchase_order_items AS i").
+		Select("i.product_id, SUM(i.quantity) AS quantity").
+		Joins("JOIN purchase_orders o ON o.id = i.purchase_order_id").
+		Where("o.status = ?", models.PurchaseOrderDraft).
+		Group("i.product_id").
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (r *purchaseOrderRepository) Create(ctx context.Context, order *models.PurchaseOrder) error {
+	return r.db.WithContext(ctx).Omit("Supplier").Create(order).Error
+}
+
+func (r *purchaseOrderRepository) Save(ctx context.Context, order *models.PurchaseOrder) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(order).Error
+}
@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"inventory-backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReorderProposalRepository interface {
+	// List returns proposals with the given status, or all when status is
+	// empty, newest first
+	List(ctx context.Context, status string, limit int) ([]models.ReorderProposal, error)
+	// ListPending returns the pending proposals with the given ids, or every
+	// pending proposal when ids is empty
+	ListPending(ctx context.Context, ids []uint) ([]models.ReorderProposal, error)
+	// LatestCreatedAt returns when the newest proposal was created, nil if
+	// there are none
+	LatestCreatedAt(ctx context.Context) (*time.Time, error)
+	// SupersedePending marks every pending proposal as superseded
+	SupersedePending(ctx context.Context) (int64, error)
+	Create(ctx context.Context, proposals []models.ReorderProposal) error
+	Save(ctx context.Context, proposal *models.ReorderProposal) error
+}
+
+type reorderProposalRepository struct {
+	db *gorm.DB
+}
+
+func (r *reorderProposalRepository) List(ctx context.Context, status string, limit int) ([]models.ReorderProposal, error) {
+	var proposals []models.ReorderProposal
+	query := r.db.WithContext(ctx).Preload("Product").Order("id DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&proposals).Error; err != nil {
+		return nil, err
+	}
+	return proposals, nil
+}
+
+func (r *reorderProposalRepository) ListPending(ctx context.Context, ids []uint) ([]models.ReorderProposal, error) {
+	var proposals []models.ReorderProposal
+	query := r.db.WithContext(ctx).Where("status = ?", models.ReorderProposalPending).Order("id")
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	if err := query.Find(&proposals).Error; err != nil {
+		return nil, err
+	}
+	return proposals, nil
+}
+
+func (r *reorderProposalRepository) LatestCreatedAt(ctx context.Context) (*time.Time, error) {
+	var latest sql.NullTime
+	if err := r.db.WithContext(ctx).Model(&models.ReorderProposal{}).
+		Select("MAX(created_at)").Row().Scan(&latest); err != nil {
+		return nil, err
+	}
+	if !latest.Valid {
+		return nil, nil
+	}
+	return &latest.Time, nil
+}
+
+func (r *reorderProposalRepository) SupersedePending(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.ReorderProposal{}).
+		Where("status = ?", models.ReorderProposalPending).
+		Update("status", models.ReorderProposalSuperseded)
+	return result.RowsAffected, result.Error
+}
+
+func (r *reorderProposalRepository) Create(ctx context.Context, proposals []models.ReorderProposal) error {
+	if len(proposals) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Omit("Product").CreateInBatches(proposals, 500).Error
+}
+
+func (r *reorderProposalRepository) Save(ctx context.Context, proposal *models.ReorderProposal) error {
+	return r.db.WithContext(ctx).Omit("Product").Save(proposal).Error
+}
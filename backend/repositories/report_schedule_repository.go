@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"context"
+	"inventory-backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReportScheduleRepository interface {
+	List(ctx context.Context) ([]models.ReportSchedule, error)
+	FindByID(ctx context.Context, id uint) (*models.ReportSchedule, error)
+	// ListDue returns enabled schedules whose next run is at or before now
+	ListDue(ctx context.Context, now time.Time) ([]models.ReportSchedule, error)
+	Create(ctx context.Context, schedule *models.ReportSchedule) error
+	Save(ctx context.Context, schedule *models.ReportSchedule) error
+	Delete(ctx context.Context, schedule *models.ReportSchedule) error
+}
+
+type reportScheduleRepository struct {
+	db *gorm.DB
+}
+
+func (r *reportScheduleRepository) List(ctx context.Context) ([]models.ReportSchedule, error) {
+	var schedules []models.ReportSchedule
+	if err := r.db.WithContext(ctx).Order("id").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func (r *reportScheduleRepository) FindByID(ctx context.Context, id uint) (*models.ReportSchedule, error) {
+	var schedule models.ReportSchedule
+	if err := r.db.WithContext(ctx).First(&schedule, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &schedule, nil
+}
+
+func (r *reportScheduleRepository) ListDue(ctx context.Context, now time.Time) ([]models.ReportSchedule, error) {
+	var schedules []models.ReportSchedule
+	if err := r.db.WithContext(ctx).
+		Where("enabled = ? AND next_run_at <= ?", true, now).
+		Order("next_run_at").
+		Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func (r *reportScheduleRepository) Create(ctx context.Context, schedule *models.ReportSchedule) error {
+	return r.db.WithContext(ctx).Create(schedule).Error
+}
+
+func (r *reportScheduleRepository) Save(ctx context.Context, schedule *models.ReportSchedule) error {
+	return r.db.WithContext(ctx).Save(schedule).Error
+}
+
+func (r *reportScheduleRepository) Delete(ctx context.Context, schedule *models.ReportSchedule) error {
+	return r.db.WithContext(ctx).Delete(schedule).Error
+}
@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"inventory-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SearchMatch is a search document matching a query with its relevance
+type SearchMatch struct {
+	ProductID uint
+	Words     float64 // Whole word and prefix relevance, SKU and name weigh double
+	Grams     float64 // Character bigram relevance, also found with typos
+}
+
+type SearchRepository interface {
+	// Save stores documents, replacing those of the same products
+	Save(ctx context.Context, documents []models.ProductSearch) error
+	Delete(ctx context.Context, productIDs []uint) error
+	// Match ranks the documents against words, a boolean mode query on the
+	// word indexes, and text, a natural language query on the bigram index.
+	// Word matches come first.
+	Match(ctx context.Context, words string, text string, limit int) ([]SearchMatch, error)
+	// ListUnindexed returns up to limit ids of live products without a
+	// document, in primary key order
+	ListUnindexed(ctx context.Context, limit int) ([]uint, error)
+	// Prune deletes the documents of deleted products
+	Prune(ctx context.Context) (int64, error)
+}
+
+type searchRepository struct {
+	db *gorm.DB
+}
+
+func (r *searchRepository) Save(ctx context.Context, documents []models.ProductSearch) error {
+	if len(documents) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&documents).Error
+}
+
+func (r *searchRepository) Delete(ctx context.Context, productIDs []uint) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Where("product_id IN ?", productIDs).Delete(&models.ProductSearch{}).Error
+}
+
+// searchMatchSQL scores every document matching either index. MATCH needs the
+// exact column list of a FULLTEXT index, see models.ProductSearch.
+const searchMatchSQL = `SELECT product_id,
+	MATCH(sku, name) AGAINST (@words IN BOOLEAN MODE) * 2
+		+ MATCH(sku, name, description, supplier, category) AGAINST (@words IN BOOLEAN MODE) AS words,
+	MATCH(terms) AGAINST (@text IN NATURAL LANGUAGE MODE) AS grams
+FROM product_searches
+WHERE MATCH(sku, name, description, supplier, category) AGAINST (@words IN BOOLEAN MODE)
+	OR MATCH(terms) AGAINST (@text IN NATURAL LANGUAGE MODE)
+ORDER BY words DESC, grams DESC
+LIMIT @limit`
+
+func (r *searchRepository) Match(ctx context.Context, words string, text string, limit int) ([]SearchMatch, error) {
+	var matches []SearchMatch
+	err := r.db.WithContext(ctx).Raw(searchMatchSQL,
+		sql.Named("words", words), sql.Named("text", text), sql.Named("limit", limit),
+	).Scan(&matches).Error
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+func (r *searchRepository) ListUnindexed(ctx context.Context, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&models.Product{}).
+		Where("id NOT IN (?)", r.db.Model(&models.ProductSearch{}).Select("product_id")).
+		Order("id").Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *searchRepository) Prune(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("product_id NOT IN (?)", r.db.Model(&models.Product{}).Select("id")).
+		Delete(&models.ProductSearch{})
+	return result.RowsAffected, result.Error
+}
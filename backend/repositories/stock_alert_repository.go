@@ -0,0 +1,183 @@
+package repositories
+
+import (
+	"context"
+	"inventory-backend/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StockAlertFilter struct {
+	Status    string
+	ProductID uint
+	Limit     int
+}
+
+type StockAlertRepository interface {
+	List(ctx context.Context, filter StockAlertFilter) ([]models.StockAlert, error)
+	// FindByID loads an alert with its product and deliveries
+	FindByID(ctx context.Context, id uint) (*models.StockAlert, error)
+	// FindActive returns the unresolved alert of a product, ErrNotFound if
+	// there is none
+	FindActive(ctx context.Context, productID uint) (*models.StockAlert, error)
+	// FindResolvedSince returns the latest alert of a product resolved at or
+	// after since, ErrNotFound if there is none
+	FindResolvedSince(ctx context.Context, productID uint, since time.Time) (*models.StockAlert, error)
+	Create(ctx context.Context, alert *models.StockAlert) error
+	Save(ctx context.Context, alert *models.StockAlert) error
+}
+
+type stockAlertRepository struct {
+	db *gorm.DB
+}
+
+func (r *stockAlertRepository) List(ctx context.Context, filter StockAlertFilter) ([]models.StockAlert, error) {
+	var alerts []models.StockAlert
+	query := r.db.WithContext(ctx).Preload("Product").Order("id DESC")
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.ProductID != 0 {
+		query = query.Where("product_id = ?", filter.ProductID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Find(&alerts).Error; err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+func (r *stockAlertRepository) FindByID(ctx context.Context, id uint) (*models.StockAlert, error) {
+	var alert models.StockAlert
+	if err := r.db.WithContext(ctx).Preload("Product").Preload("Deliveries").First(&alert, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &alert, nil
+}
+
+func (r *stockAlertRepository) FindActive(ctx context.Context, productID uint) (*models.StockAlert, error) {
+	var alert models.StockAlert
+	if err := r.db.WithContext(ctx).
+		Where("product_id = ? AND status <> ?", productID, models.StockAlertResolved).
+		Order("id DESC").
+		First(&alert).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &alert, nil
+}
+
+func (r *stockAlertRepository) FindResolvedSince(ctx context.Context, productID uint, since time.Time) (*models.StockAlert, error) {
+	var alert models.StockAlert
+	if err := r.db.WithContext(ctx).
+		Where("product_id = ? AND status = ? AND resolved_at >= ?", productID, models.StockAlertResolved, since).
+		Order("resolved_at DESC").
+		First(&alert).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &alert, nil
+}
+
+func (r *stockAlertRepository) Create(ctx context.Context, alert *models.StockAlert) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(alert).Error
+}
+
+func (r *stockAlertRepository) Save(ctx context.Context, alert *models.StockAlert) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(alert).Error
+}
+
+type AlertChannelRepository interface {
+	List(ctx context.Context) ([]models.AlertChannel, error)
+	ListEnabled(ctx context.Context) ([]models.AlertChannel, error)
+	FindByID(ctx context.Context, id uint) (*models.AlertChannel, error)
+	Create(ctx context.Context, channel *models.AlertChannel) error
+	Save(ctx context.Context, channel *models.AlertChannel) error
+	Delete(ctx context.Context, channel *models.AlertChannel) error
+}
+
+type alertChannelRepository struct {
+	db *gorm.DB
+}
+
+func (r *alertChannelRepository) List(ctx context.Context) ([]models.AlertChannel, error) {
+	var channels []models.AlertChannel
+	if err := r.db.WithContext(ctx).Order("id").Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+func (r *alertChannelRepository) ListEnabled(ctx context.Context) ([]models.AlertChannel, error) {
+	var channels []models.AlertChannel
+	if err := r.db.WithContext(ctx).Where("enabled = ?", true).Order("id").Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+func (r *alertChannelRepository) FindByID(ctx context.Context, id uint) (*models.AlertChannel, error) {
+	var channel models.AlertChannel
+	if err := r.db.WithContext(ctx).First(&channel, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &channel, nil
+}
+
+func (r *alertChannelRepository) Create(ctx context.Context, channel *models.AlertChannel) error {
+	return r.db.WithContext(ctx).Create(channel).Error
+}
+
+func (r *alertChannelRepository) Save(ctx context.Context, channel *models.AlertChannel) error {
+	return r.db.WithContext(ctx).Save(channel).Error
+}
+
+func (r *alertChannelRepository) Delete(ctx context.Context, channel *models.AlertChannel) error {
+	return r.db.WithContext(ctx).Delete(channel).Error
+}
+
+type AlertDeliveryRepository interface {
+	Create(ctx context.Context, deliveries []models.AlertDelivery) error
+	// ListDue returns pending deliveries whose next attempt is at or before now
+	ListDue(ctx context.Context, now time.Time, limit int) ([]models.AlertDelivery, error)
+	// CancelPending cancels the unsent deliveries of an alert event and
+	// returns how many there were
+	CancelPending(ctx context.Context, alertID uint, event string) (int64, error)
+	Save(ctx context.Context, delivery *models.AlertDelivery) error
+}
+
+type alertDeliveryRepository struct {
+	db *gorm.DB
+}
+
+func (r *alertDeliveryRepository) Create(ctx context.Context, deliveries []models.AlertDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&deliveries).Error
+}
+
+func (r *alertDeliveryRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]models.AlertDelivery, error) {
+	var deliveries []models.AlertDelivery
+	if err := r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", models.AlertDeliveryPending, now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *alertDeliveryRepository) CancelPending(ctx context.Context, alertID uint, event string) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.AlertDelivery{}).
+		Where("alert_id = ? AND event = ? AND status = ?", alertID, event, models.AlertDeliveryPending).
+		Update("status", models.AlertDeliveryCancelled)
+	return result.RowsAffected, result.Error
+}
+
+func (r *alertDeliveryRepository) Save(ctx context.Context, delivery *models.AlertDelivery) error {
+	return r.db.WithContext(ctx).Save(delivery).Error
+}
@@ -0,0 +1,156 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"inventory-backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MovementQuery selects and groups stock movements. GroupBy is one of
+// product, category, supplier or type and Interval one of day, week, month or
+// empty for no time bucketing. CategoryID also matches its subcategories.
+type MovementQuery struct {
+	From       time.Time
+	To         time.Time
+	GroupBy    string
+	Interval   string
+	ProductID  uint
+	CategoryID uint
+	SupplierID uint
+	Type       string
+}
+
+// MovementAggregate totals the movements of one group in one period
+type MovementAggregate struct {
+	Period    string  `json:"period,omitempty"`
+	GroupID   *uint   `json:"group_id"`
+	GroupName string  `json:"group_name"`
+	QtyIn     float64 `json:"qty_in"`
+	QtyOut    float64 `json:"qty_out"`
+	Movements int     `json:"movements"`
+}
+
+// movementGroups maps a MovementQuery.GroupBy to its id and label expressions
+var movementGroups = map[string][2]string{
+	"product":  {"p.id", "CONCAT(p.sku, ' - ', p.name)"},
+	"category": {"c.id", "COALESCE(c.name, 'Uncategorized')"},
+	"supplier": {"s.id", "COALESCE(s.name, 'Unknown')"},
+	"type":     {"NULL", "h.type"},
+}
+
+// movementIntervals maps a MovementQuery.Interval to its bucket expression
+var movementIntervals = map[string]string{
+	"":      "''",
+	"day":   "DATE_FORMAT(h.created_at, '%Y-%m-%d')",
+	"week":  "DATE_FORMAT(h.created_at, '%x-W%v')",
+	"month": "DATE_FORMAT(h.created_at, '%Y-%m')",
+}
+
+// IsMovementGroup reports whether groupBy is supported by Aggregate
+func IsMovementGroup(groupBy string) bool {
+	_, ok := movementGroups[groupBy]
+	return ok
+}
+
+// IsMovementInterval reports whether interval is supported by Aggregate
+func IsMovementInterval(interval string) bool {
+	_, ok := movementIntervals[interval]
+	return ok
+}
+
+// LedgerSummary condenses the movements recorded for one product
+type LedgerSummary struct {
+	ProductID uint
+	Opening   float64 // stock_before of the first movement
+	NetChange float64 // total in minus total out
+	Closing   float64 // stock_after of the last movement
+	Movements int
+}
+
+type StockHistoryRepository interface {
+	Create(ctx context.Context, history *models.StockHistory) error
+	ListByProduct(ctx context.Context, productID uint) ([]models.StockHistory, error)
+	// Ledger summarises the movements of every product that has any. With
+	// productID set only that product is summarised.
+	Ledger(ctx context.Context, productID uint) ([]LedgerSummary, error)
+	// Aggregate totals the movements in [From, To) per group and period
+	Aggregate(ctx context.Context, query MovementQuery) ([]MovementAggregate, error)
+}
+
+type stockHistoryRepository struct {
+	db *gorm.DB
+}
+
+func (r *stockHistoryRepository) Create(ctx context.Context, history *models.StockHistory) error {
+	return r.db.WithContext(ctx).Create(history).Error
+}
+
+func (r *stockHistoryRepository) ListByProduct(ctx context.Context, productID uint) ([]models.StockHistory, error) {
+	var history []models.StockHistory
+	if err := r.db.WithContext(ctx).Where("product_id = ?", productID).Order("created_at DESC").Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (r *stockHistoryRepository) Aggregate(ctx context.Context, query MovementQuery) ([]MovementAggregate, error) {
+	group, ok := movementGroups[query.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown movement group %q", query.GroupBy)
+	}
+	period, ok := movementIntervals[query.Interval]
+	if !ok {
+		return nil, fmt.Errorf("unknown movement interval %q", query.Interval)
+	}
+
+	db := r.db.WithContext(ctx).Table("stock_histories AS h").
+		Select(period+` AS period, `+group[0]+` AS group_id, `+group[1]+` AS group_name,
+			COALESCE(SUM(CASE WHEN h.type = 'in' THEN h.quantity ELSE 0 END), 0) AS qty_in,
+			COALESCE(SUM(CASE WHEN h.type = 'out' THEN h.quantity ELSE 0 END), 0) AS qty_out,
+			COUNT(*) AS movements`).
+		Joins("JOIN products p ON p.id = h.product_id").
+		Joins("LEFT JOIN categories c ON c.id = p.category_id").
+		Joins("LEFT JOIN suppliers s ON s.id = p.supplier_id").
+		Where("h.created_at >= ? AND h.created_at < ?", query.From, query.To)
+
+	if query.ProductID != 0 {
+		db = db.Where("h.product_id = ?", query.ProductID)
+	}
+	if query.CategoryID != 0 {
+		db = db.Where("p.category_id IN ("+categorySubtreeSQL+")", query.CategoryID)
+	}
+	if query.SupplierID != 0 {
+		db = db.Where("p.supplier_id = ?", query.SupplierID)
+	}
+	if query.Type != "" {
+		db = db.Where("h.type = ?", query.Type)
+	}
+
+	var aggregates []MovementAggregate
+	err := db.Group("period, group_id, group_name").
+		Order("period, group_name").
+		Scan(&aggregates).Error
+	return aggregates, err
+}
+
+func (r *stockHistoryRepository) Ledger(ctx context.Context, productID uint) ([]LedgerSummary, error) {
+	db := r.db.WithContext(ctx).Table("stock_histories AS h").
+		Select(`h.product_id,
+			(SELECT f.stock_before FROM stock_histories f WHERE f.product_id = h.product_id
+				ORDER BY f.created_at, f.id LIMIT 1) AS opening,
+			COALESCE(SUM(CASE WHEN h.type = 'in' THEN h.quantity ELSE -h.quantity END), 0) AS net_change,
+			(SELECT l.stock_after FROM stock_histories l WHERE l.product_id = h.product_id
+				ORDER BY l.created_at DESC, l.id DESC LIMIT 1) AS closing,
+			COUNT(*) AS movements`).
+		Group("h.product_id")
+	if productID != 0 {
+		db = db.Where("h.product_id = ?", productID)
+	}
+
+	var summaries []LedgerSummary
+	err := db.Scan(&summaries).Error
+	return summaries, err
+}
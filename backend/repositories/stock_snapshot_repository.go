@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StockLevel is the quantity of a product at a point in time
+type StockLevel struct {
+	ProductID  uint    `json:"product_id"`
+	SKU        string  `json:"sku"`
+	Name       string  `json:"name"`
+	CategoryID *uint   `json:"category_id"`
+	Price      float64 `json:"price"`
+	Quantity   float64 `json:"quantity"`
+}
+
+type StockSnapshotRepository interface {
+	// StockAsOf returns the stock of every product that existed at the given
+	// time, replaying movements on top of the latest snapshot before it. A
+	// categoryID also matches its subcategories.
+	StockAsOf(ctx context.Context, at time.Time, categoryID uint) ([]StockLevel, error)
+	// CreateSnapshot stores the stock of every product at the given time and
+	// returns the number of rows written. Existing snapshots are kept.
+	CreateSnapshot(ctx context.Context, at time.Time) (int64, error)
+	HasSnapshot(ctx context.Context, at time.Time) (bool, error)
+}
+
+type stockSnapshotRepository struct {
+	db *gorm.DB
+}
+
+// Stock at @at is the stock_after of the last movement up to @at. Without one
+// it is the stock_before of the first later movement, and a product that never
+// moved still holds its current stock.
+const ledgerStockExpr = `COALESCE(
+	(SELECT h.stock_after FROM stock_histories h
+		WHERE h.product_id = p.id AND h.created_at <= @at
+		ORDER BY h.created_at DESC, h.id DESC LIMIT 1),
+	(SELECT h.stock_before FROM stock_histories h
+		WHERE h.product_id = p.id AND h.created_at > @at
+		ORDER BY h.created_at, h.id LIMIT 1),
+	p.stock)`
+
+// With a snapshot taken at @snap only the movements after it need replaying
+const snapshotStockExpr = `COALESCE(
+	(SELECT h.stock_after FROM stock_histories h
+		WHERE h.product_id = p.id AND h.created_at > @snap AND h.created_at <= @at
+		ORDER BY h.created_at DESC, h.id DESC LIMIT 1),
+	(SELECT s.quantity FROM stock_snapshots s
+		WHERE s.product_id = p.id AND s.snapshot_at = @snap),
+	` + ledgerStockExpr + `)`
+
+// stockAtQuery selects product_id and quantity for every product that existed at @at
+func (r *stockSnapshotRepository) stockAtQuery(ctx context.Context, at time.Time) (string, map[string]interface{}, error) {
+	args := map[string]interface{}{"at": at}
+	expr := ledgerStockExpr
+
+	var snap sql.NullTime
+	if err := r.db.WithContext(ctx).Table("stock_snapshots").
+		Select("MAX(snapshot_at)").Where("snapshot_at <= ?", at).
+		Row().Scan(&snap); err != nil {
+		return "", nil, err
+	}
+	if snap.Valid {
+		args["snap"] = snap.Time
+		expr = snapshotStockExpr
+	}
+
+	return `SELECT p.id AS product_id, p.sku, p.name, p.category_id, p.price, ` + expr + ` AS quantity
+		FROM products p
+		WHERE p.created_at <= @at AND (p.deleted_at IS NULL OR p.deleted_at > @at)`, args, nil
+}
+
+func (r *stockSnapshotRepository) StockAsOf(ctx context.Context, at time.Time, categoryID uint) ([]StockLevel, error) {
+	query, args, err := r.stockAtQuery(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+	if categoryID != 0 {
+		// The query uses named arguments, the subtree takes @category too
+		query += " AND p.category_id IN (" + strings.Replace(categorySubtreeSQL, "?", "@category", 1) + ")"
+		args["category"] = categoryID
+	}
+
+	var levels []StockLevel
+	err = r.db.WithContext(ctx).Raw(query+" ORDER BY p.sku", args).Scan(&levels).Error
+	return levels, err
+}
+
+func (r *stockSnapshotRepository) CreateSnapshot(ctx context.Context, at time.Time) (int64, error) {
+	query, args, err := r.stockAtQuery(ctx, at)
+	if err != nil {
+		return 0, err
+	}
+	args["now"] = time.Now()
+
+	result := r.db.WithContext(ctx).Exec(`INSERT IGNORE INTO stock_snapshots (product_id, snapshot_at, quantity, created_at)
+		SELECT stock.product_id, @at, stock.quantity, @now FROM (`+query+`) AS stock`, args)
+	return result.RowsAffected, result.Error
+}
+
+func (r *stockSnapshotRepository) HasSnapshot(ctx context.Context, at time.Time) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table("stock_snapshots").Where("snapshot_at = ?", at).Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Store groups the repositories used by the service layer and lets a caller
+// run several repository operations inside one database transaction.
+type Store interface {
+	Products() ProductRepository
+	StockHistory() StockHistoryRepository
+	Suppliers() SupplierRepository
+	Categories() CategoryRepository
+	ActivityLogs() ActivityLogRepository
+	ExportJobs() ExportJobRepository
+	ReportSchedules() ReportScheduleRepository
+	StockSnapshots() StockSnapshotRepository
+	Analytics() AnalyticsRepository
+	ReorderProposals() ReorderProposalRepository
+	PurchaseOrders() PurchaseOrderRepository
+	StockAlerts() StockAlertRepository
+	AlertChannels() AlertChannelRepository
+	AlertDeliveries() AlertDeliveryRepository
+	WebhookSubscriptions() WebhookSubscriptionRepository
+	WebhookDeliveries() WebhookDeliveryRepository
+	Outbox() OutboxRepository
+	IdempotencyKeys() IdempotencyKeyRepository
+	Search() SearchRepository
+
+	// Transaction runs fn with a Store bound to a single transaction. The
+	// transaction is committed when fn returns nil and rolled back otherwise.
+	Transaction(ctx context.Context, fn func(tx Store) error) error
+}
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewStore returns a Store backed by the given GORM connection
+func NewStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) Products() ProductRepository {
+	return &productRepository{db: s.db}
+}
+
+func (s *gormStore) StockHistory() StockHistoryRepository {
+	return &stockHistoryRepository{db: s.db}
+}
+
+func (s *gormStore) Suppliers() SupplierRepository {
+	return &supplierRepository{db: s.db}
+}
+
+func (s *gormStore) Categories() CategoryRepository {
+	return &categoryRepository{db: s.db}
+}
+
+func (s *gormStore) ActivityLogs() ActivityLogRepository {
+	return &activityLogRepository{db: s.db}
+}
+
+func (s *gormStore) ExportJobs() ExportJobRepository {
+	return &exportJobRepository{db: s.db}
+}
+
+func (s *gormStore) ReportSchedules() ReportScheduleRepository {
+	return &reportScheduleRepository{db: s.db}
+}
+
+func (s *gormStore) StockSnapshots() StockSnapshotRepository {
+	return &stockSnapshotRepository{db: s.db}
+}
+
+func (s *gormStore) Analytics() AnalyticsRepository {
+	return &analyticsRepository{db: s.db}
+}
+
+func (s *gormStore) ReorderProposals() ReorderProposalRepository {
+	return &reorderProposalRepository{db: s.db}
+}
+
+func (s *gormStore) PurchaseOrders() PurchaseOrderRepository {
+	return &purchaseOrderRepository{db: s.db}
+}
+
+func (s *gormStore) StockAlerts() StockAlertRepository {
+	return &stockAlertRepository{db: s.db}
+}
+
+func (s *gormStore) AlertChannels() AlertChannelRepository {
+	return &alertChannelRepository{db: s.db}
+}
+
+func (s *gormStore) AlertDeliveries() AlertDeliveryRepository {
+	return &alertDeliveryRepository{db: s.db}
+}
+
+func (s *gormStore) WebhookSubscriptions() WebhookSubscriptionRepository {
+	return &webhookSubscriptionRepository{db: s.db}
+}
+
+func (s *gormStore) WebhookDeliveries() WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{db: s.db}
+}
+
+func (s *gormStore) Outbox() OutboxRepository {
+	return &outboxRepository{db: s.db}
+}
+
+func (s *gormStore) IdempotencyKeys() IdempotencyKeyRepository {
+	return &idempotencyKeyRepository{db: s.db}
+}
+
+func (s *gormStore) Search() SearchRepository {
+	return &searchRepository{db: s.db}
+}
+
+func (s *gormStore) Transaction(ctx context.Context, fn func(tx Store) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&gormStore{db: tx})
+	})
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"inventory-backend/models"
+
+	"gorm.io/gorm"
+)
+
+type SupplierRepository interface {
+	List(ctx context.Context) ([]models.Supplier, error)
+	FindByID(ctx context.Context, id uint) (*models.Supplier, error)
+	FindWithProducts(ctx context.Context, id uint) (*models.Supplier, error)
+	Create(ctx context.Context, supplier *models.Supplier) error
+	Save(ctx context.Context, supplier *models.Supplier) error
+	Delete(ctx context.Context, supplier *models.Supplier) error
+}
+
+type supplierRepository struct {
+	db *gorm.DB
+}
+
+func (r *supplierRepository) List(ctx context.Context) ([]models.Supplier, error) {
+	var suppliers []models.Supplier
+	if err := r.db.WithContext(ctx).Find(&suppliers).Error; err != nil {
+		return nil, err
+	}
+	return suppliers, nil
+}
+
+func (r *supplierRepository) FindByID(ctx context.Context, id uint) (*models.Supplier, error) {
+	var supplier models.Supplier
+	if err := r.db.WithContext(ctx).First(&supplier, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &supplier, nil
+}
+
+func (r *supplierRepository) FindWithProducts(ctx context.Context, id uint) (*models.Supplier, error) {
+	var supplier models.Supplier
+	if err := r.db.WithContext(ctx).Preload("Products").First(&supplier, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &supplier, nil
+}
+
+func (r *supplierRepository) Create(ctx context.Context, supplier *models.Supplier) error {
+	supplier.Version = 1
+	return r.db.WithContext(ctx).Create(supplier).Error
+}
+
+func (r *supplierRepository) Save(ctx context.Context, supplier *models.Supplier) error {
+	return saveVersioned(r.db.WithContext(ctx), supplier, &supplier.Version)
+}
+
+func (r *supplierRepository) Delete(ctx context.Context, supplier *models.Supplier) error {
+	return deleteVersioned(r.db.WithContext(ctx), supplier, supplier.Version)
+}
@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// saveVersioned updates every column of model, a pointer to a struct with a
+// primary key, and bumps *version, but only while the stored version is still
+// the one that was loaded. A lost race fails with ErrConflict and leaves
+// *version untouched.
+func saveVersioned(db *gorm.DB, model interface{}, version *uint) error {
+	loaded := *version
+	*version = loaded + 1
+
+	result := db.Model(model).Where("version = ?", loaded).Select("*").Omit(clause.Associations).Updates(model)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrConflict
+	}
+	if result.Error != nil {
+		*version = loaded
+	}
+	return result.Error
+}
+
+// deleteVersioned deletes model unless its stored version moved past version
+func deleteVersioned(db *gorm.DB, model interface{}, version uint) error {
+	result := db.Where("version = ?", version).Delete(model)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return result.Error
+}
@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"context"
+	"inventory-backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WebhookSubscriptionRepository interface {
+	List(ctx context.Context) ([]models.WebhookSubscription, error)
+	ListEnabled(ctx context.Context) ([]models.WebhookSubscription, error)
+	FindByID(ctx context.Context, id uint) (*models.WebhookSubscription, error)
+	Create(ctx context.Context, subscription *models.WebhookSubscription) error
+	Save(ctx context.Context, subscription *models.WebhookSubscription) error
+	Delete(ctx context.Context, subscription *models.WebhookSubscription) error
+}
+
+type webhookSubscriptionRepository struct {
+	db *gorm.DB
+}
+
+func (r *webhookSubscriptionRepository) List(ctx context.Context) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	if err := r.db.WithContext(ctx).Order("id").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (r *webhookSubscriptionRepository) ListEnabled(ctx context.Context) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	if err := r.db.WithContext(ctx).Where("enabled = ?", true).Order("id").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (r *webhookSubscriptionRepository) FindByID(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	if err := r.db.WithContext(ctx).First(&subscription, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &subscription, nil
+}
+
+func (r *webhookSubscriptionRepository) Create(ctx context.Context, subscription *models.WebhookSubscription) error {
+	return r.db.WithContext(ctx).Create(subscription).Error
+}
+
+func (r *webhookSubscriptionRepository) Save(ctx context.Context, subscription *models.WebhookSubscription) error {
+	return r.db.WithContext(ctx).Save(subscription).Error
+}
+
+func (r *webhookSubscriptionRepository) Delete(ctx context.Context, subscription *models.WebhookSubscription) error {
+	return r.db.WithContext(ctx).Delete(subscription).Error
+}
+
+type WebhookDeliveryFilter struct {
+	SubscriptionID uint
+	Status         string
+	Event          string
+	Limit          int
+}
+
+type WebhookDeliveryRepository interface {
+	List(ctx context.Context, filter WebhookDeliveryFilter) ([]models.WebhookDelivery, error)
+	FindByID(ctx context.Context, id uint) (*models.WebhookDelivery, error)
+	// ListDue returns pending deliveries whose next attempt is at or before now
+	ListDue(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)
+	// ExistsForEvent reports whether an event was already queued
+	ExistsForEvent(ctx context.Context, eventID string) (bool, error)
+	Create(ctx context.Context, deliveries []models.WebhookDelivery) error
+	Save(ctx context.Context, delivery *models.WebhookDelivery) error
+	// DeleteFinishedBefore removes succeeded and failed deliveries created
+	// before cutoff
+	DeleteFinishedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+type webhookDeliveryRepository struct {
+	db *gorm.DB
+}
+
+func (r *webhookDeliveryRepository) List(ctx context.Context, filter WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	query := r.db.WithContext(ctx).Omit("payload").Order("id DESC")
+	if filter.SubscriptionID != 0 {
+		query = query.Where("subscription_id = ?", filter.SubscriptionID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Event != "" {
+		query = query.Where("event = ?", filter.Event)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *webhookDeliveryRepository) FindByID(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := r.db.WithContext(ctx).First(&delivery, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &delivery, nil
+}
+
+func (r *webhookDeliveryRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	if err := r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+		Order("next_attempt_at, id").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *webhookDeliveryRepository) ExistsForEvent(ctx context.Context, eventID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("event_id = ?", eventID).Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *webhookDeliveryRepository) Create(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&deliveries).Error
+}
+
+func (r *webhookDeliveryRepository) Save(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Save(delivery).Error
+}
+
+func (r *webhookDeliveryRepository) DeleteFinishedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("status IN ? AND created_at < ?", []string{models.WebhookDeliverySucceeded, models.WebhookDeliveryFailed}, cutoff).
+		Delete(&models.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
@@ -3,11 +3,28 @@ package routes
 import (
 	"inventory-backend/controllers"
 	"inventory-backend/middleware"
+	"inventory-backend/services"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupRoutes(app *fiber.App) {
+func SetupRoutes(app *fiber.App, svc *services.Services) {
+	productController := controllers.NewProductController(svc.Products)
+	stockController := controllers.NewStockController(svc.Stock, svc.Reconciler)
+	supplierController := controllers.NewSupplierController(svc.Suppliers)
+	categoryController := controllers.NewCategoryController(svc.Categories)
+	exportController := controllers.NewExportController(svc.Products)
+	exportJobController := controllers.NewExportJobController(svc.Exports)
+	reportScheduleController := controllers.NewReportScheduleController(svc.Schedules)
+	reportController := controllers.NewReportController(svc.Reports, svc.Snapshots)
+	dashboardController := controllers.NewDashboardController(svc.Dashboard)
+	forecastController := controllers.NewForecastController(svc.Forecasts)
+	replenishmentController := controllers.NewReplenishmentController(svc.Replenishment)
+	stockAlertController := controllers.NewStockAlertController(svc.Alerts)
+	webhookController := controllers.NewWebhookController(svc.Webhooks)
+	outboxController := controllers.NewOutboxController(svc.Outbox)
+	streamController := controllers.NewStreamController(svc.Stream)
+
 	api := app.Group("/api")
 
 	// Auth Routes (Public)
@@ -15,36 +32,80 @@ func SetupRoutes(app *fiber.App) {
 	auth.Post("/register", controllers.Register)
 	auth.Post("/login", controllers.Login)
 
-	// Protected Routes
-	protected := api.Group("/", middleware.AuthRequired)
+	// Real-time Stream (SSE). Registered before the protected group so the
+	// token can also be passed as ?token= by EventSource clients.
+	api.Get("/stream", middleware.TokenFromQuery, middleware.AuthRequired, streamController.Stream)
+
+	// Protected Routes. Mutating requests may carry an Idempotency-Key header.
+	protected := api.Group("/", middleware.AuthRequired, middleware.Idempotency(svc.Idempotency))
 
 	// Suppliers
 	suppliers := protected.Group("/suppliers")
-	suppliers.Get("/", controllers.GetSuppliers)
-	suppliers.Get("/:id", controllers.GetSupplier)
-	suppliers.Post("/", controllers.CreateSupplier)
-	suppliers.Put("/:id", controllers.UpdateSupplier)
-	suppliers.Delete("/:id", controllers.DeleteSupplier)
+	suppliers.Get("/", supplierController.GetSuppliers)
+	suppliers.Get("/:id", supplierController.GetSupplier)
+	suppliers.Post("/", supplierController.CreateSupplier)
+	suppliers.Put("/:id", supplierController.UpdateSupplier)
+	suppliers.Delete("/:id", supplierController.DeleteSupplier)
 
 	// Categories
 	categories := protected.Group("/categories")
-	categories.Get("/", controllers.GetCategories)
-	categories.Post("/", controllers.CreateCategory)
-	categories.Put("/:id", controllers.UpdateCategory)
-	categories.Delete("/:id", controllers.DeleteCategory)
+	categories.Get("/", categoryController.GetCategories)
+	categories.Get("/tree", categoryController.GetCategoryTree)
+	categories.Post("/", categoryController.CreateCategory)
+	categories.Put("/:id", categoryController.UpdateCategory)
+	categories.Post("/:id/move", categoryController.MoveCategory)
+	categories.Delete("/:id", categoryController.DeleteCategory)
+	categories.Get("/:id/attributes", categoryController.GetCategoryAttributes)
 
 	// Products
 	products := protected.Group("/products")
-	products.Get("/", controllers.GetProducts)
-	products.Get("/low-stock", controllers.GetLowStockProducts) // Harus di atas /:id
-	products.Get("/:id", controllers.GetProduct)
-	products.Post("/", controllers.CreateProduct)
-	products.Put("/:id", controllers.UpdateProduct)
-	products.Delete("/:id", controllers.DeleteProduct)
+	products.Get("/", productController.GetProducts)
+	products.Get("/low-stock", productController.GetLowStockProducts) // Harus di atas /:id
+	products.Get("/:id", productController.GetProduct)
+	products.Post("/", productController.CreateProduct)
+	products.Post("/import", productController.ImportProducts)
+	products.Put("/:id", productController.UpdateProduct)
+	products.Delete("/:id", productController.DeleteProduct)
+
+	// Product Variants
+	products.Get("/:id/variants", productController.GetProductVariants)
+	products.Post("/:id/variants", productController.GenerateProductVariants)
+
+	// Units of Measure
+	products.Get("/:id/units", productController.GetProductUnits)
+	products.Put("/:id/units", productController.SetProductUnits)
+
+	// Kits
+	products.Get("/:id/kit", productController.GetProductKit)
+	products.Put("/:id/kit", productController.SetProductKit)
+	products.Post("/:id/assemble", stockController.AssembleKit)
+	products.Post("/:id/disassemble", stockController.DisassembleKit)
 
 	// Stock Management
-	products.Post("/:id/stock", controllers.UpdateStock)
-	products.Get("/:id/history", controllers.GetStockHistory)
+	products.Post("/:id/stock", stockController.UpdateStock)
+	products.Get("/:id/history", stockController.GetStockHistory)
+
+	// Demand Forecast
+	products.Get("/:id/forecast", forecastController.GetProductForecast)
+
+	// Replenishment
+	protected.Get("/replenishment/suggestions", replenishmentController.GetSuggestions)
+
+	// Stock Alerts
+	alerts := protected.Group("/alerts")
+	alerts.Get("/", stockAlertController.GetAlerts)
+	alerts.Get("/:id", stockAlertController.GetAlert)
+	alerts.Post("/:id/acknowledge", stockAlertController.AcknowledgeAlert)
+
+	// Dashboard
+	protected.Get("/dashboard/summary", dashboardController.GetDashboardSummary)
+
+	// Reports
+	reports := protected.Group("/reports")
+	reports.Get("/movements", reportController.GetMovementReport)
+	reports.Get("/stock-as-of", reportController.GetStockAsOf)
+	reports.Get("/abc", reportController.GetABCAnalysis)
+	reports.Get("/dead-stock", reportController.GetDeadStock)
 
 	// Profile Routes
 	profile := protected.Group("/profile")
@@ -53,10 +114,77 @@ func SetupRoutes(app *fiber.App) {
 
 	// Admin Routes
 	admin := protected.Group("/admin", middleware.AdminOnly) // Assuming middleware.AdminRequired needs to be implemented or reused
+	// Product Search
+	admin.Post("/search/reindex", productController.ReindexSearch)
+
+	// Custom Product Attributes
+	admin.Post("/categories/:id/attributes", categoryController.CreateCategoryAttribute)
+	admin.Put("/category-attributes/:id", categoryController.UpdateCategoryAttribute)
+	admin.Delete("/category-attributes/:id", categoryController.DeleteCategoryAttribute)
+
 	// Export Routes
-	admin.Get("/export/products", controllers.ExportProducts)
+	admin.Get("/export/products", exportController.ExportProducts)
 	admin.Get("/export/logs", controllers.ExportActivityLogs)
 
+	// Background Export Jobs
+	admin.Post("/exports", exportJobController.CreateExportJob)
+	admin.Get("/exports", exportJobController.GetExportJobs)
+	admin.Get("/exports/:id", exportJobController.GetExportJob)
+	admin.Get("/exports/:id/download", exportJobController.DownloadExportJob)
+
+	// Stock Reconciliation
+	admin.Get("/stock/reconciliation", stockController.GetReconciliation)
+	admin.Post("/stock/reconciliation", stockController.ApplyReconciliation)
+
+	// Stock Snapshots
+	admin.Post("/stock-snapshots", reportController.CreateStockSnapshot)
+
+	// Product Classification
+	admin.Post("/reports/abc", reportController.ApplyABCAnalysis)      // Stores the classes on the products
+	admin.Post("/reports/dead-stock", reportController.ApplyDeadStock) // Refreshes the dead stock flags
+
+	// Reorder Points
+	admin.Post("/reorder-proposals", forecastController.ProposeReorderPoints)
+	admin.Get("/reorder-proposals", forecastController.GetReorderProposals)
+	admin.Post("/reorder-proposals/approve", forecastController.ApproveReorderProposals)
+	admin.Post("/reorder-proposals/reject", forecastController.RejectReorderProposals)
+
+	// Purchase Orders
+	admin.Post("/purchase-orders/from-suggestions", replenishmentController.CreateDraftOrders)
+	admin.Get("/purchase-orders", replenishmentController.GetPurchaseOrders)
+	admin.Get("/purchase-orders/:id", replenishmentController.GetPurchaseOrder)
+	admin.Post("/purchase-orders/:id/cancel", replenishmentController.CancelPurchaseOrder)
+
+	// Alert Channels
+	admin.Get("/alert-channels", stockAlertController.GetAlertChannels)
+	admin.Post("/alert-channels", stockAlertController.CreateAlertChannel)
+	admin.Put("/alert-channels/:id", stockAlertController.UpdateAlertChannel)
+	admin.Delete("/alert-channels/:id", stockAlertController.DeleteAlertChannel)
+	admin.Post("/alert-channels/:id/test", stockAlertController.TestAlertChannel)
+
+	// Webhooks
+	admin.Get("/webhooks", webhookController.GetWebhooks)
+	admin.Post("/webhooks", webhookController.CreateWebhook)
+	admin.Get("/webhooks/:id", webhookController.GetWebhook)
+	admin.Put("/webhooks/:id", webhookController.UpdateWebhook)
+	admin.Delete("/webhooks/:id", webhookController.DeleteWebhook)
+	admin.Post("/webhooks/:id/rotate-secret", webhookController.RotateWebhookSecret)
+	admin.Post("/webhooks/:id/test", webhookController.TestWebhook)
+	admin.Get("/webhooks/:id/deliveries", webhookController.GetWebhookDeliveries)
+	admin.Get("/webhook-deliveries/:id", webhookController.GetWebhookDelivery)
+	admin.Post("/webhook-deliveries/:id/retry", webhookController.RetryWebhookDelivery)
+
+	// Event Outbox
+	admin.Get("/outbox", outboxController.GetOutbox)
+
+	// Scheduled Reports
+	admin.Get("/report-schedules", reportScheduleController.GetReportSchedules)
+	admin.Get("/report-schedules/:id", reportScheduleController.GetReportSchedule)
+	admin.Post("/report-schedules", reportScheduleController.CreateReportSchedule)
+	admin.Put("/report-schedules/:id", reportScheduleController.UpdateReportSchedule)
+	admin.Delete("/report-schedules/:id", reportScheduleController.DeleteReportSchedule)
+	admin.Post("/report-schedules/:id/run", reportScheduleController.RunReportSchedule)
+
 	admin.Get("/users", controllers.GetAllUsers) // New endpoint to get all users
 	admin.Get("/users/pending", controllers.GetPendingUsers)
 	admin.Get("/logs", controllers.GetActivityLogs) // Audit Logs
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"inventory-backend/repositories"
+	"time"
+)
+
+// ABC classes
+const (
+	AbcClassA = "A"
+	AbcClassB = "B"
+	AbcClassC = "C"
+)
+
+// ABCParams configures an ABC analysis. Products are ranked by consumption
+// value and take class A until the running share reaches AShare percent of
+// the total, then class B until BShare percent, the rest is class C.
+type ABCParams struct {
+	Days   int
+	AShare float64
+	BShare float64
+}
+
+// ABCItem is one classified product
+type ABCItem struct {
+	repositories.ProductConsumption
+	Share           float64 `json:"share"`            // percent of the total consumption value
+	CumulativeShare float64 `json:"cumulative_share"` // running share including this product
+	Class           string  `json:"class"`
+}
+
+// ABCClassSummary totals one class
+type ABCClassSummary struct {
+	Class    string  `json:"class"`
+	Products int     `json:"products"`
+	Value    float64 `json:"consumption_value"`
+	Share    float64 `json:"share"`
+}
+
+// ABCReport is the result of an ABC analysis
+type ABCReport struct {
+	From       time.Time         `json:"from"`
+	To         time.Time         `json:"to"`
+	AShare     float64           `json:"a_share"`
+	BShare     float64           `json:"b_share"`
+	TotalValue float64           `json:"total_value"`
+	Classes    []ABCClassSummary `json:"classes"`
+	Items      []ABCItem         `json:"items"`
+	Applied    bool              `json:"applied"`
+}
+
+// DeadStockReport lists products without outbound movements since a cutoff
+type DeadStockReport struct {
+	Days       int                        `json:"days"`
+	Since      time.Time                  `json:"since"`
+	TotalUnits float64                    `json:"total_units"`
+	TotalValue float64                    `json:"total_value"`
+	Products   []repositories.IdleProduct `json:"products"`
+	Applied    bool                       `json:"applied"`
+}
+
+// ABC classifies every product by the value of its outbound movements over
+// the last Days days. With apply set the classes are stored on the products
+// so they can be filtered on.
+func (s *ReportService) ABC(ctx context.Context, userID uint, params ABCParams, apply bool) (*ABCReport, error) {
+	if params.Days == 0 {
+		params.Days = 90
+	}
+	if params.AShare == 0 {
+		params.AShare = 80
+	}
+	if params.BShare == 0 {
+		params.BShare = 95
+	}
+	if params.Days < 1 || params.Days > 730 {
+		return nil, invalid("Days must be between 1 and 730")
+	}
+	if params.AShare <= 0 || params.AShare >= params.BShare || params.BShare > 100 {
+		return nil, invalid("Shares must satisfy 0 < a < b <= 100")
+	}
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -params.Days)
+	rows, err := s.store.Analytics().Consumption(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ABCReport{From: from, To: to, AShare: params.AShare, BShare: params.BShare, Items: make([]ABCItem, 0, len(rows))}
+	for _, row := range rows {
+		report.TotalValue += row.Value
+	}
+
+	summaries := map[string]*ABCClassSummary{
+		AbcClassA: {Class: AbcClassA},
+		AbcClassB: {Class: AbcClassB},
+		AbcClassC: {Class: AbcClassC},
+	}
+	ids := map[string][]uint{}
+	var cumulative float64
+	for _, row := range rows {
+		item := ABCItem{ProductConsumption: row, Class: AbcClassC}
+		if report.TotalValue > 0 && row.Value > 0 {
+			// A product belongs to a class when the share before it is still
+			// below the threshold, so the top seller is always class A
+			before := cumulative
+			item.Share = row.Value / report.TotalValue * 100
+			cumulative += item.Share
+			item.CumulativeShare = cumulative
+			switch {
+			case before < params.AShare:
+				item.Class = AbcClassA
+			case before < params.BShare:
+				item.Class = AbcClassB
+			}
+		} else {
+			item.CumulativeShare = cumulative
+		}
+
+		summary := summaries[item.Class]
+		summary.Products++
+		summary.Value += row.Value
+		summary.Share += item.Share
+		ids[item.Class] = append(ids[item.Class], row.ProductID)
+		report.Items = append(report.Items, item)
+	}
+	report.Classes = []ABCClassSummary{*summaries[AbcClassA], *summaries[AbcClassB], *summaries[AbcClassC]}
+
+	if !apply {
+		return report, nil
+	}
+
+	err = s.store.Transaction(ctx, func(tx repositories.Store) error {
+		for class, classIDs := range ids {
+			if err := tx.Products().SetAbcClass(ctx, class, classIDs); err != nil {
+				return err
+			}
+		}
+		logActivity(ctx, tx, userID, "CLASSIFY", "Product", 0, fmt.Sprintf("Stored ABC classes over %d days: %d A, %d B, %d C",
+			params.Days, summaries[AbcClassA].Products, summaries[AbcClassB].Products, summaries[AbcClassC].Products))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Applied = true
+
+	return report, nil
+}
+
+// DeadStock lists products holding stock without any outbound movement in
+// the last days days. Products created within that window are not included.
+// With apply set the dead stock flag of every product is updated.
+func (s *ReportService) DeadStock(ctx context.Context, userID uint, days int, apply bool) (*DeadStockReport, error) {
+	if days == 0 {
+		days = 90
+	}
+	if days < 1 || days > 3650 {
+		return nil, invalid("Days must be between 1 and 3650")
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	products, err := s.store.Analytics().IdleProducts(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DeadStockReport{Days: days, Since: since, Products: products}
+	ids := make([]uint, 0, len(products))
+	for _, p := range products {
+		report.TotalUnits += p.Stock
+		report.TotalValue += p.Value
+		ids = append(ids, p.ProductID)
+	}
+	report.TotalUnits = roundQuantity(report.TotalUnits)
+
+	if !apply {
+		return report, nil
+	}
+
+	err = s.store.Transaction(ctx, func(tx repositories.Store) error {
+		if err := tx.Products().SetDeadStock(ctx, ids); err != nil {
+			return err
+		}
+		logActivity(ctx, tx, userID, "CLASSIFY", "Product", 0, fmt.Sprintf("Flagged %d products as dead stock (no outbound movement in %d days)", len(ids), days))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Applied = true
+
+	return report, nil
+}
+
+// Report converts the analysis into a table for PDF or XLSX rendering
+func (r *ABCReport) Report() *Report {
+	report := &Report{
+		Title: "ABC Analysis",
+		Subtitle: fmt.Sprintf("Consumption %s to %s, A up to %.0f%%, B up to %.0f%%, total value %.2f",
+			r.From.Format("2006-01-02"), r.To.Format("2006-01-02"), r.AShare, r.BShare, r.TotalValue),
+		Columns:     []string{"Class", "SKU", "Name", "Qty Out", "Price", "Value", "Share %", "Cumulative %"},
+		Rows:        [][]interface{}{},
+		GeneratedAt: time.Now(),
+	}
+	for _, item := range r.Items {
+		report.Rows = append(report.Rows, []interface{}{item.Class, item.SKU, item.Name, reportQuantity(item.QtyOut), item.Price, item.Value,
+			fmt.Sprintf("%.2f", item.Share), fmt.Sprintf("%.2f", item.CumulativeShare)})
+	}
+	return report
+}
+
+// Report converts the dead stock list into a table for PDF or XLSX rendering
+func (r *DeadStockReport) Report() *Report {
+	report := &Report{
+		Title:       "Dead Stock Report",
+		Subtitle:    fmt.Sprintf("%d products without outbound movement since %s", len(r.Products), r.Since.Format("2006-01-02")),
+		Columns:     []string{"SKU", "Name", "Stock", "Price", "Value", "Last Out"},
+		Rows:        [][]interface{}{},
+		GeneratedAt: time.Now(),
+	}
+	for _, p := range r.Products {
+		lastOut := "never"
+		if p.LastOutAt != nil {
+			lastOut = p.LastOutAt.Format("2006-01-02")
+		}
+		report.Rows = append(report.Rows, []interface{}{p.SKU, p.Name, reportQuantity(p.Stock), p.Price, p.Value, lastOut})
+	}
+	report.Rows = append(report.Rows, []interface{}{"TOTAL", "", reportQuantity(r.TotalUnits), nil, r.TotalValue, ""})
+	return report
+}
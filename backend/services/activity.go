@@ -0,0 +1,24 @@
+package services
+
+import (
+	"context"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"log"
+)
+
+// logActivity writes an audit entry through the given store. A failure is only
+// logged so that auditing never aborts the operation being audited.
+func logActivity(ctx context.Context, store repositories.Store, userID uint, action, entity string, entityID uint, details string) {
+	activity := models.ActivityLog{
+		UserID:   userID,
+		Action:   action,
+		Entity:   entity,
+		EntityID: entityID,
+		Details:  details,
+	}
+
+	if err := store.ActivityLogs().Create(ctx, &activity); err != nil {
+		log.Printf("Failed to create activity log: %v", err)
+	}
+}
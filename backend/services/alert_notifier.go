@@ -0,0 +1,96 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"inventory-backend/models"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Stock alert events
+const (
+	AlertEventLowStock  = "stock.low"
+	AlertEventRecovered = "stock.recovered"
+	AlertEventTest      = "alert.test"
+)
+
+// AlertEvent is the payload delivered to alert channels
+type AlertEvent struct {
+	Event      string    `json:"event"`
+	AlertID    uint      `json:"alert_id"`
+	ProductID  uint      `json:"product_id"`
+	SKU        string    `json:"sku"`
+	Name       string    `json:"name"`
+	Stock      float64   `json:"stock"`
+	MinStock   float64   `json:"min_stock"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// AlertNotifier delivers an alert event to one kind of channel
+type AlertNotifier interface {
+	Notify(ctx context.Context, channel models.AlertChannel, event AlertEvent) error
+}
+
+// WebhookNotifier posts the event as JSON to the channel URL
+type WebhookNotifier struct {
+	Client *http.Client
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, channel models.AlertChannel, event AlertEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, channel.Target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Inventory-Event", event.Event)
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// EmailNotifier mails the event to the recipients of the channel
+type EmailNotifier struct {
+	Sender Sender
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, channel models.AlertChannel, event AlertEvent) error {
+	var subject, summary string
+	switch event.Event {
+	case AlertEventLowStock:
+		subject = fmt.Sprintf("[Inventory] Low stock: %s (%s)", event.Name, event.SKU)
+		summary = "dropped below its minimum stock"
+	case AlertEventRecovered:
+		subject = fmt.Sprintf("[Inventory] Stock recovered: %s (%s)", event.Name, event.SKU)
+		summary = "is back at or above its minimum stock"
+	default:
+		subject = "[Inventory] Test alert"
+		summary = "is a test notification"
+	}
+
+	body := fmt.Sprintf("%s (%s) %s.\n\nStock: %s\nMin stock: %s\nTime: %s\n",
+		event.Name, event.SKU, summary, formatQuantity(event.Stock), formatQuantity(event.MinStock), event.OccurredAt.Format("2006-01-02 15:04:05"))
+
+	return n.Sender.Send(ctx, Message{
+		To:      splitRecipients(channel.Target),
+		Subject: subject,
+		Body:    body,
+	})
+}
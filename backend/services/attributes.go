@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxAttributeOptions bounds the values of one enum attribute
+const maxAttributeOptions = 100
+
+// attributeKeyPattern is the form of attribute keys, e.g. voltage or
+// max_load_kg, so they can be used in query parameters
+var attributeKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// AttributeDefinitionInput holds the fields of a custom attribute. Key and
+// Type cannot change once the attribute exists.
+type AttributeDefinitionInput struct {
+	Key      string
+	Label    string
+	Type     string
+	Required bool
+	Options  []string // Enum values, in display order
+	Position int
+}
+
+// ListAttributes returns the attribute definitions that apply to the
+// products of a category, inherited ones first
+func (s *CategoryService) ListAttributes(ctx context.Context, categoryID uint) ([]models.AttributeDefinition, error) {
+	if _, err := s.store.Categories().FindByID(ctx, categoryID); err != nil {
+		return nil, notFound(err, ErrCategoryNotFound)
+	}
+	return s.store.Categories().ListAttributes(ctx, categoryID)
+}
+
+// CreateAttribute defines a custom attribute on a category. Its key must be
+// unique along the category path, i.e. not used by an ancestor or a
+// descendant of the category. A new required attribute is enforced the next
+// time the attributes of an existing product are edited.
+func (s *CategoryService) CreateAttribute(ctx context.Context, categoryID uint, input AttributeDefinitionInput) (*models.AttributeDefinition, error) {
+	input, err := normalizeAttributeDefinition(input)
+	if err != nil {
+		return nil, err
+	}
+
+	attribute := models.AttributeDefinition{
+		CategoryID: categoryID,
+		Key:        input.Key,
+		Label:      input.Label,
+		Type:       input.Type,
+		Required:   input.Required,
+		Options:    strings.Join(input.Options, ","),
+		Position:   input.Position,
+	}
+
+	err = s.store.Transaction(ctx, func(tx repositories.Store) error {
+		// Locks the tree so a concurrent move cannot bring in the same key
+		categories, err := tx.Categories().ListForUpdate(ctx)
+		if err != nil {
+			return err
+		}
+		if findCategory(categories, categoryID) == nil {
+			return ErrCategoryNotFound
+		}
+		above, err := tx.Categories().ListAttributes(ctx, categoryID)
+		if err != nil {
+			return err
+		}
+		below, err := tx.Categories().ListSubtreeAttributes(ctx, categoryID)
+		if err != nil {
+			return err
+		}
+		if err := checkAttributeKeys(append(above, below...), []models.AttributeDefinition{attribute}); err != nil {
+			return err
+		}
+		return tx.Categories().CreateAttribute(ctx, &attribute)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &attribute, nil
+}
+
+// UpdateAttribute changes the label, required flag, options and position of
+// an attribute. Enum options still used by products cannot be removed.
+func (s *CategoryService) UpdateAttribute(ctx context.Context, id uint, input AttributeDefinitionInput) (*models.AttributeDefinition, error) {
+	var attribute *models.AttributeDefinition
+	err := s.store.Transaction(ctx, func(tx repositories.Store) error {
+		var err error
+		attribute, err = tx.Categories().FindAttribute(ctx, id)
+		if err != nil {
+			return notFound(err, ErrAttributeNotFound)
+		}
+		if input.Key == "" {
+			input.Key = attribute.Key
+		}
+		if input.Type == "" {
+			input.Type = attribute.Type
+		}
+		if input.Key != attribute.Key || input.Type != attribute.Type {
+			return invalid("The key and type of an attribute cannot change, define a new attribute instead")
+		}
+		input, err = normalizeAttributeDefinition(input)
+		if err != nil {
+			return err
+		}
+
+		if attribute.Type == models.AttributeEnum {
+			used, err := tx.Products().CountAttributeValues(ctx, attribute.ID, input.Options)
+			if err != nil {
+				return err
+			}
+			if used > 0 {
+				return invalid(fmt.Sprintf("%d products use options of %s that are no longer listed", used, attribute.Label))
+			}
+		}
+
+		attribute.Label = input.Label
+		attribute.Required = input.Required
+		attribute.Options = strings.Join(input.Options, ",")
+		attribute.Position = input.Position
+		return tx.Categories().SaveAttribute(ctx, attribute)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attribute, nil
+}
+
+// DeleteAttribute removes an attribute together with its product values
+func (s *CategoryService) DeleteAttribute(ctx context.Context, id uint) error {
+	return s.store.Transaction(ctx, func(tx repositories.Store) error {
+		attribute, err := tx.Categories().FindAttribute(ctx, id)
+		if err != nil {
+			return notFound(err, ErrAttributeNotFound)
+		}
+		if err := tx.Products().DeleteAttributeValues(ctx, attribute.ID); err != nil {
+			return err
+		}
+		return tx.Categories().DeleteAttribute(ctx, attribute)
+	})
+}
+
+// checkAttributeKeys rejects attributes of below that reuse the key of an
+// attribute of above, so no product gets two attributes with one key
+func checkAttributeKeys(above []models.AttributeDefinition, below []models.AttributeDefinition) error {
+	for _, attribute := range below {
+		for _, other := range above {
+			if attribute.Key == other.Key {
+				return invalid(fmt.Sprintf("Attribute %s is already defined on category #%d", attribute.Key, other.CategoryID))
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeAttributeDefinition trims the fields and checks the type and the
+// enum options
+func normalizeAttributeDefinition(input AttributeDefinitionInput) (AttributeDefinitionInput, error) {
+	input.Key = strings.TrimSpace(input.Key)
+	if !attributeKeyPattern.MatchString(input.Key) {
+		return input, invalid("Attribute key must start with a letter and hold at most 50 lowercase letters, digits and underscores")
+	}
+	input.Label = strings.TrimSpace(input.Label)
+	if input.Label == "" {
+		input.Label = input.Key
+	}
+	if len(input.Label) > 100 {
+		return input, invalid("Attribute label must be at most 100 characters")
+	}
+
+	switch input.Type {
+	case models.AttributeString, models.AttributeNumber, models.AttributeBool, models.AttributeDate:
+		if len(input.Options) > 0 {
+			return input, invalid("Only enum attributes have options")
+		}
+	case models.AttributeEnum:
+		if len(input.Options) == 0 || len(input.Options) > maxAttributeOptions {
+			return input, invalid(fmt.Sprintf("An enum attribute needs between 1 and %d options", maxAttributeOptions))
+		}
+		seen := map[string]bool{}
+		options := make([]string, len(input.Options))
+		for i, option := range input.Options {
+			option = strings.TrimSpace(option)
+			if option == "" || len(option) > 255 || strings.Contains(option, ",") {
+				return input, invalid("Enum options are required, at most 255 characters and cannot contain commas")
+			}
+			if seen[strings.ToLower(option)] {
+				return input, invalid(fmt.Sprintf("Option %s is listed twice", option))
+			}
+			seen[strings.ToLower(option)] = true
+			options[i] = option
+		}
+		input.Options = options
+	default:
+		return input, invalid("Attribute type must be 'string', 'number', 'bool', 'enum' or 'date'")
+	}
+	return input, nil
+}
+
+// resolveAttributes merges changes into the current attribute values of a
+// product and validates the result against the definitions of its category.
+// A nil or empty change removes a value. Values whose definition no longer
+// applies, e.g. after a category change, are dropped.
+func resolveAttributes(ctx context.Context, tx repositories.Store, product *models.Product, current []models.ProductAttribute, changes map[string]interface{}) ([]models.ProductAttribute, error) {
+	var definitions []models.AttributeDefinition
+	if product.CategoryID != nil {
+		var err error
+		definitions, err = tx.Categories().ListAttributes(ctx, *product.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	defined := make(map[string]bool, len(definitions))
+	for _, definition := range definitions {
+		defined[definition.Key] = true
+	}
+	for key := range changes {
+		if !defined[key] {
+			return nil, invalid(fmt.Sprintf("Unknown attribute %s for the category of %s", key, product.SKU))
+		}
+	}
+
+	attributes := []models.ProductAttribute{}
+	for _, definition := range definitions {
+		attribute := models.ProductAttribute{ProductID: product.ID, AttributeID: definition.ID, Key: definition.Key, Type: definition.Type}
+		change, changed := changes[definition.Key]
+		switch {
+		case changed && !isEmptyAttribute(change):
+			value, number, err := normalizeAttributeValue(definition, change)
+			if err != nil {
+				return nil, err
+			}
+			attribute.Value, attribute.Number = value, number
+		case changed:
+			attribute.Value = ""
+		default:
+			for _, existing := range current {
+				if existing.AttributeID == definition.ID {
+					attribute.Value, attribute.Number = existing.Value, existing.Number
+				}
+			}
+		}
+
+		if attribute.Value == "" {
+			if definition.Required {
+				return nil, invalid(fmt.Sprintf("%s is required", definition.Label))
+			}
+			continue
+		}
+		attributes = append(attributes, attribute)
+	}
+	return attributes, nil
+}
+
+func isEmptyAttribute(value interface{}) bool {
+	text, isText := value.(string)
+	return value == nil || isText && strings.TrimSpace(text) == ""
+}
+
+// normalizeAttributeValue converts a value given as JSON or as text to the
+// canonical text form of its type, plus the number of number attributes
+func normalizeAttributeValue(definition models.AttributeDefinition, value interface{}) (string, *float64, error) {
+	text, isText := value.(string)
+	text = strings.TrimSpace(text)
+
+	switch definition.Type {
+	case models.AttributeNumber:
+		number, isNumber := value.(float64)
+		if isText {
+			var err error
+			number, err = strconv.ParseFloat(text, 64)
+			isNumber = err == nil
+		}
+		if !isNumber || math.IsInf(number, 0) || math.IsNaN(number) {
+			return "", nil, invalid(fmt.Sprintf("%s must be a number", definition.Label))
+		}
+		number = math.Round(number*factorScale) / factorScale
+		return strconv.FormatFloat(number, 'f', -1, 64), &number, nil
+
+	case models.AttributeBool:
+		flag, isBool := value.(bool)
+		if isText {
+			var err error
+			flag, err = strconv.ParseBool(text)
+			isBool = err == nil
+		}
+		if !isBool {
+			return "", nil, invalid(fmt.Sprintf("%s must be true or false", definition.Label))
+		}
+		return strconv.FormatBool(flag), nil, nil
+
+	case models.AttributeEnum:
+		for _, option := range strings.Split(definition.Options, ",") {
+			if isText && strings.EqualFold(option, text) {
+				return option, nil, nil
+			}
+		}
+		return "", nil, invalid(fmt.Sprintf("%s must be one of %s", definition.Label, strings.ReplaceAll(definition.Options, ",", ", ")))
+
+	case models.AttributeDate:
+		date, err := time.Parse("2006-01-02", text)
+		if err != nil {
+			date, err = time.Parse(time.RFC3339, text)
+		}
+		if !isText || err != nil {
+			return "", nil, invalid(fmt.Sprintf("%s must be a date as YYYY-MM-DD", definition.Label))
+		}
+		return date.Format("2006-01-02"), nil, nil
+
+	default:
+		if !isText || len(text) > 255 {
+			return "", nil, invalid(fmt.Sprintf("%s must be text of at most 255 characters", definition.Label))
+		}
+		return text, nil, nil
+	}
+}
+
+// copyAttributes gives a new variant the attribute values of its parent
+func copyAttributes(ctx context.Context, tx repositories.Store, parent *models.Product, variant *models.Product) error {
+	attributes, err := tx.Products().ListAttributes(ctx, parent.ID)
+	if err != nil || len(attributes) == 0 {
+		return err
+	}
+	for i := range attributes {
+		attributes[i].ID = 0
+		attributes[i].ProductID = variant.ID
+	}
+	variant.Attributes = attributes
+	return tx.Products().ReplaceAttributes(ctx, variant.ID, attributes)
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"context"
+	"time"
+)
+
+// every calls fn immediately and then on every tick of interval until ctx is cancelled
+func every(ctx context.Context, interval time.Duration, fn func(context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fn(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"sort"
+	"time"
+)
+
+// CategoryInput holds category fields
+type CategoryInput struct {
+	Name        string
+	Description string
+	ParentID    *uint // Create only, categories change parent through Move
+}
+
+// CategoryDeleteOptions decides what happens to the subcategories and
+// products of a deleted category. Without Reassign a category that still has
+// either cannot be deleted.
+type CategoryDeleteOptions struct {
+	Reassign bool
+	// TargetID receives the subcategories and products, the parent of the
+	// deleted category when nil
+	TargetID *uint
+}
+
+type CategoryService struct {
+	store  repositories.Store
+	events EventPublisher
+	search SearchIndex
+}
+
+func NewCategoryService(store repositories.Store, events EventPublisher, search SearchIndex) *CategoryService {
+	return &CategoryService{store: store, events: events, search: search}
+}
+
+func (s *CategoryService) List(ctx context.Context) ([]models.Category, error) {
+	return s.store.Categories().List(ctx)
+}
+
+// Tree returns the top level categories with their descendants nested in
+// Children, siblings by name
+func (s *CategoryService) Tree(ctx context.Context) ([]models.Category, error) {
+	categories, err := s.store.Categories().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	children := map[uint][]models.Category{}
+	var roots []models.Category
+	for _, category := range categories {
+		if category.ParentID == nil {
+			roots = append(roots, category)
+		} else {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		}
+	}
+
+	var build func(nodes []models.Category) []models.Category
+	build = func(nodes []models.Category) []models.Category {
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+		for i := range nodes {
+			nodes[i].Children = build(children[nodes[i].ID])
+		}
+		return nodes
+	}
+	return append([]models.Category{}, build(roots)...), nil
+}
+
+func (s *CategoryService) Create(ctx context.Context, input CategoryInput) (*models.Category, error) {
+	if input.Name == "" {
+		return nil, invalid("Category name is required")
+	}
+
+	category := models.Category{
+		Name:        input.Name,
+		Description: input.Description,
+		ParentID:    input.ParentID,
+	}
+
+	err := s.store.Transaction(ctx, func(tx repositories.Store) error {
+		if category.ParentID != nil {
+			if _, err := tx.Categories().FindByID(ctx, *category.ParentID); err != nil {
+				return notFound(err, ErrCategoryNotFound)
+			}
+		}
+		if err := releaseCategoryName(ctx, tx, category.ParentID, category.Name, 0); err != nil {
+			return err
+		}
+		return tx.Categories().Create(ctx, &category)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+func (s *CategoryService) Update(ctx context.Context, id uint, input CategoryInput) (*models.Category, error) {
+	category, err := s.store.Categories().FindByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrCategoryNotFound)
+	}
+
+	renamed := input.Name != category.Name
+	category.Name = input.Name
+	category.Description = input.Description
+
+	err = s.store.Transaction(ctx, func(tx repositories.Store) error {
+		if renamed {
+			if err := releaseCategoryName(ctx, tx, category.ParentID, category.Name, category.ID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Categories().Save(ctx, category); err != nil {
+			return err
+		}
+		// Product search covers the category path, subcategories included
+		if renamed {
+			return reindexProducts(ctx, tx, s.search, repositories.ProductFilter{CategoryID: category.ID})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// Move gives a category a new parent, nil for the top level, taking its
+// whole subtree along. A category cannot move below itself or one of its
+// descendants, nor below a category whose attributes it redefines. The
+// categories are locked so concurrent moves cannot build a cycle together.
+func (s *CategoryService) Move(ctx context.Context, id uint, parentID *uint) (*models.Category, error) {
+	var category *models.Category
+	err := s.store.Transaction(ctx, func(tx repositories.Store) error {
+		categories, err := tx.Categories().ListForUpdate(ctx)
+		if err != nil {
+			return err
+		}
+		category = findCategory(categories, id)
+		if category == nil {
+			return ErrCategoryNotFound
+		}
+
+		if parentID != nil {
+			parent := findCategory(categories, *parentID)
+			if parent == nil {
+				return ErrCategoryNotFound
+			}
+			if inSubtree(categories, id, parent.ID) {
+				return invalid(fmt.Sprintf("Cannot move %s below itself or one of its subcategories", category.Name))
+			}
+
+			above, err := tx.Categories().ListAttributes(ctx, parent.ID)
+			if err != nil {
+				return err
+			}
+			below, err := tx.Categories().ListSubtreeAttributes(ctx, id)
+			if err != nil {
+				return err
+			}
+			if err := checkAttributeKeys(above, below); err != nil {
+				return err
+			}
+		}
+
+		if err := releaseCategoryName(ctx, tx, parentID, category.Name, category.ID); err != nil {
+			return err
+		}
+		category.ParentID = parentID
+		if err := tx.Categories().Save(ctx, category); err != nil {
+			return err
+		}
+		return reindexProducts(ctx, tx, s.search, repositories.ProductFilter{CategoryID: category.ID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// Delete removes a category. A category with subcategories or products is
+// only deleted when options ask to reassign them, so products are never left
+// pointing at a deleted category. The attributes of the category go with it,
+// values included.
+func (s *CategoryService) Delete(ctx context.Context, id uint, options CategoryDeleteOptions) error {
+	return s.store.Transaction(ctx, func(tx repositories.Store) error {
+		categories, err := tx.Categories().ListForUpdate(ctx)
+		if err != nil {
+			return err
+		}
+		category := findCategory(categories, id)
+		if category == nil {
+			return ErrCategoryNotFound
+		}
+
+		if !options.Reassign {
+			children, err := tx.Categories().CountChildren(ctx, id)
+			if err != nil {
+				return err
+			}
+			products, err := tx.Products().CountByCategory(ctx, id)
+			if err != nil {
+				return err
+			}
+			if children > 0 || products > 0 {
+				return ErrCategoryInUse
+			}
+			return deleteCategory(ctx, tx, category)
+		}
+
+		target := category.ParentID
+		if options.TargetID != nil {
+			if findCategory(categories, *options.TargetID) == nil {
+				return ErrCategoryNotFound
+			}
+			if inSubtree(categories, id, *options.TargetID) {
+				return invalid(fmt.Sprintf("Cannot reassign to %s itself or one of its subcategories", category.Name))
+			}
+			target = options.TargetID
+		}
+		if target != nil {
+			if err := checkReassignedAttributes(ctx, tx, id, *target); err != nil {
+				return err
+			}
+		} else {
+			// Subcategories can become top level, products need a category
+			products, err := tx.Products().CountByCategory(ctx, id)
+			if err != nil {
+				return err
+			}
+			if products > 0 {
+				return invalid(fmt.Sprintf("%s is a top level category with %d products, choose a category to reassign them to", category.Name, products))
+			}
+		}
+
+		// The category paths of the whole subtree change
+		affected, err := tx.Products().ListIDs(ctx, repositories.ProductFilter{CategoryID: id})
+		if err != nil {
+			return err
+		}
+		attributes, err := tx.Categories().ListSubtreeAttributes(ctx, id)
+		if err != nil {
+			return err
+		}
+		for i := range attributes {
+			if attributes[i].CategoryID != id {
+				continue
+			}
+			if err := tx.Products().DeleteAttributeValues(ctx, attributes[i].ID); err != nil {
+				return err
+			}
+			if err := tx.Categories().DeleteAttribute(ctx, &attributes[i]); err != nil {
+				return err
+			}
+		}
+		// The deleted category gives up its name first, a subcategory moving
+		// up may share it
+		if err := deleteCategory(ctx, tx, category); err != nil {
+			return err
+		}
+		for _, child := range categories {
+			if child.ParentID != nil && *child.ParentID == id {
+				if err := releaseCategoryName(ctx, tx, target, child.Name, child.ID); err != nil {
+					return err
+				}
+			}
+		}
+		if err := tx.Categories().MoveChildren(ctx, id, target); err != nil {
+			return err
+		}
+		if err := tx.Products().MoveCategory(ctx, id, target); err != nil {
+			return err
+		}
+		if err := s.search.Index(ctx, tx, affected...); err != nil {
+			return err
+		}
+
+		// Reloaded so the events carry the versions bumped by the move
+		products, err := tx.Products().ListByIDs(ctx, affected)
+		if err != nil {
+			return err
+		}
+		for i := range products {
+			if err := s.events.Publish(ctx, tx, AggregateProduct, products[i].ID, EventProductUpdated, products[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// checkReassignedAttributes makes sure the subcategories of a deleted
+// category do not redefine the attributes of the category taking them over
+func checkReassignedAttributes(ctx context.Context, tx repositories.Store, id uint, targetID uint) error {
+	above, err := tx.Categories().ListAttributes(ctx, targetID)
+	if err != nil {
+		return err
+	}
+	subtree, err := tx.Categories().ListSubtreeAttributes(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// The attributes of the deleted category go with it
+	below := []models.AttributeDefinition{}
+	for _, attribute := range subtree {
+		if attribute.CategoryID != id {
+			below = append(below, attribute)
+		}
+	}
+	return checkAttributeKeys(above, below)
+}
+
+func findCategory(categories []models.Category, id uint) *models.Category {
+	for i := range categories {
+		if categories[i].ID == id {
+			return &categories[i]
+		}
+	}
+	return nil
+}
+
+// inSubtree reports whether id is rootID or one of its descendants, walking
+// up the parents of id
+func inSubtree(categories []models.Category, rootID uint, id uint) bool {
+	parents := make(map[uint]*uint, len(categories))
+	for _, category := range categories {
+		parents[category.ID] = category.ParentID
+	}
+
+	// Bounded by the number of categories in case the stored tree is broken
+	for steps := 0; steps <= len(categories); steps++ {
+		if id == rootID {
+			return true
+		}
+		parent := parents[id]
+		if parent == nil {
+			return false
+		}
+		id = *parent
+	}
+	return true
+}
+
+// deleteCategory soft-deletes a category and renames it out of the way, so
+// its name can be reused below the same parent
+func deleteCategory(ctx context.Context, tx repositories.Store, category *models.Category) error {
+	if err := tx.Categories().Rename(ctx, category.ID, deletedCategoryName(category.Name)); err != nil {
+		return err
+	}
+	return tx.Categories().Delete(ctx, category)
+}
+
+// releaseCategoryName makes name available below parentID, nil for the top
+// level, for the category id, 0 for a new one. A live sibling holding the name
+// is a collision, while a soft-deleted one is renamed out of the way.
+func releaseCategoryName(ctx context.Context, tx repositories.Store, parentID *uint, name string, id uint) error {
+	existing, err := tx.Categories().FindByName(ctx, parentID, name)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if existing.ID == id {
+		return nil
+	}
+	if !existing.DeletedAt.Valid {
+		return ErrCategoryExists
+	}
+
+	return tx.Categories().Rename(ctx, existing.ID, deletedCategoryName(existing.Name))
+}
+
+// deletedCategoryName suffixes name like deletedSKU, shortened to fit the
+// name column
+func deletedCategoryName(name string) string {
+	suffix := fmt.Sprintf("_DELETED_%d", time.Now().UnixNano())
+	if runes := []rune(name); len(runes)+len(suffix) > 100 {
+		name = string(runes[:100-len(suffix)])
+	}
+	return name + suffix
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"inventory-backend/models"
+)
+
+func TestCategoryDeleteTopLevelWithProducts(t *testing.T) {
+	store := newTestStore(t)
+	products, _, _ := newTestServices(store)
+	categories := NewCategoryService(store, nopEvents{}, nopSearch{})
+	supplier := createTestSupplier(t, store)
+	ctx := context.Background()
+
+	category := models.Category{Name: "Tools"}
+	if err := store.Categories().Create(ctx, &category); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := products.Create(ctx, 1, ProductInput{SKU: "HAMMER", Name: "Hammer", Price: 5, SupplierID: supplier.ID, CategoryID: &category.ID}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	err := categories.Delete(ctx, category.ID, CategoryDeleteOptions{Reassign: true})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Delete of a top level category with products: got %v, want a validation error", err)
+	}
+	if _, err := store.Categories().FindByID(ctx, category.ID); err != nil {
+		t.Errorf("category deleted anyway: %v", err)
+	}
+}
+
+func TestCategoryDeleteReassignsProducts(t *testing.T) {
+	store := newTestStore(t)
+	products, _, _ := newTestServices(store)
+	events := &recordedEvents{}
+	categories := NewCategoryService(store, events, nopSearch{})
+	supplier := createTestSupplier(t, store)
+	ctx := context.Background()
+
+	parent := models.Category{Name: "Power"}
+	if err := store.Categories().Create(ctx, &parent); err != nil {
+		t.Fatal(err)
+	}
+	child := models.Category{Name: "Batteries", ParentID: &parent.ID}
+	if err := store.Categories().Create(ctx, &child); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := categories.CreateAttribute(ctx, child.ID, AttributeDefinitionInput{Key: "voltage", Type: models.AttributeNumber}); err != nil {
+		t.Fatalf("CreateAttribute: %v", err)
+	}
+	product, err := products.Create(ctx, 1, ProductInput{
+		SKU: "AA", Name: "AA cell", Price: 1, SupplierID: supplier.ID, CategoryID: &child.ID,
+		Attributes: map[string]interface{}{"voltage": 1.5},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if err := categories.Delete(ctx, child.ID, CategoryDeleteOptions{Reassign: true}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	saved, err := store.Products().FindByID(ctx, product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.CategoryID == nil || *saved.CategoryID != parent.ID {
+		t.Errorf("product category = %v, want %d", saved.CategoryID, parent.ID)
+	}
+	if saved.Version <= product.Version {
+		t.Errorf("product version = %d, want above %d", saved.Version, product.Version)
+	}
+	attributes, err := store.Products().ListAttributes(ctx, product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attributes) != 0 {
+		t.Errorf("attributes of the deleted category kept: %+v", attributes)
+	}
+	if len(events.types) != 1 || events.types[0] != EventProductUpdated {
+		t.Errorf("published %v, want one %s", events.types, EventProductUpdated)
+	}
+}
+
+func TestCategoryNamesUniqueAmongSiblings(t *testing.T) {
+	store := newTestStore(t)
+	categories := NewCategoryService(store, nopEvents{}, nopSearch{})
+	ctx := context.Background()
+
+	power, err := categories.Create(ctx, CategoryInput{Name: "Power"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	data, err := categories.Create(ctx, CategoryInput{Name: "Data"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := categories.Create(ctx, CategoryInput{Name: "Cables", ParentID: &power.ID}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	cables, err := categories.Create(ctx, CategoryInput{Name: "Cables", ParentID: &data.ID})
+	if err != nil {
+		t.Fatalf("Create of a name used below another parent: %v", err)
+	}
+
+	if _, err := categories.Create(ctx, CategoryInput{Name: "cables", ParentID: &power.ID}); !errors.Is(err, ErrCategoryExists) {
+		t.Errorf("Create of a sibling name: got %v, want ErrCategoryExists", err)
+	}
+	if _, err := categories.Create(ctx, CategoryInput{Name: "POWER"}); !errors.Is(err, ErrCategoryExists) {
+		t.Errorf("Create of a top level name: got %v, want ErrCategoryExists", err)
+	}
+	if _, err := categories.Move(ctx, cables.ID, &power.ID); !errors.Is(err, ErrCategoryExists) {
+		t.Errorf("Move next to a sibling of the same name: got %v, want ErrCategoryExists", err)
+	}
+	if _, err := categories.Update(ctx, data.ID, CategoryInput{Name: "Power"}); !errors.Is(err, ErrCategoryExists) {
+		t.Errorf("Update to a sibling name: got %v, want ErrCategoryExists", err)
+	}
+}
+
+func TestCategoryNameReusedAfterDelete(t *testing.T) {
+	store := newTestStore(t)
+	categories := NewCategoryService(store, nopEvents{}, nopSearch{})
+	ctx := context.Background()
+
+	tools, err := categories.Create(ctx, CategoryInput{Name: "Tools"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := categories.Delete(ctx, tools.ID, CategoryDeleteOptions{}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := categories.Create(ctx, CategoryInput{Name: "Tools"}); err != nil {
+		t.Fatalf("Create of a deleted name: %v", err)
+	}
+
+	// A subcategory moving up may take the name of its deleted parent
+	power, err := categories.Create(ctx, CategoryInput{Name: "Power"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	child, err := categories.Create(ctx, CategoryInput{Name: "Power", ParentID: &power.ID})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := categories.Delete(ctx, power.ID, CategoryDeleteOptions{Reassign: true}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	moved, err := store.Categories().FindByID(ctx, child.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.ParentID != nil || moved.Name != "Power" {
+		t.Errorf("subcategory = %q below %v, want Power at the top level", moved.Name, moved.ParentID)
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronStepDaysCombineWithWeekday(t *testing.T) {
+	// Odd days of month that are also Mondays, "*/2" does not turn the
+	// day fields into an OR
+	schedule, err := ParseCron("0 9 */2 * 1")
+	if err != nil {
+		t.Fatalf("ParseCron: %v", err)
+	}
+
+	from := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	want := time.Date(2026, 11, 9, 9, 0, 0, 0, time.UTC)
+	if next := schedule.Next(from); !next.Equal(want) {
+		t.Errorf("Next(%v) = %v, want %v", from, next, want)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// A Monday morning
+	from := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 10, 19, 10, 15, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2026, 10, 19, 10, 5, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)},
+		{"30 2 1 * *", time.Date(2026, 11, 1, 2, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted, either one matches
+		{"0 0 13 * 5", time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// April has no 31st
+		{"0 0 31 4 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", tt.expr, err)
+			continue
+		}
+		if next := schedule.Next(from); !next.Equal(tt.want) {
+			t.Errorf("%q: Next = %v, want %v", tt.expr, next, tt.want)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"* * * *",
+		"60 * * * *",
+		"0 24 * * *",
+		"0 0 0 * *",
+		"0 0 * 13 *",
+		"0 0 * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@often",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"inventory-backend/repositories"
+	"time"
+)
+
+// DashboardSummary is everything the dashboard shows, computed in SQL
+type DashboardSummary struct {
+	PeriodDays     int                               `json:"period_days"`
+	From           time.Time                         `json:"from"`
+	To             time.Time                         `json:"to"`
+	Totals         *repositories.InventoryTotals     `json:"totals"`
+	TopMovers      []repositories.ProductMovement    `json:"top_movers"`
+	DailyMovements []repositories.DailyMovement      `json:"daily_movements"`
+	ByCategory     []repositories.InventoryBreakdown `json:"by_category"`
+	BySupplier     []repositories.InventoryBreakdown `json:"by_supplier"`
+}
+
+type DashboardService struct {
+	store repositories.Store
+}
+
+func NewDashboardService(store repositories.Store) *DashboardService {
+	return &DashboardService{store: store}
+}
+
+// Summary builds the dashboard for the last days days including today, with
+// the topN products that moved the most units
+func (s *DashboardService) Summary(ctx context.Context, days, topN int) (*DashboardSummary, error) {
+	if days <= 0 || days > 366 {
+		return nil, invalid("Days must be between 1 and 366")
+	}
+	if topN <= 0 || topN > 100 {
+		return nil, invalid("Top must be between 1 and 100")
+	}
+
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	from := to.AddDate(0, 0, -days)
+
+	analytics := s.store.Analytics()
+	summary := &DashboardSummary{PeriodDays: days, From: from, To: to}
+
+	var err error
+	if summary.Totals, err = analytics.InventoryTotals(ctx); err != nil {
+		return nil, err
+	}
+	if summary.TopMovers, err = analytics.TopMovers(ctx, from, to, topN); err != nil {
+		return nil, err
+	}
+	daily, err := analytics.DailyMovements(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	summary.DailyMovements = fillDays(daily, from, days)
+	if summary.ByCategory, err = analytics.CategoryBreakdown(ctx); err != nil {
+		return nil, err
+	}
+	if summary.BySupplier, err = analytics.SupplierBreakdown(ctx); err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+// fillDays returns one entry per day starting at from so charts get a
+// continuous series, using zeros for days without movements
+func fillDays(daily []repositories.DailyMovement, from time.Time, days int) []repositories.DailyMovement {
+	byDay := make(map[string]repositories.DailyMovement, len(daily))
+	for _, d := range daily {
+		byDay[d.Day] = d
+	}
+
+	series := make([]repositories.DailyMovement, days)
+	for i := range series {
+		day := from.AddDate(0, 0, i).Format("2006-01-02")
+		if d, ok := byDay[day]; ok {
+			series[i] = d
+		} else {
+			series[i] = repositories.DailyMovement{Day: day}
+		}
+	}
+	return series
+}
@@ -0,0 +1,24 @@
+package services
+
+import (
+	"os"
+	"strconv"
+)
+
+// getEnv returns the environment variable key or fallback when it is unset
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvInt returns the environment variable key as an int or fallback when
+// it is unset or not a number
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"inventory-backend/repositories"
+)
+
+var (
+	ErrProductNotFound    = errors.New("product not found")
+	ErrSupplierNotFound   = errors.New("supplier not found")
+	ErrCategoryNotFound   = errors.New("category not found")
+	ErrAttributeNotFound  = errors.New("attribute not found")
+	ErrSKUExists          = errors.New("SKU already exists")
+	ErrCategoryExists     = errors.New("category name already exists at this level")
+	ErrInsufficientStock  = errors.New("insufficient stock")
+	ErrSupplierInUse      = errors.New("cannot delete supplier with existing products")
+	ErrCategoryInUse      = errors.New("cannot delete category with subcategories or products")
+	ErrProductHasVariants = errors.New("cannot delete product with existing variants")
+	ErrProductInKit       = errors.New("cannot delete product used as a kit component")
+	ErrExportJobNotFound  = errors.New("export job not found")
+	ErrExportNotReady     = errors.New("export is not ready")
+	ErrExportExpired      = errors.New("export has expired")
+
+	ErrReportScheduleNotFound  = errors.New("report schedule not found")
+	ErrPurchaseOrderNotFound   = errors.New("purchase order not found")
+	ErrStockAlertNotFound      = errors.New("stock alert not found")
+	ErrAlertChannelNotFound    = errors.New("alert channel not found")
+	ErrAlertTestFailed         = errors.New("test notification failed")
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrIdempotencyKeyReused    = errors.New("idempotency key was used for a different request")
+	ErrIdempotencyKeyInFlight  = errors.New("a request with this idempotency key is still in progress")
+	ErrVersionMismatch         = errors.New("version does not match the current version")
+	ErrVersionConflict         = errors.New("modified concurrently")
+)
+
+// ValidationError reports input rejected by a service before touching the database
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func invalid(message string) error {
+	return &ValidationError{Message: message}
+}
+
+// notFound replaces a repository miss with the service level error
+func notFound(err error, target error) error {
+	if errors.Is(err, repositories.ErrNotFound) {
+		return target
+	}
+	return err
+}
+
+// checkVersion compares the version a client last saw with the stored one.
+// An expected version of 0 skips the check.
+func checkVersion(current, expected uint) error {
+	if expected != 0 && expected != current {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+// conflict replaces a lost optimistic lock with the service level error
+func conflict(err error) error {
+	if errors.Is(err, repositories.ErrConflict) {
+		return ErrVersionConflict
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"time"
+)
+
+// Inventory event types
+const (
+	EventProductCreated  = "product.created"
+	EventProductUpdated  = "product.updated"
+	EventProductDeleted  = "product.deleted"
+	EventStockChanged    = "stock.changed"
+	EventSupplierCreated = "supplier.created"
+	EventSupplierUpdated = "supplier.updated"
+	EventSupplierDeleted = "supplier.deleted"
+	EventWebhookTest     = "webhook.test"
+)
+
+// Aggregate types. Events of one aggregate are published in commit order.
+const (
+	AggregateProduct  = "product"
+	AggregateSupplier = "supplier"
+)
+
+// EventTypes lists the events a subscriber can choose from
+var EventTypes = []string{
+	EventProductCreated,
+	EventProductUpdated,
+	EventProductDeleted,
+	EventStockChanged,
+	EventSupplierCreated,
+	EventSupplierUpdated,
+	EventSupplierDeleted,
+}
+
+// IsEventType reports whether eventType names a known event
+func IsEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is the envelope every inventory event is published in
+type Event struct {
+	ID            string      `json:"id"`
+	Type          string      `json:"type"`
+	AggregateType string      `json:"aggregate_type,omitempty"`
+	AggregateID   uint        `json:"aggregate_id,omitempty"`
+	OccurredAt    time.Time   `json:"occurred_at"`
+	Data          interface{} `json:"data"`
+}
+
+func newEvent(eventType string, data interface{}) Event {
+	return Event{ID: newEventID(), Type: eventType, OccurredAt: time.Now(), Data: data}
+}
+
+// newEventID returns a random identifier receivers can use to drop duplicates
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "evt_" + hex.EncodeToString(b)
+}
+
+// EventPublisher records an event about an aggregate as part of the
+// transaction tx, so it is only published when the change it describes is
+// committed
+type EventPublisher interface {
+	Publish(ctx context.Context, tx repositories.Store, aggregateType string, aggregateID uint, eventType string, data interface{}) error
+}
+
+// StockChange is the data of a stock.changed event
+type StockChange struct {
+	ProductID    uint    `json:"product_id"`
+	SKU          string  `json:"sku"`
+	Name         string  `json:"name"`
+	MovementID   uint    `json:"movement_id"`
+	Type         string  `json:"type"` // in, out
+	Quantity     float64 `json:"quantity"`
+	BaseUnit     string  `json:"base_unit"`
+	Unit         string  `json:"unit"`          // Unit the movement was entered in
+	UnitQuantity float64 `json:"unit_quantity"` // Quantity in Unit
+	StockBefore  float64 `json:"stock_before"`
+	StockAfter   float64 `json:"stock_after"`
+	Note         string  `json:"note"`
+}
+
+func stockChanged(product *models.Product, history *models.StockHistory) StockChange {
+	return StockChange{
+		ProductID:    product.ID,
+		SKU:          product.SKU,
+		Name:         product.Name,
+		MovementID:   history.ID,
+		Type:         history.Type,
+		Quantity:     history.Quantity,
+		BaseUnit:     product.BaseUnit,
+		Unit:         history.Unit,
+		UnitQuantity: history.UnitQuantity,
+		StockBefore:  history.StockBefore,
+		StockAfter:   history.StockAfter,
+		Note:         history.Note,
+	}
+}
+
+// DeletedEntity is the data of the *.deleted events
+type DeletedEntity struct {
+	ID   uint   `json:"id"`
+	SKU  string `json:"sku,omitempty"`
+	Name string `json:"name"`
+}
@@ -0,0 +1,339 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Export job types
+const (
+	ExportTypeProducts     = "products"
+	ExportTypeActivityLogs = "activity_logs"
+)
+
+const (
+	// exportPollInterval is how often pending jobs missed by the queue are picked up
+	exportPollInterval = 30 * time.Second
+	// exportCleanupInterval is how often expired artifacts are removed
+	exportCleanupInterval = 10 * time.Minute
+	// defaultActivityLogExport is the number of logs exported without a limit
+	defaultActivityLogExport = 100
+	// maxActivityLogExport caps the number of logs rendered into one PDF
+	maxActivityLogExport = 10000
+)
+
+// ExportJobInput describes an export to run in the background
+type ExportJobInput struct {
+	Type       string `json:"type"`
+	Format     string `json:"format"`
+	Search     string `json:"search,omitempty"`
+	CategoryID uint   `json:"category_id,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+}
+
+// ExportJobOptions configures where artifacts are stored and for how long
+type ExportJobOptions struct {
+	Dir     string
+	TTL     time.Duration
+	Workers int
+}
+
+// ExportJobService queues exports and generates their files with a pool of
+// background workers. Jobs are stored in the database, the in-memory queue only
+// wakes workers up, so pending jobs survive a restart.
+type ExportJobService struct {
+	store    repositories.Store
+	products *ProductService
+	opts     ExportJobOptions
+	queue    chan uint
+}
+
+func NewExportJobService(store repositories.Store, products *ProductService, opts ExportJobOptions) *ExportJobService {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	return &ExportJobService{
+		store:    store,
+		products: products,
+		opts:     opts,
+		queue:    make(chan uint, 100),
+	}
+}
+
+// Create validates and stores a new export job and queues it
+func (s *ExportJobService) Create(ctx context.Context, userID uint, input ExportJobInput) (*models.ExportJob, error) {
+	switch input.Type {
+	case ExportTypeProducts:
+		if input.Format == "" {
+			input.Format = "xlsx"
+		}
+		if _, ok := ProductExportContentTypes[input.Format]; !ok {
+			return nil, invalid("Format must be 'csv', 'json' or 'xlsx'")
+		}
+	case ExportTypeActivityLogs:
+		if input.Format == "" {
+			input.Format = "pdf"
+		}
+		if input.Format != "pdf" {
+			return nil, invalid("Activity logs can only be exported as 'pdf'")
+		}
+		if input.Limit == 0 {
+			input.Limit = defaultActivityLogExport
+		}
+		if input.Limit < 1 || input.Limit > maxActivityLogExport {
+			return nil, invalid(fmt.Sprintf("Limit must be between 1 and %d", maxActivityLogExport))
+		}
+	default:
+		return nil, invalid("Type must be 'products' or 'activity_logs'")
+	}
+
+	params, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	job := models.ExportJob{
+		UserID: userID,
+		Type:   input.Type,
+		Format: input.Format,
+		Params: string(params),
+		Status: models.ExportJobPending,
+	}
+	if err := s.store.ExportJobs().Create(ctx, &job); err != nil {
+		return nil, err
+	}
+
+	s.enqueue(job.ID)
+	return &job, nil
+}
+
+func (s *ExportJobService) Get(ctx context.Context, id uint) (*models.ExportJob, error) {
+	job, err := s.store.ExportJobs().FindByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrExportJobNotFound)
+	}
+	return job, nil
+}
+
+func (s *ExportJobService) List(ctx context.Context, limit int) ([]models.ExportJob, error) {
+	return s.store.ExportJobs().ListRecent(ctx, limit)
+}
+
+// Artifact returns a job whose file is ready to be downloaded
+func (s *ExportJobService) Artifact(ctx context.Context, id uint) (*models.ExportJob, error) {
+	job, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case job.Status == models.ExportJobExpired:
+		return nil, ErrExportExpired
+	case job.Status != models.ExportJobCompleted:
+		return nil, ErrExportNotReady
+	case job.ExpiresAt != nil && job.ExpiresAt.Before(time.Now()):
+		return nil, ErrExportExpired
+	}
+
+	return job, nil
+}
+
+// Start launches the workers, the pending job poller and the cleanup loop.
+// They stop when ctx is cancelled.
+func (s *ExportJobService) Start(ctx context.Context) {
+	if err := os.MkdirAll(s.opts.Dir, os.ModePerm); err != nil {
+		log.Printf("Failed to create export folder: %v", err)
+	}
+
+	// Jobs left running by a previous process were interrupted, run them again
+	if jobs, err := s.store.ExportJobs().ListByStatus(ctx, models.ExportJobRunning); err == nil {
+		for _, job := range jobs {
+			job.Status = models.ExportJobPending
+			if err := s.store.ExportJobs().Save(ctx, &job); err != nil {
+				log.Printf("Failed to requeue export job %d: %v", job.ID, err)
+			}
+		}
+	}
+
+	for i := 0; i < s.opts.Workers; i++ {
+		go s.work(ctx)
+	}
+	go every(ctx, exportPollInterval, s.enqueuePending)
+	go every(ctx, exportCleanupInterval, s.cleanup)
+}
+
+func (s *ExportJobService) enqueue(id uint) {
+	select {
+	case s.queue <- id:
+	default:
+		// Queue is full, the poller will pick the job up later
+	}
+}
+
+func (s *ExportJobService) enqueuePending(ctx context.Context) {
+	jobs, err := s.store.ExportJobs().ListByStatus(ctx, models.ExportJobPending)
+	if err != nil {
+		log.Printf("Failed to list pending export jobs: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		s.enqueue(job.ID)
+	}
+}
+
+func (s *ExportJobService) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-s.queue:
+			s.run(ctx, id)
+		}
+	}
+}
+
+func (s *ExportJobService) run(ctx context.Context, id uint) {
+	claimed, err := s.store.ExportJobs().Claim(ctx, id, time.Now())
+	if err != nil {
+		log.Printf("Failed to claim export job %d: %v", id, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	job, err := s.store.ExportJobs().FindByID(ctx, id)
+	if err != nil {
+		log.Printf("Failed to load export job %d: %v", id, err)
+		return
+	}
+
+	fileName, path, size, err := s.generate(ctx, job)
+
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	if err != nil {
+		log.Printf("Export job %d failed: %v", id, err)
+		job.Status = models.ExportJobFailed
+		job.Error = err.Error()
+	} else {
+		expiresAt := finishedAt.Add(s.opts.TTL)
+		job.Status = models.ExportJobCompleted
+		job.Progress = 100
+		job.FileName = fileName
+		job.FilePath = path
+		job.FileSize = size
+		job.ExpiresAt = &expiresAt
+	}
+
+	if err := s.store.ExportJobs().Finish(ctx, job); err != nil {
+		log.Printf("Failed to save export job %d: %v", id, err)
+	}
+}
+
+// generate writes the job artifact and returns its download name, path and size
+func (s *ExportJobService) generate(ctx context.Context, job *models.ExportJob) (string, string, int64, error) {
+	var input ExportJobInput
+	if err := json.Unmarshal([]byte(job.Params), &input); err != nil {
+		return "", "", 0, err
+	}
+
+	fileName := fmt.Sprintf("%s_%s.%s", job.Type, time.Now().Format("20060102_150405"), job.Format)
+	path := filepath.Join(s.opts.Dir, fmt.Sprintf("export_%d_%s", job.ID, fileName))
+
+	// Write to a temporary name so a partial file is never served
+	tmpPath := path + ".part"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	switch job.Type {
+	case ExportTypeProducts:
+		err = s.generateProducts(ctx, job, input, file)
+	case ExportTypeActivityLogs:
+		err = s.generateActivityLogs(ctx, job, input, file)
+	default:
+		err = fmt.Errorf("unknown export type %q", job.Type)
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", "", 0, err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return "", "", 0, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	return fileName, path, info.Size(), nil
+}
+
+func (s *ExportJobService) generateProducts(ctx context.Context, job *models.ExportJob, input ExportJobInput, file *os.File) error {
+	filter := repositories.ProductFilter{Search: input.Search, CategoryID: input.CategoryID}
+
+	total, err := s.products.Count(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	return s.products.ExportWithProgress(ctx, filter, job.Format, file, func(written int) {
+		if err := s.store.ExportJobs().UpdateProgress(ctx, job.ID, written, int(total)); err != nil {
+			log.Printf("Failed to update export job %d progress: %v", job.ID, err)
+		}
+	})
+}
+
+func (s *ExportJobService) generateActivityLogs(ctx context.Context, job *models.ExportJob, input ExportJobInput, file *os.File) error {
+	limit := input.Limit
+	if limit == 0 {
+		limit = defaultActivityLogExport
+	}
+
+	logs, err := s.store.ActivityLogs().ListRecent(ctx, limit)
+	if err != nil {
+		return err
+	}
+	s.store.ExportJobs().UpdateProgress(ctx, job.ID, len(logs), len(logs))
+
+	return GenerateActivityLogPDF(logs, file)
+}
+
+// cleanup deletes expired artifacts and marks their jobs as expired
+func (s *ExportJobService) cleanup(ctx context.Context) {
+	jobs, err := s.store.ExportJobs().ListExpired(ctx, time.Now())
+	if err != nil {
+		log.Printf("Failed to list expired export jobs: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if job.FilePath != "" {
+			if err := os.Remove(job.FilePath); err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed to delete export file %s: %v", job.FilePath, err)
+				continue
+			}
+		}
+		job.Status = models.ExportJobExpired
+		job.FilePath = ""
+		if err := s.store.ExportJobs().Save(ctx, &job); err != nil {
+			log.Printf("Failed to expire export job %d: %v", job.ID, err)
+		}
+	}
+}
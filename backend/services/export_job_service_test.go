@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"inventory-backend/models"
+)
+
+func TestExportJobKeepsProgressCounts(t *testing.T) {
+	store := newTestStore(t)
+	products, _, _ := newTestServices(store)
+	supplier := createTestSupplier(t, store)
+	for _, sku := range []string{"SKU-1", "SKU-2", "SKU-3"} {
+		createTestProduct(t, products, supplier.ID, sku, 1)
+	}
+	exports := NewExportJobService(store, products, ExportJobOptions{Dir: t.TempDir(), TTL: time.Hour})
+	ctx := context.Background()
+
+	job, err := exports.Create(ctx, 1, ExportJobInput{Type: ExportTypeProducts, Format: "csv"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	exports.run(ctx, job.ID)
+
+	saved, err := store.ExportJobs().FindByID(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != models.ExportJobCompleted {
+		t.Fatalf("status = %s (%s), want completed", saved.Status, saved.Error)
+	}
+	if saved.Processed != 3 || saved.Total != 3 || saved.Progress != 100 {
+		t.Errorf("progress %d%%, %d of %d, want 100%%, 3 of 3", saved.Progress, saved.Processed, saved.Total)
+	}
+}
+
+func TestExportJobLimit(t *testing.T) {
+	store := newTestStore(t)
+	products, _, _ := newTestServices(store)
+	exports := NewExportJobService(store, products, ExportJobOptions{Dir: t.TempDir()})
+	ctx := context.Background()
+
+	for _, limit := range []int{-1, maxActivityLogExport + 1} {
+		if _, err := exports.Create(ctx, 1, ExportJobInput{Type: ExportTypeActivityLogs, Limit: limit}); err == nil {
+			t.Errorf("Create with limit %d succeeded", limit)
+		}
+	}
+	job, err := exports.Create(ctx, 1, ExportJobInput{Type: ExportTypeActivityLogs})
+	if err != nil {
+		t.Fatalf("Create without a limit: %v", err)
+	}
+	if want := `"limit":100`; !strings.Contains(job.Params, want) {
+		t.Errorf("params %s, want %s", job.Params, want)
+	}
+}
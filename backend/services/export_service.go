@@ -9,56 +9,12 @@ import (
 	"io"
 
 	"github.com/jung-kurt/gofpdf"
-	"github.com/xuri/excelize/v2"
 )
 
-// GenerateProductExcel creates an Excel file from the product list
+// GenerateProductExcel creates an Excel file from the product list using
+// ProductExportColumns, so the sheet can be imported again
 func GenerateProductExcel(products []models.Product, writer io.Writer) error {
-	f := excelize.NewFile()
-	sheetName := "Products"
-	index, _ := f.NewSheet(sheetName)
-	f.SetActiveSheet(index)
-
-	// Headers
-	headers := []string{"ID", "SKU", "Name", "Category", "Supplier", "Stock", "Price", "Total Value"}
-	for i, header := range headers {
-		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
-		f.SetCellValue(sheetName, cell, header)
-		f.SetColWidth(sheetName, string(rune('A'+i)), string(rune('A'+i)), 20)
-	}
-
-	// Style Header
-	style, _ := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Bold: true},
-		Fill: excelize.Fill{Type: "pattern", Color: []string{"#CCCCCC"}, Pattern: 1},
-	})
-	f.SetCellStyle(sheetName, "A1", "H1", style)
-
-	// Data
-	for i, p := range products {
-		row := i + 2
-		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), p.ID)
-		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), p.SKU)
-		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), p.Name)
-
-		categoryName := "-"
-		if p.CategoryID != nil {
-			categoryName = p.Category.Name
-		}
-		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), categoryName)
-
-		supplierName := "-"
-		if p.Supplier.Name != "" {
-			supplierName = p.Supplier.Name
-		}
-		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), supplierName)
-
-		f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), p.Stock)
-		f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), p.Price)
-		f.SetCellValue(sheetName, fmt.Sprintf("H%d", row), float64(p.Stock)*p.Price)
-	}
-
-	return f.Write(writer)
+	return generateProducts("xlsx", products, writer)
 }
 
 // GenerateActivityLogPDF creates a PDF file from activity logs
@@ -0,0 +1,405 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"log"
+	"math"
+	"time"
+)
+
+// ForecastOptions holds the defaults used when a request does not set them
+type ForecastOptions struct {
+	WindowDays   int
+	ServiceLevel float64
+	// Interval is how often the background job proposes new reorder points,
+	// zero disables it
+	Interval time.Duration
+}
+
+// ForecastParams overrides the default window and service level
+type ForecastParams struct {
+	WindowDays   int
+	ServiceLevel float64
+}
+
+// Forecast is the demand estimate and reorder point of one product
+type Forecast struct {
+	ProductID       uint      `json:"product_id"`
+	SKU             string    `json:"sku"`
+	Name            string    `json:"name"`
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	WindowDays      int       `json:"window_days"`
+	TotalDemand     float64   `json:"total_demand"`
+	ActiveDays      int       `json:"active_days"`
+	AvgDailyDemand  float64   `json:"avg_daily_demand"`
+	DemandStdDev    float64   `json:"demand_std_dev"` // of the daily demand
+	LeadTimeDays    int       `json:"lead_time_days"`
+	ServiceLevel    float64   `json:"service_level"`
+	ServiceFactor   float64   `json:"service_factor"` // z score of the service level
+	SafetyStock     float64   `json:"safety_stock"`
+	ReorderPoint    float64   `json:"reorder_point"`
+	Stock           float64   `json:"stock"`
+	CurrentMinStock float64   `json:"current_min_stock"`
+	// DaysOfCover is how long the current stock lasts at the average demand,
+	// nil without demand
+	DaysOfCover *float64 `json:"days_of_cover"`
+}
+
+// ReorderProposalRun is the result of proposing reorder points for the catalog
+type ReorderProposalRun struct {
+	Products   int                      `json:"products"`
+	Skipped    int                      `json:"skipped"` // no demand in the window
+	Unchanged  int                      `json:"unchanged"`
+	Superseded int64                    `json:"superseded"`
+	Proposals  []models.ReorderProposal `json:"proposals"`
+}
+
+// ReorderReview is the result of approving or rejecting proposals
+type ReorderReview struct {
+	Applied  int `json:"applied"`
+	Rejected int `json:"rejected"`
+}
+
+// ForecastService estimates demand from outbound stock movements and derives
+// reorder points (MinStock) from it
+type ForecastService struct {
+	store   repositories.Store
+	alerts  *StockAlertService
+	events  EventPublisher
+	options ForecastOptions
+}
+
+func NewForecastService(store repositories.Store, alerts *StockAlertService, events EventPublisher, options ForecastOptions) *ForecastService {
+	if options.WindowDays <= 0 {
+		options.WindowDays = 90
+	}
+	if options.ServiceLevel <= 0 {
+		options.ServiceLevel = 0.95
+	}
+	return &ForecastService{store: store, alerts: alerts, events: events, options: options}
+}
+
+// Forecast computes the demand statistics and reorder point of one product
+func (s *ForecastService) Forecast(ctx context.Context, productID uint, params ForecastParams) (*Forecast, error) {
+	params, err := s.params(params)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := s.store.Products().FindByID(ctx, productID)
+	if err != nil {
+		return nil, notFound(err, ErrProductNotFound)
+	}
+
+	from, to := forecastWindow(params.WindowDays)
+	stats, err := s.store.Analytics().Demand(ctx, from, to, product.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var demand repositories.DemandStats
+	if len(stats) > 0 {
+		demand = stats[0]
+	}
+	return forecast(*product, demand, from, to, params), nil
+}
+
+// Propose computes the reorder point of every product with demand in the
+// window and stores a pending proposal for each one that differs from its
+// MinStock. Older pending proposals are superseded.
+func (s *ForecastService) Propose(ctx context.Context, userID uint, params ForecastParams) (*ReorderProposalRun, error) {
+	params, err := s.params(params)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := forecastWindow(params.WindowDays)
+	stats, err := s.store.Analytics().Demand(ctx, from, to, 0)
+	if err != nil {
+		return nil, err
+	}
+	demand := make(map[uint]repositories.DemandStats, len(stats))
+	for _, stat := range stats {
+		demand[stat.ProductID] = stat
+	}
+
+	run := &ReorderProposalRun{Proposals: []models.ReorderProposal{}}
+	err = s.store.Products().EachBatch(ctx, repositories.ProductFilter{Variants: repositories.VariantsFlat}, exportBatchSize, func(products []models.Product) error {
+		for _, p := range products {
+			run.Products++
+			stat, ok := demand[p.ID]
+			if !ok {
+				// Keep the hand-entered value for products that did not move
+				run.Skipped++
+				continue
+			}
+
+			f := forecast(p, stat, from, to, params)
+			if f.ReorderPoint == p.MinStock {
+				run.Unchanged++
+				continue
+			}
+
+			run.Proposals = append(run.Proposals, models.ReorderProposal{
+				ProductID:        p.ID,
+				CurrentMinStock:  p.MinStock,
+				ProposedMinStock: f.ReorderPoint,
+				SafetyStock:      f.SafetyStock,
+				AvgDailyDemand:   f.AvgDailyDemand,
+				DemandStdDev:     f.DemandStdDev,
+				LeadTimeDays:     f.LeadTimeDays,
+				ServiceLevel:     f.ServiceLevel,
+				WindowDays:       f.WindowDays,
+				Status:           models.ReorderProposalPending,
+				CreatedBy:        userID,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.Transaction(ctx, func(tx repositories.Store) error {
+		superseded, err := tx.ReorderProposals().SupersedePending(ctx)
+		if err != nil {
+			return err
+		}
+		run.Superseded = superseded
+
+		if err := tx.ReorderProposals().Create(ctx, run.Proposals); err != nil {
+			return err
+		}
+
+		logActivity(ctx, tx, userID, "FORECAST", "ReorderProposal", 0, fmt.Sprintf("Proposed %d new reorder points (%d-day window, %.1f%% service level)", len(run.Proposals), params.WindowDays, params.ServiceLevel*100))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// ListProposals returns up to 500 proposals with the given status, newest first
+func (s *ForecastService) ListProposals(ctx context.Context, status string) ([]models.ReorderProposal, error) {
+	switch status {
+	case "", models.ReorderProposalPending, models.ReorderProposalApplied, models.ReorderProposalRejected, models.ReorderProposalSuperseded:
+	default:
+		return nil, invalid("Status must be 'pending', 'applied', 'rejected' or 'superseded'")
+	}
+	return s.store.ReorderProposals().List(ctx, status, 500)
+}
+
+// Approve copies the proposed reorder points of the given pending proposals,
+// or of all pending proposals when ids is empty, into MinStock
+func (s *ForecastService) Approve(ctx context.Context, userID uint, ids []uint) (*ReorderReview, error) {
+	review := &ReorderReview{}
+	err := s.store.Transaction(ctx, func(tx repositories.Store) error {
+		proposals, err := tx.ReorderProposals().ListPending(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		for i := range proposals {
+			proposal := &proposals[i]
+			product, err := tx.Products().FindByIDForUpdate(ctx, proposal.ProductID)
+			switch {
+			case errors.Is(err, repositories.ErrNotFound):
+				// The product was deleted since the proposal was made
+				proposal.Status = models.ReorderProposalRejected
+				review.Rejected++
+			case err != nil:
+				return err
+			default:
+				wasLow := product.Stock < product.MinStock
+				product.MinStock = proposal.ProposedMinStock
+				if err := tx.Products().Save(ctx, product); err != nil {
+					return err
+				}
+				// A new reorder point can cross the low stock threshold
+				if err := s.alerts.track(ctx, tx, product, wasLow); err != nil {
+					return err
+				}
+				if err := s.events.Publish(ctx, tx, AggregateProduct, product.ID, EventProductUpdated, product); err != nil {
+					return err
+				}
+				logActivity(ctx, tx, userID, "UPDATE", "Product", product.ID, fmt.Sprintf("Set min stock of %s (%s) from %s to %s by reorder proposal #%d", product.Name, product.SKU, formatQuantity(proposal.CurrentMinStock), formatQuantity(proposal.ProposedMinStock), proposal.ID))
+				proposal.Status = models.ReorderProposalApplied
+				review.Applied++
+			}
+
+			if err := s.review(ctx, tx, userID, proposal); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// Reject discards the given pending proposals, or all of them when ids is empty
+func (s *ForecastService) Reject(ctx context.Context, userID uint, ids []uint) (*ReorderReview, error) {
+	review := &ReorderReview{}
+	err := s.store.Transaction(ctx, func(tx repositories.Store) error {
+		proposals, err := tx.ReorderProposals().ListPending(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		for i := range proposals {
+			proposals[i].Status = models.ReorderProposalRejected
+			if err := s.review(ctx, tx, userID, &proposals[i]); err != nil {
+				return err
+			}
+			review.Rejected++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+func (s *ForecastService) review(ctx context.Context, tx repositories.Store, userID uint, proposal *models.ReorderProposal) error {
+	now := time.Now()
+	proposal.ReviewedBy = &userID
+	proposal.ReviewedAt = &now
+	return tx.ReorderProposals().Save(ctx, proposal)
+}
+
+// Start proposes new reorder points every Interval. A restart does not create
+// a new batch when the last one is more recent than Interval.
+func (s *ForecastService) Start(ctx context.Context) {
+	if s.options.Interval <= 0 {
+		return
+	}
+
+	go every(ctx, time.Hour, func(ctx context.Context) {
+		latest, err := s.store.ReorderProposals().LatestCreatedAt(ctx)
+		if err != nil {
+			log.Printf("Failed to check reorder proposals: %v", err)
+			return
+		}
+		if latest != nil && time.Since(*latest) < s.options.Interval {
+			return
+		}
+
+		run, err := s.Propose(ctx, 0, ForecastParams{})
+		if err != nil {
+			log.Printf("Failed to propose reorder points: %v", err)
+			return
+		}
+		if len(run.Proposals) > 0 {
+			log.Printf("📈 Proposed %d new reorder points, waiting for approval", len(run.Proposals))
+		}
+	})
+}
+
+// params fills in and validates the forecast parameters
+func (s *ForecastService) params(params ForecastParams) (ForecastParams, error) {
+	if params.WindowDays == 0 {
+		params.WindowDays = s.options.WindowDays
+	}
+	if params.ServiceLevel == 0 {
+		params.ServiceLevel = s.options.ServiceLevel
+	}
+	if params.WindowDays < 7 || params.WindowDays > 730 {
+		return params, invalid("Days must be between 7 and 730")
+	}
+	if params.ServiceLevel < 0.5 || params.ServiceLevel >= 1 {
+		return params, invalid("Service level must be at least 0.5 and below 1")
+	}
+	return params, nil
+}
+
+// forecastWindow returns the last days whole days, excluding today
+func forecastWindow(days int) (time.Time, time.Time) {
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return to.AddDate(0, 0, -days), to
+}
+
+// forecast derives the reorder point of a product from its demand:
+//
+//	safety stock  = z * σ(daily demand) * √lead time
+//	reorder point = average daily demand * lead time + safety stock
+func forecast(p models.Product, demand repositories.DemandStats, from, to time.Time, params ForecastParams) *Forecast {
+	days := float64(params.WindowDays)
+	mean := demand.Total / days
+
+	var stdDev float64
+	if params.WindowDays > 1 {
+		// Sample variance over every day of the window, idle days included
+		variance := (demand.SumSquares - days*mean*mean) / (days - 1)
+		stdDev = math.Sqrt(math.Max(variance, 0))
+	}
+
+	leadTime := p.Supplier.LeadTimeDays
+	if leadTime <= 0 {
+		leadTime = defaultLeadTimeDays
+	}
+
+	z := normalQuantile(params.ServiceLevel)
+	safety := z * stdDev * math.Sqrt(float64(leadTime))
+
+	f := &Forecast{
+		ProductID:       p.ID,
+		SKU:             p.SKU,
+		Name:            p.Name,
+		From:            from,
+		To:              to,
+		WindowDays:      params.WindowDays,
+		TotalDemand:     demand.Total,
+		ActiveDays:      demand.ActiveDays,
+		AvgDailyDemand:  round2(mean),
+		DemandStdDev:    round2(stdDev),
+		LeadTimeDays:    leadTime,
+		ServiceLevel:    params.ServiceLevel,
+		ServiceFactor:   round2(z),
+		SafetyStock:     ceilQuantity(&p, safety),
+		ReorderPoint:    ceilQuantity(&p, mean*float64(leadTime)+safety),
+		Stock:           p.Stock,
+		CurrentMinStock: p.MinStock,
+	}
+	if mean > 0 {
+		cover := round2(p.Stock / mean)
+		f.DaysOfCover = &cover
+	}
+	return f
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// normalQuantile returns the z score below which the fraction p of a
+// standard normal distribution lies (Acklam's rational approximation)
+func normalQuantile(p float64) float64 {
+	a := [6]float64{-3.969683028665376e+01, 2.209460984245205e+02, -2.759285104469687e+02, 1.383577518672690e+02, -3.066479806614716e+01, 2.506628277459239e+00}
+	b := [5]float64{-5.447609879822406e+01, 1.615858368580409e+02, -1.556989798598866e+02, 6.680131188771972e+01, -1.328068155288572e+01}
+	c := [6]float64{-7.784894002430293e-03, -3.223964580411365e-01, -2.400758277161838e+00, -2.549732539343734e+00, 4.374664141464968e+00, 2.938163982698783e+00}
+	d := [4]float64{7.784695709041462e-03, 3.224671290700398e-01, 2.445134137142996e+00, 3.754408661907416e+00}
+
+	const low = 0.02425
+	switch {
+	case p < low:
+		q := math.Sqrt(-2 * math.Log(p))
+		return (((((c[0]*q+c[1])*q+c[2])*q+c[3])*q+c[4])*q + c[5]) / ((((d[0]*q+d[1])*q+d[2])*q+d[3])*q + 1)
+	case p > 1-low:
+		q := math.Sqrt(-2 * math.Log(1-p))
+		return -(((((c[0]*q+c[1])*q+c[2])*q+c[3])*q+c[4])*q + c[5]) / ((((d[0]*q+d[1])*q+d[2])*q+d[3])*q + 1)
+	}
+	q := p - 0.5
+	r := q * q
+	return (((((a[0]*r+a[1])*r+a[2])*r+a[3])*r+a[4])*r + a[5]) * q / (((((b[0]*r+b[1])*r+b[2])*r+b[3])*r+b[4])*r + 1)
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+)
+
+func TestForecastApproveRaisesAlert(t *testing.T) {
+	store := newTestStore(t)
+	products, _, _ := newTestServices(store)
+	supplier := createTestSupplier(t, store)
+	ctx := context.Background()
+	product := createTestProduct(t, products, supplier.ID, "SKU-1", 20)
+
+	proposal := models.ReorderProposal{
+		ProductID:        product.ID,
+		CurrentMinStock:  product.MinStock,
+		ProposedMinStock: 30,
+		Status:           models.ReorderProposalPending,
+	}
+	if err := store.ReorderProposals().Create(ctx, []models.ReorderProposal{proposal}); err != nil {
+		t.Fatal(err)
+	}
+
+	events := &recordedEvents{}
+	alerts := NewStockAlertService(store, map[string]AlertNotifier{}, StockAlertOptions{})
+	forecasts := NewForecastService(store, alerts, events, ForecastOptions{})
+
+	review, err := forecasts.Approve(ctx, 1, nil)
+	if err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	if review.Applied != 1 {
+		t.Fatalf("applied %d proposals, want 1", review.Applied)
+	}
+
+	if len(events.types) != 1 || events.types[0] != EventProductUpdated {
+		t.Errorf("published %v, want [%s]", events.types, EventProductUpdated)
+	}
+	if _, err := store.StockAlerts().FindActive(ctx, product.ID); err != nil {
+		t.Errorf("no active alert after raising the minimum above the stock: %v", err)
+	}
+}
+
+func TestNormalQuantile(t *testing.T) {
+	tests := []struct {
+		p, want float64
+	}{
+		{0.5, 0},
+		{0.9, 1.2816},
+		{0.95, 1.6449},
+		{0.99, 2.3263},
+		{0.01, -2.3263},
+	}
+	for _, tt := range tests {
+		if got := normalQuantile(tt.p); math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("normalQuantile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestForecastReorderPoint(t *testing.T) {
+	product := models.Product{Stock: 30, Supplier: models.Supplier{LeadTimeDays: 4}}
+	params := ForecastParams{WindowDays: 10, ServiceLevel: 0.95}
+	to := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -10)
+
+	// 5 a day without variation needs no safety stock
+	steady := forecast(product, repositories.DemandStats{Total: 50, SumSquares: 250, ActiveDays: 10}, from, to, params)
+	if steady.SafetyStock != 0 || steady.ReorderPoint != 20 {
+		t.Errorf("steady demand: safety stock %v, reorder point %v, want 0 and 20", steady.SafetyStock, steady.ReorderPoint)
+	}
+	if steady.DaysOfCover == nil || *steady.DaysOfCover != 6 {
+		t.Errorf("steady demand: days of cover %v, want 6", steady.DaysOfCover)
+	}
+
+	// 10 every other day: σ = √(250/9), safety = 1.645 * σ * √4 = 17.34
+	bursty := forecast(product, repositories.DemandStats{Total: 50, SumSquares: 500, ActiveDays: 5}, from, to, params)
+	if bursty.DemandStdDev != 5.27 || bursty.SafetyStock != 18 || bursty.ReorderPoint != 38 {
+		t.Errorf("bursty demand: σ %v, safety stock %v, reorder point %v, want 5.27, 18 and 38", bursty.DemandStdDev, bursty.SafetyStock, bursty.ReorderPoint)
+	}
+
+	// Without a supplier lead time the default applies
+	product.Supplier.LeadTimeDays = 0
+	if f := forecast(product, repositories.DemandStats{Total: 50, SumSquares: 250}, from, to, params); f.LeadTimeDays != defaultLeadTimeDays || f.ReorderPoint != 5*defaultLeadTimeDays {
+		t.Errorf("default lead time: %d days, reorder point %v", f.LeadTimeDays, f.ReorderPoint)
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"log"
+	"time"
+)
+
+// IdempotencyService remembers the responses to mutating requests sent with
+// an Idempotency-Key header, per user, for TTL
+type IdempotencyService struct {
+	store repositories.Store
+	ttl   time.Duration
+}
+
+func NewIdempotencyService(store repositories.Store, ttl time.Duration) *IdempotencyService {
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return &IdempotencyService{store: store, ttl: ttl}
+}
+
+// IdempotencyRequest identifies one request sent with a key
+type IdempotencyRequest struct {
+	UserID uint
+	Key    string
+	Method string
+	Path   string
+	URL    string // Path with query string, part of the hash
+	Body   []byte
+}
+
+func (r IdempotencyRequest) hash() string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL + "\n"))
+	h.Write(r.Body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Begin claims the key for a request. It returns the stored record and true
+// when the same request was already answered, so the stored response can be
+// replayed. Otherwise the key is reserved and the caller must Complete or
+// Release it. A key reused for a different request fails with
+// ErrIdempotencyKeyReused, one whose first request is still running with
+// ErrIdempotencyKeyInFlight.
+func (s *IdempotencyService) Begin(ctx context.Context, req IdempotencyRequest) (*models.IdempotencyKey, bool, error) {
+	hash := req.hash()
+
+	existing, err := s.store.IdempotencyKeys().Find(ctx, req.UserID, req.Key)
+	switch {
+	case err == nil && existing.ExpiresAt.Before(time.Now()):
+		if err := s.store.IdempotencyKeys().Delete(ctx, existing); err != nil {
+			return nil, false, err
+		}
+	case err == nil:
+		return existing, true, replayable(existing, hash)
+	case !errors.Is(err, repositories.ErrNotFound):
+		return nil, false, err
+	}
+
+	now := time.Now()
+	record := &models.IdempotencyKey{
+		UserID:      req.UserID,
+		Key:         req.Key,
+		Method:      req.Method,
+		Path:        req.Path,
+		RequestHash: hash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+	if err := s.store.IdempotencyKeys().Create(ctx, record); err != nil {
+		// Lost the race against a concurrent request with the same key
+		existing, findErr := s.store.IdempotencyKeys().Find(ctx, req.UserID, req.Key)
+		if findErr != nil {
+			return nil, false, err
+		}
+		return existing, true, replayable(existing, hash)
+	}
+	return record, false, nil
+}
+
+// replayable checks that a stored key can answer the request with hash
+func replayable(record *models.IdempotencyKey, hash string) error {
+	if record.RequestHash != hash {
+		return ErrIdempotencyKeyReused
+	}
+	if record.ResponseStatus == 0 {
+		return ErrIdempotencyKeyInFlight
+	}
+	return nil
+}
+
+// Complete stores the response of the request that reserved the key
+func (s *IdempotencyService) Complete(ctx context.Context, record *models.IdempotencyKey, status int, contentType string, etag string, body []byte) error {
+	record.ResponseStatus = status
+	record.ContentType = contentType
+	record.ETag = etag
+	record.ResponseBody = string(body)
+	return s.store.IdempotencyKeys().Save(ctx, record)
+}
+
+// Release frees a reserved key without storing a response, so the request can
+// be retried, e.g. after a server error
+func (s *IdempotencyService) Release(ctx context.Context, record *models.IdempotencyKey) error {
+	return s.store.IdempotencyKeys().Delete(ctx, record)
+}
+
+// Start removes expired keys every hour until ctx is cancelled
+func (s *IdempotencyService) Start(ctx context.Context) {
+	go every(ctx, time.Hour, func(ctx context.Context) {
+		removed, err := s.store.IdempotencyKeys().DeleteExpired(ctx, time.Now())
+		if err != nil {
+			log.Printf("Failed to prune idempotency keys: %v", err)
+			return
+		}
+		if removed > 0 {
+			log.Printf("🧹 Pruned %d expired idempotency keys", removed)
+		}
+	})
+}
@@ -0,0 +1,380 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"math"
+	"sort"
+)
+
+// maxKitComponents bounds the bill of materials of one kit
+const maxKitComponents = 50
+
+// KitComponentInput is one line of a bill of materials, Quantity in the base
+// unit of the component
+type KitComponentInput struct {
+	ProductID uint
+	Quantity  float64
+}
+
+// KitInput defines a product as a kit. An empty Type turns a kit back into a
+// plain product.
+type KitInput struct {
+	Type       string // models.KitAssembled, models.KitVirtual or empty
+	Components []KitComponentInput
+}
+
+// Kit is a kit product with its bill of materials
+type Kit struct {
+	Product *models.Product `json:"product"`
+	// Buildable is how many kits the component stock is enough for
+	Buildable float64 `json:"buildable"`
+	// Available is the stock of an assembled kit, Buildable for a virtual one
+	Available float64 `json:"available"`
+}
+
+// KitOperation is the result of assembling or disassembling kits. Movements
+// share Reference.
+type KitOperation struct {
+	Kit       *models.Product       `json:"kit"`
+	Reference string                `json:"reference"`
+	Movements []models.StockHistory `json:"movements"`
+}
+
+// kitComponent is a bill of materials line with its component locked
+type kitComponent struct {
+	line    models.BillOfMaterials
+	product *models.Product
+}
+
+// GetKit returns a kit with its components and availability
+func (s *ProductService) GetKit(ctx context.Context, id uint) (*Kit, error) {
+	product, err := s.store.Products().FindByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrProductNotFound)
+	}
+	if product.KitType == "" {
+		return nil, invalid(fmt.Sprintf("%s is not a kit", product.SKU))
+	}
+	product.Components, err = s.store.Products().ListComponents(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	components := make([]kitComponent, len(product.Components))
+	for i := range product.Components {
+		components[i] = kitComponent{line: product.Components[i], product: &product.Components[i].Component}
+	}
+	return newKit(product, components), nil
+}
+
+func newKit(product *models.Product, components []kitComponent) *Kit {
+	kit := &Kit{Product: product, Buildable: kitBuildable(product, components)}
+	kit.Available = product.Stock
+	if product.KitType == models.KitVirtual {
+		kit.Available = kit.Buildable
+	}
+	return kit
+}
+
+// SetKit replaces the kit type and bill of materials of a product. A virtual
+// kit holds no stock, so a product can only become one at 0 stock.
+func (s *ProductService) SetKit(ctx context.Context, userID uint, id uint, version uint, input KitInput) (*Kit, error) {
+	input, err := normalizeKit(id, input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.Transaction(ctx, func(tx repositories.Store) error {
+		product, err := tx.Products().FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return notFound(err, ErrProductNotFound)
+		}
+		if err := checkVersion(product.Version, version); err != nil {
+			return err
+		}
+		if product.HasVariants {
+			return invalid("A product with variants cannot be a kit, define the kit on a variant")
+		}
+		if input.Type == models.KitVirtual && product.Stock != 0 {
+			return invalid(fmt.Sprintf("%s still holds %s %s, a virtual kit holds no stock so bring it to 0 first", product.Name, formatQuantity(product.Stock), product.BaseUnit))
+		}
+
+		lines := make([]models.BillOfMaterials, len(input.Components))
+		for i, component := range input.Components {
+			p, err := tx.Products().FindByID(ctx, component.ProductID)
+			if err != nil {
+				return notFound(err, ErrProductNotFound)
+			}
+			switch {
+			case p.HasVariants:
+				return invalid(fmt.Sprintf("%s has variants, use one of its variants as the component", p.SKU))
+			case p.KitType == models.KitVirtual:
+				return invalid(fmt.Sprintf("%s is a virtual kit and holds no stock, use its components instead", p.SKU))
+			}
+			if err := checkQuantity(p, "Quantity of "+p.SKU, component.Quantity); err != nil {
+				return err
+			}
+			lines[i] = models.BillOfMaterials{KitID: product.ID, ComponentID: p.ID, Quantity: component.Quantity}
+		}
+		if err := tx.Products().ReplaceComponents(ctx, product.ID, lines); err != nil {
+			return err
+		}
+
+		wasLow := product.Stock < product.MinStock
+		product.KitType = input.Type
+		if err := tx.Products().Save(ctx, product); err != nil {
+			return conflict(err)
+		}
+		// A virtual kit is no longer tracked itself
+		if err := s.alerts.track(ctx, tx, product, wasLow); err != nil {
+			return err
+		}
+		if err := s.events.Publish(ctx, tx, AggregateProduct, product.ID, EventProductUpdated, product); err != nil {
+			return err
+		}
+
+		description := fmt.Sprintf("Set %s kit of %s (%s) with %d components", input.Type, product.Name, product.SKU, len(lines))
+		if input.Type == "" {
+			description = fmt.Sprintf("Removed kit definition of %s (%s)", product.Name, product.SKU)
+		}
+		logActivity(ctx, tx, userID, "UPDATE", "Product", product.ID, description)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Type == "" {
+		product, err := s.store.Products().FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &Kit{Product: product, Available: product.Stock}, nil
+	}
+	return s.GetKit(ctx, id)
+}
+
+// normalizeKit checks the kit type and the component lines
+func normalizeKit(kitID uint, input KitInput) (KitInput, error) {
+	switch input.Type {
+	case "":
+		if len(input.Components) > 0 {
+			return input, invalid("Components need a kit type, 'assembled' or 'virtual'")
+		}
+		return input, nil
+	case models.KitAssembled, models.KitVirtual:
+	default:
+		return input, invalid("Kit type must be 'assembled' or 'virtual'")
+	}
+
+	if len(input.Components) == 0 || len(input.Components) > maxKitComponents {
+		return input, invalid(fmt.Sprintf("A kit needs between 1 and %d components", maxKitComponents))
+	}
+	seen := map[uint]bool{}
+	for i, component := range input.Components {
+		switch {
+		case component.ProductID == kitID:
+			return input, invalid("A kit cannot be a component of itself")
+		case seen[component.ProductID]:
+			return input, invalid(fmt.Sprintf("Product #%d is listed twice", component.ProductID))
+		}
+		seen[component.ProductID] = true
+
+		input.Components[i].Quantity = roundQuantity(component.Quantity)
+		if input.Components[i].Quantity <= 0 {
+			return input, invalid(fmt.Sprintf("Quantity of product #%d must be greater than 0", component.ProductID))
+		}
+	}
+	return input, nil
+}
+
+// Assemble builds quantity assembled kits, consuming their components. The
+// movements of the kit and the components are recorded in one transaction
+// under a shared reference.
+func (s *StockService) Assemble(ctx context.Context, userID uint, kitID uint, quantity float64, note string) (*KitOperation, error) {
+	return s.assemble(ctx, userID, kitID, quantity, note, true)
+}
+
+// Disassemble breaks quantity assembled kits up, returning their components
+// to stock
+func (s *StockService) Disassemble(ctx context.Context, userID uint, kitID uint, quantity float64, note string) (*KitOperation, error) {
+	return s.assemble(ctx, userID, kitID, quantity, note, false)
+}
+
+func (s *StockService) assemble(ctx context.Context, userID uint, kitID uint, quantity float64, note string, build bool) (*KitOperation, error) {
+	quantity = roundQuantity(quantity)
+	if quantity <= 0 {
+		return nil, invalid("Quantity must be greater than 0")
+	}
+
+	operation := &KitOperation{Reference: newKitReference(), Movements: []models.StockHistory{}}
+	err := s.store.Transaction(ctx, func(tx repositories.Store) error {
+		kit, err := tx.Products().FindByIDForUpdate(ctx, kitID)
+		if err != nil {
+			return notFound(err, ErrProductNotFound)
+		}
+		if kit.KitType != models.KitAssembled {
+			return invalid(fmt.Sprintf("%s is not an assembled kit", kit.SKU))
+		}
+		if err := checkQuantity(kit, "Quantity", quantity); err != nil {
+			return err
+		}
+		components, err := lockKitComponents(ctx, tx, kit)
+		if err != nil {
+			return err
+		}
+
+		action, verb, kitType, componentType := "ASSEMBLE", "Assembled", "in", "out"
+		if !build {
+			action, verb, kitType, componentType = "DISASSEMBLE", "Disassembled", "out", "in"
+		}
+		note := kitNote(kit, quantity, build, note)
+
+		// Components go out before the kit comes in and the kit goes out before
+		// the components come back, so every stock check sees the final state
+		kitMovement := models.StockHistory{Type: kitType, Quantity: quantity, Note: note, Reference: operation.Reference}
+		if !build {
+			history, err := s.applyMovement(ctx, tx, kit, kitMovement)
+			if err != nil {
+				return err
+			}
+			operation.Movements = append(operation.Movements, *history)
+		}
+		movements, err := s.moveComponents(ctx, tx, components, componentType, quantity, note, operation.Reference)
+		if err != nil {
+			return err
+		}
+		operation.Movements = append(operation.Movements, movements...)
+		if build {
+			history, err := s.applyMovement(ctx, tx, kit, kitMovement)
+			if err != nil {
+				return err
+			}
+			operation.Movements = append(operation.Movements, *history)
+		}
+
+		logActivity(ctx, tx, userID, action, "Product", kit.ID, fmt.Sprintf("%s %s of %s (%s), reference %s", verb, formatQuantity(quantity), kit.Name, kit.SKU, operation.Reference))
+		operation.Kit = kit
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return operation, nil
+}
+
+// moveVirtualKit applies a movement of quantity virtual kits to their
+// components. The stock before and after is the number of kits available.
+func (s *StockService) moveVirtualKit(ctx context.Context, tx repositories.Store, userID uint, kit *models.Product, movementType string, quantity float64, note string, result *StockMovementResult) error {
+	components, err := lockKitComponents(ctx, tx, kit)
+	if err != nil {
+		return err
+	}
+
+	reference := newKitReference()
+	before := kitBuildable(kit, components)
+	movements, err := s.moveComponents(ctx, tx, components, movementType, quantity, fmt.Sprintf("Kit %s x %s: %s", kit.SKU, formatQuantity(quantity), note), reference)
+	if err != nil {
+		return err
+	}
+	after := kitBuildable(kit, components)
+
+	logActivity(ctx, tx, userID, "UPDATE", "Product", kit.ID, fmt.Sprintf("Updated stock for kit %s (%s): %s %s %s through %d components, reference %s", kit.Name, kit.SKU, movementType, formatQuantity(quantity), kit.BaseUnit, len(components), reference))
+
+	*result = StockMovementResult{
+		Product:     kit,
+		Movements:   movements,
+		StockBefore: before,
+		StockAfter:  after,
+	}
+	return nil
+}
+
+// moveComponents moves the components of quantity kits. Running short of a
+// component fails with a message naming it.
+func (s *StockService) moveComponents(ctx context.Context, tx repositories.Store, components []kitComponent, movementType string, quantity float64, note string, reference string) ([]models.StockHistory, error) {
+	movements := make([]models.StockHistory, 0, len(components))
+	for _, component := range components {
+		needed := roundQuantity(component.line.Quantity * quantity)
+		if movementType == "out" && component.product.Stock < needed {
+			return nil, invalid(fmt.Sprintf("Insufficient stock of %s: %s %s needed, %s available", component.product.SKU, formatQuantity(needed), component.product.BaseUnit, formatQuantity(component.product.Stock)))
+		}
+
+		history, err := s.applyMovement(ctx, tx, component.product, models.StockHistory{Type: movementType, Quantity: needed, Note: note, Reference: reference})
+		if err != nil {
+			return nil, err
+		}
+		movements = append(movements, *history)
+	}
+	return movements, nil
+}
+
+// lockKitComponents loads the bill of materials of a kit and locks the
+// components in primary key order, so concurrent kit operations sharing
+// components cannot deadlock
+func lockKitComponents(ctx context.Context, tx repositories.Store, kit *models.Product) ([]kitComponent, error) {
+	lines, err := tx.Products().ListComponents(ctx, kit.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, invalid(fmt.Sprintf("Kit %s has no components", kit.SKU))
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i].ComponentID < lines[j].ComponentID })
+
+	components := make([]kitComponent, len(lines))
+	for i, line := range lines {
+		product, err := tx.Products().FindByIDForUpdate(ctx, line.ComponentID)
+		if err != nil {
+			return nil, invalid(fmt.Sprintf("Component #%d of kit %s no longer exists, update the kit first", line.ComponentID, kit.SKU))
+		}
+		components[i] = kitComponent{line: line, product: product}
+	}
+	return components, nil
+}
+
+// kitBuildable returns how many kits the component stock is enough for,
+// whole kits unless the kit is fractional
+func kitBuildable(kit *models.Product, components []kitComponent) float64 {
+	if len(components) == 0 {
+		return 0
+	}
+	buildable := math.Inf(1)
+	for _, component := range components {
+		if component.line.Quantity > 0 {
+			buildable = math.Min(buildable, component.product.Stock/component.line.Quantity)
+		}
+	}
+	if math.IsInf(buildable, 1) {
+		return 0
+	}
+	if kit.Fractional {
+		return math.Floor(math.Round(buildable*quantityScale*1000)/1000) / quantityScale
+	}
+	return math.Floor(math.Round(buildable*quantityScale) / quantityScale)
+}
+
+func kitNote(kit *models.Product, quantity float64, build bool, note string) string {
+	action := "Assembly"
+	if !build {
+		action = "Disassembly"
+	}
+	text := fmt.Sprintf("%s of %s x %s", action, kit.SKU, formatQuantity(quantity))
+	if note != "" {
+		text += ": " + note
+	}
+	return text
+}
+
+// newKitReference returns a random reference grouping the movements of one
+// kit operation
+func newKitReference() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "kit_" + hex.EncodeToString(b)
+}
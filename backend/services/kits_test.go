@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+)
+
+// createTestKit defines a kit of 2 A and 1 B, with 10 A and 3 B in stock
+func createTestKit(t *testing.T, store repositories.Store, kitType string) (kit, a, b *models.Product) {
+	t.Helper()
+	products, _, _ := newTestServices(store)
+	supplier := createTestSupplier(t, store)
+
+	a = createTestProduct(t, products, supplier.ID, "A", 10)
+	b = createTestProduct(t, products, supplier.ID, "B", 3)
+	kit = createTestProduct(t, products, supplier.ID, "KIT", 0)
+	_, err := products.SetKit(context.Background(), 1, kit.ID, 0, KitInput{
+		Type:       kitType,
+		Components: []KitComponentInput{{ProductID: a.ID, Quantity: 2}, {ProductID: b.ID, Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("SetKit: %v", err)
+	}
+	return kit, a, b
+}
+
+// assertStock checks the stock of products by id
+func assertStock(t *testing.T, store repositories.Store, want map[uint]float64) {
+	t.Helper()
+	for id, stock := range want {
+		product, err := store.Products().FindByID(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if product.Stock != stock {
+			t.Errorf("%s stock = %v, want %v", product.SKU, product.Stock, stock)
+		}
+	}
+}
+
+func TestAssembleKit(t *testing.T) {
+	store := newTestStore(t)
+	products, stock, _ := newTestServices(store)
+	kit, a, b := createTestKit(t, store, models.KitAssembled)
+	ctx := context.Background()
+
+	info, err := products.GetKit(ctx, kit.ID)
+	if err != nil {
+		t.Fatalf("GetKit: %v", err)
+	}
+	if info.Buildable != 3 {
+		t.Errorf("buildable = %v, want 3", info.Buildable)
+	}
+
+	operation, err := stock.Assemble(ctx, 1, kit.ID, 2, "")
+	if err != nil {
+		t.Fatalf("Assemble: %v", err)
+	}
+	if len(operation.Movements) != 3 {
+		t.Fatalf("%d movements, want 3", len(operation.Movements))
+	}
+	for _, movement := range operation.Movements {
+		if movement.Reference != operation.Reference {
+			t.Errorf("movement reference %q, want %q", movement.Reference, operation.Reference)
+		}
+	}
+	assertStock(t, store, map[uint]float64{kit.ID: 2, a.ID: 6, b.ID: 1})
+
+	// One B is left, nothing moves when the second component runs short
+	if _, err := stock.Assemble(ctx, 1, kit.ID, 2, ""); err == nil {
+		t.Fatal("Assemble beyond the component stock succeeded")
+	}
+	assertStock(t, store, map[uint]float64{kit.ID: 2, a.ID: 6, b.ID: 1})
+
+	if _, err := stock.Disassemble(ctx, 1, kit.ID, 1, ""); err != nil {
+		t.Fatalf("Disassemble: %v", err)
+	}
+	assertStock(t, store, map[uint]float64{kit.ID: 1, a.ID: 8, b.ID: 2})
+
+	if _, err := stock.Disassemble(ctx, 1, kit.ID, 2, ""); err == nil {
+		t.Error("Disassemble of more kits than in stock succeeded")
+	}
+}
+
+func TestVirtualKitMovesComponents(t *testing.T) {
+	store := newTestStore(t)
+	_, stock, _ := newTestServices(store)
+	kit, a, b := createTestKit(t, store, models.KitVirtual)
+	ctx := context.Background()
+
+	result, err := stock.Move(ctx, 1, kit.ID, StockMovementInput{Type: "out", Quantity: 2})
+	if err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if result.StockBefore != 3 || result.StockAfter != 1 || len(result.Movements) != 2 {
+		t.Errorf("kits %v -> %v with %d movements, want 3 -> 1 with 2", result.StockBefore, result.StockAfter, len(result.Movements))
+	}
+	assertStock(t, store, map[uint]float64{kit.ID: 0, a.ID: 6, b.ID: 1})
+
+	if _, err := stock.Assemble(ctx, 1, kit.ID, 1, ""); err == nil {
+		t.Error("Assemble of a virtual kit succeeded")
+	}
+}
+
+func TestNormalizeKit(t *testing.T) {
+	tests := []struct {
+		name  string
+		input KitInput
+	}{
+		{"unknown type", KitInput{Type: "bundle", Components: []KitComponentInput{{ProductID: 2, Quantity: 1}}}},
+		{"no components", KitInput{Type: models.KitAssembled}},
+		{"components without a type", KitInput{Components: []KitComponentInput{{ProductID: 2, Quantity: 1}}}},
+		{"itself", KitInput{Type: models.KitAssembled, Components: []KitComponentInput{{ProductID: 1, Quantity: 1}}}},
+		{"listed twice", KitInput{Type: models.KitAssembled, Components: []KitComponentInput{{ProductID: 2, Quantity: 1}, {ProductID: 2, Quantity: 2}}}},
+		{"no quantity", KitInput{Type: models.KitVirtual, Components: []KitComponentInput{{ProductID: 2, Quantity: 0.0001}}}},
+	}
+	for _, tt := range tests {
+		if _, err := normalizeKit(1, tt.input); err == nil {
+			t.Errorf("%s: accepted", tt.name)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	// outboxPollInterval is how often the relay looks for unpublished events
+	outboxPollInterval = time.Second
+	// outboxBatchSize is how many events the relay reads per poll
+	outboxBatchSize = 200
+)
+
+// OutboxOptions configures the relay
+type OutboxOptions struct {
+	// Retention is how long published events are kept
+	Retention time.Duration
+}
+
+// OutboxStatus describes the relay backlog and the configured sinks
+type OutboxStatus struct {
+	repositories.OutboxStats
+	Sinks  []string             `json:"sinks"`
+	Recent []models.OutboxEvent `json:"recent"`
+}
+
+// OutboxService is the transactional outbox. Publish records events in the
+// transaction of the change they describe, and the relay hands them to every
+// sink once committed.
+//
+// Delivery is at least once: an event is retried until all sinks accept it,
+// so a sink may see it again after a partial failure and should drop
+// duplicates by event ID. Events of one aggregate are relayed in the order
+// they were written; a failing event holds back the later events of its
+// aggregate but not those of others. Ordering assumes a single relay, so run
+// the API with one instance or disable the relay on the others.
+type OutboxService struct {
+	store   repositories.Store
+	sinks   []OutboxSink
+	options OutboxOptions
+}
+
+func NewOutboxService(store repositories.Store, sinks []OutboxSink, options OutboxOptions) *OutboxService {
+	if options.Retention <= 0 {
+		options.Retention = 7 * 24 * time.Hour
+	}
+	return &OutboxService{store: store, sinks: sinks, options: options}
+}
+
+// AddSink registers another sink, e.g. a message broker client. It must be
+// called before Start.
+func (s *OutboxService) AddSink(sink OutboxSink) {
+	s.sinks = append(s.sinks, sink)
+}
+
+// Publish writes the event to the outbox as part of tx
+func (s *OutboxService) Publish(ctx context.Context, tx repositories.Store, aggregateType string, aggregateID uint, eventType string, data interface{}) error {
+	event := newEvent(eventType, data)
+	event.AggregateType = aggregateType
+	event.AggregateID = aggregateID
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return tx.Outbox().Create(ctx, &models.OutboxEvent{
+		EventID:       event.ID,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       string(payload),
+		NextAttemptAt: event.OccurredAt,
+		CreatedAt:     event.OccurredAt,
+	})
+}
+
+// Start relays committed events and prunes published ones until ctx is
+// cancelled
+func (s *OutboxService) Start(ctx context.Context) {
+	go every(ctx, outboxPollInterval, s.relay)
+	go every(ctx, 24*time.Hour, func(ctx context.Context) {
+		removed, err := s.store.Outbox().DeletePublishedBefore(ctx, time.Now().Add(-s.options.Retention))
+		if err != nil {
+			log.Printf("Failed to prune outbox: %v", err)
+			return
+		}
+		if removed > 0 {
+			log.Printf("🧹 Pruned %d published outbox events", removed)
+		}
+	})
+}
+
+// relay publishes the unpublished events in the order they were written. Once
+// an event of an aggregate is waiting for a retry, the later events of that
+// aggregate are left for a later poll.
+func (s *OutboxService) relay(ctx context.Context) {
+	events, err := s.store.Outbox().ListUnpublished(ctx, outboxBatchSize)
+	if err != nil {
+		log.Printf("Failed to list outbox events: %v", err)
+		return
+	}
+
+	now := time.Now()
+	blocked := map[string]bool{}
+	for i := range events {
+		if ctx.Err() != nil {
+			return
+		}
+
+		event := &events[i]
+		aggregate := fmt.Sprintf("%s:%d", event.AggregateType, event.AggregateID)
+		if blocked[aggregate] {
+			continue
+		}
+		if event.NextAttemptAt.After(now) || !s.publish(ctx, event) {
+			blocked[aggregate] = true
+		}
+	}
+}
+
+// publish hands one event to every sink and records the outcome. It reports
+// whether the event is now published.
+func (s *OutboxService) publish(ctx context.Context, event *models.OutboxEvent) bool {
+	msg := OutboxMessage{
+		EventID:       event.EventID,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		EventType:     event.EventType,
+		Payload:       []byte(event.Payload),
+	}
+
+	var failures []string
+	for _, sink := range s.sinks {
+		if err := sink.Publish(ctx, msg); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", sink.Name(), err))
+		}
+	}
+
+	now := time.Now()
+	event.Attempts++
+	if len(failures) == 0 {
+		event.PublishedAt = &now
+		event.LastError = ""
+	} else {
+		event.LastError = strings.Join(failures, "; ")
+		event.NextAttemptAt = now.Add(outboxBackoff(event.Attempts))
+	}
+
+	if err := s.store.Outbox().Save(ctx, event); err != nil {
+		log.Printf("Failed to update outbox event %d: %v", event.ID, err)
+		return false
+	}
+	return event.PublishedAt != nil
+}
+
+// outboxBackoff doubles the wait after every failed attempt, from 1 second up
+// to 5 minutes. Events are never dropped, as that would break the ordering.
+func outboxBackoff(attempts int) time.Duration {
+	backoff := time.Second << (attempts - 1)
+	if backoff > 5*time.Minute || backoff <= 0 {
+		return 5 * time.Minute
+	}
+	return backoff
+}
+
+// Status returns the backlog, the configured sinks and the latest events,
+// only unpublished ones when pending is set
+func (s *OutboxService) Status(ctx context.Context, pending bool, limit int) (*OutboxStatus, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	stats, err := s.store.Outbox().Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recent, err := s.store.Outbox().ListRecent(ctx, pending, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &OutboxStatus{OutboxStats: *stats, Sinks: []string{}, Recent: recent}
+	for _, sink := range s.sinks {
+		status.Sinks = append(status.Sinks, sink.Name())
+	}
+	return status, nil
+}
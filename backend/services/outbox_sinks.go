@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Outbox sink names, as used in OUTBOX_SINKS
+const (
+	OutboxSinkWebhook = "webhook"
+	OutboxSinkFile    = "file"
+)
+
+// OutboxMessage is an outbox event as handed to the sinks. Payload is the
+// JSON event envelope.
+type OutboxMessage struct {
+	EventID       string
+	AggregateType string
+	AggregateID   uint
+	EventType     string
+	Payload       []byte
+}
+
+// Key identifies the aggregate of the message. Brokers that partition by key
+// keep the events of one aggregate in order.
+func (m OutboxMessage) Key() string {
+	return fmt.Sprintf("%s:%d", m.AggregateType, m.AggregateID)
+}
+
+// OutboxSink receives relayed events. Publish must only return nil once the
+// message is durably accepted; it may be called again for the same message.
+type OutboxSink interface {
+	Name() string
+	Publish(ctx context.Context, msg OutboxMessage) error
+}
+
+// WebhookSink queues events for the webhook subscriptions
+type WebhookSink struct {
+	Webhooks *WebhookService
+}
+
+func (s *WebhookSink) Name() string {
+	return OutboxSinkWebhook
+}
+
+func (s *WebhookSink) Publish(ctx context.Context, msg OutboxMessage) error {
+	return s.Webhooks.Enqueue(ctx, msg)
+}
+
+// MessageBroker is the subset of a NATS or Kafka client the broker sink
+// needs. subject maps to a NATS subject or a Kafka topic, key to the Kafka
+// partition key.
+type MessageBroker interface {
+	Publish(ctx context.Context, subject, key string, payload []byte) error
+}
+
+// BrokerSink publishes events to a message broker, one subject per event
+// type, e.g. "inventory.stock.changed"
+type BrokerSink struct {
+	Broker        MessageBroker
+	SubjectPrefix string
+}
+
+func (s *BrokerSink) Name() string {
+	return "broker"
+}
+
+func (s *BrokerSink) Publish(ctx context.Context, msg OutboxMessage) error {
+	return s.Broker.Publish(ctx, s.SubjectPrefix+msg.EventType, msg.Key(), msg.Payload)
+}
+
+// FileSink appends events to a JSON lines file
+type FileSink struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (s *FileSink) Name() string {
+	return OutboxSinkFile
+}
+
+func (s *FileSink) Publish(ctx context.Context, msg OutboxMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(msg.Payload, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// NewOutboxSinksFromEnv builds the sinks listed in OUTBOX_SINKS, webhook by
+// default. Broker sinks need a client and are added with AddSink.
+func NewOutboxSinksFromEnv(webhooks *WebhookService) []OutboxSink {
+	var sinks []OutboxSink
+	for _, name := range strings.Split(getEnv("OUTBOX_SINKS", OutboxSinkWebhook), ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "":
+		case OutboxSinkWebhook:
+			sinks = append(sinks, &WebhookSink{Webhooks: webhooks})
+		case OutboxSinkFile:
+			sinks = append(sinks, &FileSink{Path: getEnv("OUTBOX_FILE_PATH", "./events/outbox.jsonl")})
+		default:
+			log.Printf("⚠️ Unknown outbox sink %q ignored", name)
+		}
+	}
+	return sinks
+}
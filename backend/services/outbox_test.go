@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"inventory-backend/models"
+)
+
+// flakySink records the events it accepts and rejects those of failing types
+type flakySink struct {
+	failing  map[string]bool
+	accepted []string
+}
+
+func (s *flakySink) Name() string { return "flaky" }
+
+func (s *flakySink) Publish(ctx context.Context, msg OutboxMessage) error {
+	if s.failing[msg.EventType] {
+		return errors.New("unavailable")
+	}
+	s.accepted = append(s.accepted, msg.EventType)
+	return nil
+}
+
+func TestOutboxRelayKeepsAggregateOrder(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	sink := &flakySink{failing: map[string]bool{"first": true}}
+	outbox := NewOutboxService(store, []OutboxSink{sink}, OutboxOptions{})
+
+	for _, event := range []struct {
+		id        uint
+		eventType string
+	}{{1, "first"}, {2, "other"}, {1, "second"}} {
+		if err := outbox.Publish(ctx, store, AggregateProduct, event.id, event.eventType, nil); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	// The failed event holds back the later event of its product only
+	outbox.relay(ctx)
+	if want := []string{"other"}; !reflect.DeepEqual(sink.accepted, want) {
+		t.Fatalf("accepted %v, want %v", sink.accepted, want)
+	}
+
+	// Still waiting for the retry
+	outbox.relay(ctx)
+	if len(sink.accepted) != 1 {
+		t.Fatalf("accepted %v before the retry was due", sink.accepted)
+	}
+
+	sink.failing = nil
+	if err := newTestDB(t).Model(&models.OutboxEvent{}).Where("published_at IS NULL").
+		Update("next_attempt_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	outbox.relay(ctx)
+	if want := []string{"other", "first", "second"}; !reflect.DeepEqual(sink.accepted, want) {
+		t.Errorf("accepted %v, want %v", sink.accepted, want)
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{4, 8 * time.Second},
+		{9, 256 * time.Second},
+		{10, 5 * time.Minute},
+		{100, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := outboxBackoff(tt.attempts); got != tt.want {
+			t.Errorf("outboxBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
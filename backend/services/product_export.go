@@ -0,0 +1,338 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"inventory-backend/models"
+	"io"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ProductExportColumns are the columns shared by every product export format.
+// They match the columns accepted by ParseProductImport so an export can be
+// edited and imported again.
+var ProductExportColumns = []string{
+	"id", "sku", "name", "description", "price", "stock", "min_stock",
+	"max_stock", "min_order_qty", "pack_size", "base_unit", "fractional",
+	"supplier_id", "supplier", "category_id", "category", "image_url",
+}
+
+// attributeColumnPrefix starts the columns holding custom attribute values,
+// e.g. attr.voltage. They follow ProductExportColumns.
+const attributeColumnPrefix = "attr."
+
+// productExportHeader returns ProductExportColumns followed by a column per
+// attribute key
+func productExportHeader(attributeKeys []string) []string {
+	header := append([]string{}, ProductExportColumns...)
+	for _, key := range attributeKeys {
+		header = append(header, attributeColumnPrefix+key)
+	}
+	return header
+}
+
+// ProductExportRecord is a product flattened to the export columns
+type ProductExportRecord struct {
+	ID          uint    `json:"id"`
+	SKU         string  `json:"sku"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+	Stock       float64 `json:"stock"`
+	MinStock    float64 `json:"min_stock"`
+	MaxStock    float64 `json:"max_stock"`
+	MinOrderQty float64 `json:"min_order_qty"`
+	PackSize    float64 `json:"pack_size"`
+	BaseUnit    string  `json:"base_unit"`
+	Fractional  bool    `json:"fractional"`
+	SupplierID  uint    `json:"supplier_id"`
+	Supplier    string  `json:"supplier"`
+	CategoryID  *uint   `json:"category_id"`
+	Category    string  `json:"category"`
+	ImageURL    string  `json:"image_url"`
+	// Attributes holds the custom attribute values by key, typed as in the
+	// product JSON
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	// text holds the attribute values in their text form
+	text map[string]string
+}
+
+func NewProductExportRecord(p models.Product) ProductExportRecord {
+	record := ProductExportRecord{
+		ID:          p.ID,
+		SKU:         p.SKU,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.Price,
+		Stock:       p.Stock,
+		MinStock:    p.MinStock,
+		MaxStock:    p.MaxStock,
+		MinOrderQty: p.MinOrderQty,
+		PackSize:    p.PackSize,
+		BaseUnit:    p.BaseUnit,
+		Fractional:  p.Fractional,
+		SupplierID:  p.SupplierID,
+		Supplier:    p.Supplier.Name,
+		CategoryID:  p.CategoryID,
+		ImageURL:    p.ImageURL,
+	}
+	if p.CategoryID != nil {
+		record.Category = p.Category.Name
+	}
+	if len(p.Attributes) > 0 {
+		record.Attributes = make(map[string]interface{}, len(p.Attributes))
+		record.text = make(map[string]string, len(p.Attributes))
+		for _, attribute := range p.Attributes {
+			var value interface{} = attribute.Value
+			switch {
+			case attribute.Type == models.AttributeNumber && attribute.Number != nil:
+				value = *attribute.Number
+			case attribute.Type == models.AttributeBool:
+				value = attribute.Value == "true"
+			}
+			record.Attributes[attribute.Key] = value
+			record.text[attribute.Key] = attribute.Value
+		}
+	}
+	return record
+}
+
+// values returns the record in the order of productExportHeader. A missing
+// category or attribute value is returned as nil.
+func (r ProductExportRecord) values(attributeKeys []string) []interface{} {
+	var categoryID interface{}
+	if r.CategoryID != nil {
+		categoryID = *r.CategoryID
+	}
+
+	values := []interface{}{
+		r.ID, r.SKU, r.Name, r.Description, r.Price, r.Stock, r.MinStock,
+		r.MaxStock, r.MinOrderQty, r.PackSize, r.BaseUnit, r.Fractional,
+		r.SupplierID, r.Supplier, categoryID, r.Category, r.ImageURL,
+	}
+	for _, key := range attributeKeys {
+		values = append(values, r.Attributes[key])
+	}
+	return values
+}
+
+// strings returns the record in the order of productExportHeader as text
+func (r ProductExportRecord) strings(attributeKeys []string) []string {
+	categoryID := ""
+	if r.CategoryID != nil {
+		categoryID = strconv.FormatUint(uint64(*r.CategoryID), 10)
+	}
+
+	values := []string{
+		strconv.FormatUint(uint64(r.ID), 10), r.SKU, r.Name, r.Description,
+		strconv.FormatFloat(r.Price, 'f', -1, 64), formatQuantity(r.Stock), formatQuantity(r.MinStock),
+		formatQuantity(r.MaxStock), formatQuantity(r.MinOrderQty), formatQuantity(r.PackSize), r.BaseUnit, strconv.FormatBool(r.Fractional),
+		strconv.FormatUint(uint64(r.SupplierID), 10), r.Supplier, categoryID, r.Category, r.ImageURL,
+	}
+	for _, key := range attributeKeys {
+		values = append(values, r.text[key])
+	}
+	return values
+}
+
+// ProductExportContentTypes lists the supported export formats
+var ProductExportContentTypes = map[string]string{
+	"csv":  "text/csv",
+	"json": "application/json",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// ProductExporter writes products in batches so an export never needs the
+// whole catalog in memory. Close must be called to finish the file.
+type ProductExporter interface {
+	WriteBatch(products []models.Product) error
+	Close() error
+}
+
+// NewProductExporter returns an exporter for format writing to writer. The
+// tabular formats get a column per attribute key, JSON records carry every
+// attribute value of the product.
+func NewProductExporter(format string, writer io.Writer, attributeKeys []string) (ProductExporter, error) {
+	switch format {
+	case "csv":
+		return newCSVProductExporter(writer, attributeKeys)
+	case "json":
+		return &jsonProductExporter{writer: writer}, nil
+	case "xlsx":
+		return newExcelProductExporter(writer, attributeKeys)
+	}
+	return nil, invalid("Format must be 'csv', 'json' or 'xlsx'")
+}
+
+type csvProductExporter struct {
+	w             *csv.Writer
+	attributeKeys []string
+}
+
+func newCSVProductExporter(writer io.Writer, attributeKeys []string) (*csvProductExporter, error) {
+	w := csv.NewWriter(writer)
+	if err := w.Write(productExportHeader(attributeKeys)); err != nil {
+		return nil, err
+	}
+	return &csvProductExporter{w: w, attributeKeys: attributeKeys}, nil
+}
+
+func (e *csvProductExporter) WriteBatch(products []models.Product) error {
+	for _, p := range products {
+		if err := e.w.Write(NewProductExportRecord(p).strings(e.attributeKeys)); err != nil {
+			return err
+		}
+	}
+	// Flush per batch so rows reach the client as they are produced
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvProductExporter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonProductExporter writes a JSON array one record at a time
+type jsonProductExporter struct {
+	writer io.Writer
+	count  int
+}
+
+func (e *jsonProductExporter) WriteBatch(products []models.Product) error {
+	for _, p := range products {
+		data, err := json.MarshalIndent(NewProductExportRecord(p), "  ", "  ")
+		if err != nil {
+			return err
+		}
+
+		prefix := ",\n  "
+		if e.count == 0 {
+			prefix = "[\n  "
+		}
+		if _, err := io.WriteString(e.writer, prefix); err != nil {
+			return err
+		}
+		if _, err := e.writer.Write(data); err != nil {
+			return err
+		}
+		e.count++
+	}
+	return nil
+}
+
+func (e *jsonProductExporter) Close() error {
+	suffix := "\n]\n"
+	if e.count == 0 {
+		suffix = "[]\n"
+	}
+	_, err := io.WriteString(e.writer, suffix)
+	return err
+}
+
+// excelProductExporter uses the excelize stream writer, which spills rows to
+// a temporary file once excelize.StreamChunkSize of sheet data is buffered,
+// and zips the workbook straight into the writer on Close. Memory levels off
+// at a few chunks whatever the row count.
+type excelProductExporter struct {
+	writer        io.Writer
+	file          *excelize.File
+	stream        *excelize.StreamWriter
+	row           int
+	attributeKeys []string
+}
+
+func newExcelProductExporter(writer io.Writer, attributeKeys []string) (*excelProductExporter, error) {
+	f := excelize.NewFile()
+	sheetName := "Products"
+	f.SetSheetName("Sheet1", sheetName)
+
+	sw, err := f.NewStreamWriter(sheetName)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	columns := productExportHeader(attributeKeys)
+	if err := sw.SetColWidth(1, len(columns), 20); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	// Style Header
+	style, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#CCCCCC"}, Pattern: 1},
+	})
+	headers := make([]interface{}, len(columns))
+	for i, header := range columns {
+		headers[i] = excelize.Cell{StyleID: style, Value: header}
+	}
+	if err := sw.SetRow("A1", headers); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &excelProductExporter{writer: writer, file: f, stream: sw, row: 1, attributeKeys: attributeKeys}, nil
+}
+
+func (e *excelProductExporter) WriteBatch(products []models.Product) error {
+	for _, p := range products {
+		e.row++
+		cell, _ := excelize.CoordinatesToCellName(1, e.row)
+		if err := e.stream.SetRow(cell, NewProductExportRecord(p).values(e.attributeKeys)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *excelProductExporter) Close() error {
+	defer e.file.Close()
+
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	// File.Write builds the whole archive in a buffer before copying it out,
+	// which grows with the row count. Handing excelize a zip writer on our
+	// writer streams the archive instead and leaves its buffer empty.
+	e.file.SetZipWriter(func(io.Writer) excelize.ZipWriter { return zip.NewWriter(e.writer) })
+	return e.file.Write(io.Discard)
+}
+
+// GenerateProductCSV writes the product list as CSV with ProductExportColumns
+func GenerateProductCSV(products []models.Product, writer io.Writer) error {
+	return generateProducts("csv", products, writer)
+}
+
+// GenerateProductJSON writes the product list as a JSON array of export records
+func GenerateProductJSON(products []models.Product, writer io.Writer) error {
+	return generateProducts("json", products, writer)
+}
+
+func generateProducts(format string, products []models.Product, writer io.Writer) error {
+	// The attribute columns are those used by the products, in order of
+	// appearance
+	var attributeKeys []string
+	seen := map[string]bool{}
+	for _, p := range products {
+		for _, attribute := range p.Attributes {
+			if !seen[attribute.Key] {
+				seen[attribute.Key] = true
+				attributeKeys = append(attributeKeys, attribute.Key)
+			}
+		}
+	}
+
+	exporter, err := NewProductExporter(format, writer, attributeKeys)
+	if err != nil {
+		return err
+	}
+	if err := exporter.WriteBatch(products); err != nil {
+		return err
+	}
+	return exporter.Close()
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/metrics"
+	"testing"
+	"time"
+
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// BenchmarkProductExport streams catalogs of growing size through the batch
+// iterator. The heap is sampled in the background for the whole export,
+// including the Close that finishes the file, and peak-heap-MB reports the
+// growth over the heap in use before the export. Rows are written batch by
+// batch and never held together, so the benchmark fails when a format's
+// peak grows with the row count.
+func BenchmarkProductExport(b *testing.B) {
+	// baselines holds the peak of each format at the smallest size
+	baselines := map[string]uint64{}
+
+	for _, size := range []struct {
+		name string
+		rows int
+	}{{"1k", 1000}, {"10k", 10000}, {"100k", 100000}} {
+		b.Run(size.name, func(b *testing.B) {
+			db := newTestDB(b)
+			store := repositories.NewStore(db)
+			products, _, _ := newTestServices(store)
+			seedExportProducts(b, db, size.rows)
+
+			for _, format := range []string{"csv", "json", "xlsx"} {
+				b.Run(format, func(b *testing.B) {
+					b.ReportAllocs()
+					runtime.GC()
+
+					sampler := startHeapSampler()
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						if err := products.Export(context.Background(), repositories.ProductFilter{}, format, io.Discard); err != nil {
+							b.Fatal(err)
+						}
+					}
+					b.StopTimer()
+					peak := sampler.stop()
+
+					b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+
+					baseline, ok := baselines[format]
+					if !ok {
+						baselines[format] = peak
+						return
+					}
+					// Allow for GC pacing noise, a leak grows far beyond this
+					limit := 2*baseline + exportHeapSlack
+					if format == "xlsx" {
+						limit += xlsxStreamHeap
+					}
+					if peak > limit {
+						b.Errorf("%s export of %d rows peaked at %.1f MB over the heap, more than %.1f MB: memory grows with the row count",
+							format, size.rows, float64(peak)/(1<<20), float64(limit)/(1<<20))
+					}
+				})
+			}
+		})
+	}
+}
+
+const (
+	// exportHeapSlack is the heap growth tolerated over twice the baseline peak
+	exportHeapSlack = 8 << 20
+	// xlsxStreamHeap is the sheet data the excelize stream writer buffers
+	// before spilling to a temporary file. The buffer grows by doubling and
+	// the outgrown copies wait for the GC, so the XLSX peak levels off around
+	// three chunks instead of one.
+	xlsxStreamHeap = 3 * excelize.StreamChunkSize
+)
+
+// heapSampler records the peak of the live heap above the heap in use when it
+// started
+type heapSampler struct {
+	done chan struct{}
+	peak chan uint64
+}
+
+func startHeapSampler() *heapSampler {
+	s := &heapSampler{done: make(chan struct{}), peak: make(chan uint64)}
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	read := func() uint64 {
+		metrics.Read(sample)
+		return sample[0].Value.Uint64()
+	}
+
+	start := read()
+	go func() {
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+
+		var peak uint64
+		for {
+			if heap := read(); heap > start {
+				peak = max(peak, heap-start)
+			}
+			select {
+			case <-ticker.C:
+			case <-s.done:
+				s.peak <- peak
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// stop takes a last sample and returns the peak
+func (s *heapSampler) stop() uint64 {
+	close(s.done)
+	return <-s.peak
+}
+
+// seedExportProducts inserts rows synthetic products spread over a few
+// suppliers and categories
+func seedExportProducts(b *testing.B, db *gorm.DB, rows int) {
+	b.Helper()
+	store := repositories.NewStore(db)
+	ctx := context.Background()
+
+	var supplierIDs, categoryIDs []uint
+	for i := 1; i <= 5; i++ {
+		supplier := models.Supplier{Name: fmt.Sprintf("Benchmark Supplier %d", i)}
+		if err := store.Suppliers().Create(ctx, &supplier); err != nil {
+			b.Fatal(err)
+		}
+		supplierIDs = append(supplierIDs, supplier.ID)
+	}
+	for i := 1; i <= 8; i++ {
+		category := models.Category{Name: fmt.Sprintf("Benchmark Category %d", i)}
+		if err := store.Categories().Create(ctx, &category); err != nil {
+			b.Fatal(err)
+		}
+		categoryIDs = append(categoryIDs, category.ID)
+	}
+
+	products := make([]models.Product, 0, rows)
+	for id := 1; id <= rows; id++ {
+		products = append(products, models.Product{
+			SKU:         fmt.Sprintf("SKU-%08d", id),
+			Name:        fmt.Sprintf("Product %d", id),
+			Description: "Synthetic product used to measure export memory usage",
+			Price:       float64(id%1000) + 0.99,
+			Stock:       float64(id % 500),
+			MinStock:    10,
+			PackSize:    1,
+			BaseUnit:    defaultBaseUnit,
+			SupplierID:  supplierIDs[id%len(supplierIDs)],
+			CategoryID:  &categoryIDs[id%len(categoryIDs)],
+		})
+	}
+	if err := db.CreateInBatches(products, exportBatchSize).Error; err != nil {
+		b.Fatal(err)
+	}
+}
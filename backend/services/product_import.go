@@ -0,0 +1,571 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ImportRow is one data row of an import file keyed by lower-case column name
+type ImportRow struct {
+	Line   int
+	Fields map[string]string
+}
+
+// ImportRowError describes why a row of an import file was rejected
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	SKU     string `json:"sku"`
+	Message string `json:"message"`
+}
+
+// ImportResult summarises an import run
+type ImportResult struct {
+	DryRun  bool             `json:"dry_run"`
+	Total   int              `json:"total"`
+	Created int              `json:"created"`
+	Updated int              `json:"updated"`
+	Errors  []ImportRowError `json:"errors"`
+}
+
+// errImportRejected rolls back the import transaction
+var errImportRejected = errors.New("import rejected")
+
+// ParseProductImport reads a CSV, XLSX or JSON file into import rows. The
+// format is taken from the file name extension. CSV and XLSX files must start
+// with a header row, JSON files hold an array of objects as written by
+// GenerateProductJSON.
+func ParseProductImport(r io.Reader, fileName string) ([]ImportRow, error) {
+	var records [][]string
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".json":
+		return parseJSONImport(r)
+	case ".csv":
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, invalid(fmt.Sprintf("Invalid CSV file: %v", err))
+		}
+		records = rows
+	case ".xlsx":
+		f, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, invalid("Invalid XLSX file")
+		}
+		defer f.Close()
+
+		rows, err := f.GetRows(f.GetSheetName(f.GetActiveSheetIndex()))
+		if err != nil {
+			return nil, invalid("Invalid XLSX file")
+		}
+		records = rows
+	default:
+		return nil, invalid("File must be .csv, .xlsx or .json")
+	}
+
+	if len(records) < 2 {
+		return nil, invalid("File must contain a header row and at least one product")
+	}
+
+	headers := make([]string, len(records[0]))
+	for i, header := range records[0] {
+		headers[i] = strings.ToLower(strings.TrimSpace(header))
+	}
+
+	var rows []ImportRow
+	for i, record := range records[1:] {
+		fields := make(map[string]string, len(headers))
+		empty := true
+		for j, value := range record {
+			if j >= len(headers) || headers[j] == "" {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			if value != "" {
+				empty = false
+			}
+			fields[headers[j]] = value
+		}
+		if empty {
+			continue
+		}
+		// Line numbers are 1-based and count the header row
+		rows = append(rows, ImportRow{Line: i + 2, Fields: fields})
+	}
+
+	return rows, nil
+}
+
+func parseJSONImport(r io.Reader) ([]ImportRow, error) {
+	var objects []map[string]interface{}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if err := decoder.Decode(&objects); err != nil {
+		return nil, invalid(fmt.Sprintf("Invalid JSON file: %v", err))
+	}
+	if len(objects) == 0 {
+		return nil, invalid("File must contain at least one product")
+	}
+
+	rows := make([]ImportRow, 0, len(objects))
+	for i, object := range objects {
+		fields := make(map[string]string, len(object))
+		for key, value := range object {
+			if value == nil {
+				continue
+			}
+			// The attributes object of an export record maps to attr.<key>
+			if attributes, ok := value.(map[string]interface{}); ok && strings.EqualFold(key, "attributes") {
+				for name, value := range attributes {
+					if value != nil {
+						fields[attributeColumnPrefix+strings.ToLower(name)] = strings.TrimSpace(fmt.Sprint(value))
+					}
+				}
+				continue
+			}
+			fields[strings.ToLower(key)] = strings.TrimSpace(fmt.Sprint(value))
+		}
+		// For JSON the line is the 1-based position in the array
+		rows = append(rows, ImportRow{Line: i + 1, Fields: fields})
+	}
+
+	return rows, nil
+}
+
+// Import upserts products by SKU. Every row is validated first and nothing is
+// written unless all rows are valid. With dryRun the validation result is
+// returned without committing anything.
+func (s *ProductService) Import(ctx context.Context, userID uint, rows []ImportRow, dryRun bool) (*ImportResult, error) {
+	result := &ImportResult{DryRun: dryRun, Total: len(rows), Errors: []ImportRowError{}}
+
+	err := s.store.Transaction(ctx, func(tx repositories.Store) error {
+		resolver, err := newImportResolver(ctx, tx)
+		if err != nil {
+			return err
+		}
+		state := &importState{resolver: resolver}
+
+		seen := make(map[string]int)
+		for _, row := range rows {
+			sku := row.Fields["sku"]
+			if line, ok := seen[strings.ToLower(sku)]; ok && sku != "" {
+				result.Errors = append(result.Errors, ImportRowError{Line: row.Line, SKU: sku, Message: fmt.Sprintf("Duplicate SKU, already used on line %d", line)})
+				continue
+			}
+			seen[strings.ToLower(sku)] = row.Line
+
+			created, err := s.importRow(ctx, tx, state, row)
+			if err != nil {
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) {
+					return err
+				}
+				result.Errors = append(result.Errors, ImportRowError{Line: row.Line, SKU: sku, Message: validationErr.Message})
+				continue
+			}
+
+			if created {
+				result.Created++
+			} else {
+				result.Updated++
+			}
+		}
+
+		// Variants follow the new parent prices once every row is in, so a
+		// variant row of the same file still compares with its old price
+		for _, parent := range state.repriced {
+			if err := s.syncVariantPrices(ctx, tx, parent); err != nil {
+				return err
+			}
+		}
+
+		if len(result.Errors) > 0 || dryRun {
+			return errImportRejected
+		}
+
+		logActivity(ctx, tx, userID, "IMPORT", "Product", 0, fmt.Sprintf("Imported %d products (%d created, %d updated)", result.Total, result.Created, result.Updated))
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportRejected) {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// importState is shared by the rows of one import
+type importState struct {
+	resolver *importResolver
+	// repriced holds the parents whose price changed
+	repriced []*models.Product
+}
+
+// importRow validates a row, creates or updates the matching product and
+// publishes its events. It reports whether a new product was created.
+func (s *ProductService) importRow(ctx context.Context, tx repositories.Store, state *importState, row ImportRow) (bool, error) {
+	fields := row.Fields
+
+	sku := fields["sku"]
+	if sku == "" {
+		return false, invalid("SKU is required")
+	}
+
+	existing, err := tx.Products().FindBySKU(ctx, sku)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return false, err
+	}
+	if existing != nil && existing.DeletedAt.Valid {
+		if err := releaseSKU(ctx, tx, sku); err != nil {
+			return false, err
+		}
+		existing = nil
+	}
+
+	product := existing
+	if product == nil {
+		product = &models.Product{SKU: sku, MinStock: 10, PackSize: 1, BaseUnit: defaultBaseUnit}
+	}
+	// A new product starts out low, so like Create it raises no alert for
+	// its opening stock
+	wasLow := product.Stock < product.MinStock
+
+	if name := fields["name"]; name != "" {
+		product.Name = name
+	}
+	if product.Name == "" {
+		return false, invalid("Name is required")
+	}
+
+	if description, ok := fields["description"]; ok && description != "" {
+		product.Description = description
+	}
+	if imageURL, ok := fields["image_url"]; ok && imageURL != "" {
+		product.ImageURL = imageURL
+	}
+
+	priceChanged := false
+	if value := fields["price"]; value != "" {
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false, invalid(fmt.Sprintf("Invalid price %q", value))
+		}
+		// Only a changed price overrides the parent, otherwise re-importing
+		// an export would pin every variant to its current price
+		priceChanged = existing != nil && price != product.Price
+		if priceChanged && product.ParentID != nil {
+			product.PriceOverride = &price
+		}
+		product.Price = price
+	}
+	if product.Price <= 0 {
+		return false, invalid("Price must be greater than 0")
+	}
+
+	// Units come first, the quantities below are checked against them
+	if err := importUnits(ctx, tx, product, fields); err != nil {
+		return false, err
+	}
+
+	// Stock is applied through the ledger once the product is saved
+	targetStock := product.Stock
+	if err := importQuantity(fields, "stock", &targetStock); err != nil {
+		return false, err
+	}
+	if product.HasVariants && targetStock != product.Stock {
+		return false, invalid("The stock of a product with variants is kept on its variants")
+	}
+	if product.KitType == models.KitVirtual && targetStock != product.Stock {
+		return false, invalid("A virtual kit holds no stock, its availability follows its components")
+	}
+	quantities := []struct {
+		column string
+		target *float64
+	}{
+		{"min_stock", &product.MinStock},
+		{"max_stock", &product.MaxStock},
+		{"min_order_qty", &product.MinOrderQty},
+		{"pack_size", &product.PackSize},
+	}
+	for _, quantity := range quantities {
+		if err := importQuantity(fields, quantity.column, quantity.target); err != nil {
+			return false, err
+		}
+	}
+	if err := validateOrdering(product); err != nil {
+		return false, err
+	}
+	if err := checkQuantity(product, "Stock", targetStock); err != nil {
+		return false, err
+	}
+
+	supplierID, err := state.resolver.supplier(fields["supplier_id"], fields["supplier"])
+	if err != nil {
+		return false, err
+	}
+	if supplierID != 0 {
+		product.SupplierID = supplierID
+	}
+	if product.SupplierID == 0 {
+		return false, invalid("Supplier is required")
+	}
+
+	categoryID, err := state.resolver.category(fields["category_id"], fields["category"])
+	if err != nil {
+		return false, err
+	}
+	categoryChanged := false
+	if categoryID != 0 {
+		categoryChanged = product.CategoryID == nil || *product.CategoryID != categoryID
+		product.CategoryID = &categoryID
+	}
+
+	// Empty attribute cells keep their value, as the other columns do, so an
+	// export listing the attributes of several categories imports cleanly
+	changes := map[string]interface{}{}
+	for column, value := range fields {
+		if key, ok := strings.CutPrefix(column, attributeColumnPrefix); ok && value != "" {
+			changes[key] = value
+		}
+	}
+	// Required attributes are checked whenever the attributes or the
+	// category change, as in Update
+	var attributes []models.ProductAttribute
+	resolve := existing == nil || len(changes) > 0 || categoryChanged
+	if resolve {
+		var current []models.ProductAttribute
+		if existing != nil {
+			current, err = tx.Products().ListAttributes(ctx, product.ID)
+			if err != nil {
+				return false, err
+			}
+		}
+		attributes, err = resolveAttributes(ctx, tx, product, current, changes)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	note := "Stock adjusted by import"
+	if existing == nil {
+		note = "Initial stock (import)"
+		if err := tx.Products().Create(ctx, product); err != nil {
+			return false, err
+		}
+	}
+	if resolve {
+		for i := range attributes {
+			attributes[i].ProductID = product.ID
+		}
+		if err := tx.Products().ReplaceAttributes(ctx, product.ID, attributes); err != nil {
+			return false, err
+		}
+		product.Attributes = attributes
+	}
+
+	history, err := adjustStock(ctx, tx, product, targetStock, note)
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Products().Save(ctx, product); err != nil {
+		return false, conflict(err)
+	}
+	if product.HasVariants && priceChanged {
+		state.repriced = append(state.repriced, product)
+	}
+
+	// Stock or MinStock changes can cross the low stock threshold
+	if err := s.alerts.track(ctx, tx, product, wasLow); err != nil {
+		return false, err
+	}
+	if err := s.search.Index(ctx, tx, product.ID); err != nil {
+		return false, err
+	}
+	event := EventProductUpdated
+	if existing == nil {
+		event = EventProductCreated
+	}
+	if err := s.events.Publish(ctx, tx, AggregateProduct, product.ID, event, product); err != nil {
+		return false, err
+	}
+	if history != nil {
+		if err := s.events.Publish(ctx, tx, AggregateProduct, product.ID, EventStockChanged, stockChanged(product, history)); err != nil {
+			return false, err
+		}
+	}
+	return existing == nil, nil
+}
+
+// importUnits applies the base_unit and fractional columns. As in SetUnits the
+// base unit only changes at 0 stock, and the units of variants stay with their
+// parent.
+func importUnits(ctx context.Context, tx repositories.Store, product *models.Product, fields map[string]string) error {
+	baseUnit, fractional := product.BaseUnit, product.Fractional
+	if value := fields["base_unit"]; value != "" {
+		if len(value) > 20 {
+			return invalid("Base unit must be at most 20 characters")
+		}
+		baseUnit = value
+	}
+	if value := fields["fractional"]; value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return invalid(fmt.Sprintf("Invalid fractional %q, use true or false", value))
+		}
+		fractional = parsed
+	}
+	if baseUnit == product.BaseUnit && fractional == product.Fractional {
+		return nil
+	}
+
+	if product.ID != 0 {
+		if product.ParentID != nil || product.HasVariants {
+			return invalid("The units of a product with variants are changed through the units of the parent")
+		}
+		if baseUnit != product.BaseUnit {
+			if product.Stock != 0 {
+				return invalid(fmt.Sprintf("%s still holds %s %s, the base unit can only change at 0 stock", product.SKU, formatQuantity(product.Stock), product.BaseUnit))
+			}
+			units, err := tx.Products().ListUnits(ctx, product.ID)
+			if err != nil {
+				return err
+			}
+			if len(units) > 0 {
+				return invalid("The alternative units are defined in the base unit, change it through the product units")
+			}
+		}
+	}
+
+	product.BaseUnit, product.Fractional = baseUnit, fractional
+	return nil
+}
+
+// importQuantity parses the quantity in column into target. A missing column
+// leaves target untouched.
+func importQuantity(fields map[string]string, column string, target *float64) error {
+	value := fields[column]
+	if value == "" {
+		return nil
+	}
+	quantity, err := strconv.ParseFloat(value, 64)
+	if err != nil || quantity < 0 {
+		return invalid(fmt.Sprintf("Invalid %s %q", column, value))
+	}
+	*target = roundQuantity(quantity)
+	return nil
+}
+
+// importResolver maps supplier and category IDs or names to IDs. Categories
+// are also found by their path, e.g. "Electronics > Phones", which tells
+// apart subcategories sharing a name.
+type importResolver struct {
+	supplierIDs   map[uint]bool
+	supplierNames map[string]uint
+	categoryIDs   map[uint]bool
+	categoryNames map[string]uint
+	// ambiguous holds the names shared by several suppliers or categories,
+	// with a hint on how to pick one
+	ambiguous map[string]string
+}
+
+func newImportResolver(ctx context.Context, tx repositories.Store) (*importResolver, error) {
+	suppliers, err := tx.Suppliers().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := tx.Categories().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &importResolver{
+		supplierIDs:   make(map[uint]bool),
+		supplierNames: make(map[string]uint),
+		categoryIDs:   make(map[uint]bool),
+		categoryNames: make(map[string]uint),
+		ambiguous:     make(map[string]string),
+	}
+	for _, s := range suppliers {
+		r.supplierIDs[s.ID] = true
+		name := importName(s.Name)
+		if _, taken := r.supplierNames[name]; taken {
+			r.ambiguous["supplier:"+name] = "use supplier_id"
+		}
+		r.supplierNames[name] = s.ID
+	}
+
+	paths := make(map[string][]string)
+	for _, c := range categories {
+		r.categoryIDs[c.ID] = true
+		path := categoryPath(categories, c.ID)
+		name := importName(c.Name)
+		paths[name] = append(paths[name], path)
+		r.categoryNames[name] = c.ID
+		r.categoryNames[importName(path)] = c.ID
+	}
+	for name, matches := range paths {
+		if len(matches) > 1 {
+			sort.Strings(matches)
+			r.ambiguous["category:"+name] = "use its path, one of " + strings.Join(matches, ", ")
+		}
+	}
+	return r, nil
+}
+
+// importName normalizes a name or category path for lookups
+func importName(name string) string {
+	parts := strings.Split(strings.ToLower(name), ">")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return strings.Join(parts, " > ")
+}
+
+// supplier resolves a supplier by ID, falling back to its name. It returns 0
+// when neither column is set.
+func (r *importResolver) supplier(idValue, name string) (uint, error) {
+	return r.resolve("supplier", idValue, name, r.supplierIDs, r.supplierNames)
+}
+
+// category resolves a category by ID, falling back to its name or path. It
+// returns 0 when neither column is set.
+func (r *importResolver) category(idValue, name string) (uint, error) {
+	return r.resolve("category", idValue, name, r.categoryIDs, r.categoryNames)
+}
+
+func (r *importResolver) resolve(kind, idValue, name string, ids map[uint]bool, names map[string]uint) (uint, error) {
+	if idValue != "" {
+		id, err := strconv.ParseUint(idValue, 10, 32)
+		if err != nil || !ids[uint(id)] {
+			return 0, invalid(fmt.Sprintf("Unknown %s_id %q", kind, idValue))
+		}
+		return uint(id), nil
+	}
+
+	if name != "" {
+		key := importName(name)
+		if hint, ok := r.ambiguous[kind+":"+key]; ok {
+			return 0, invalid(fmt.Sprintf("Ambiguous %s %q, %s", kind, name, hint))
+		}
+		id, ok := names[key]
+		if !ok {
+			return 0, invalid(fmt.Sprintf("Unknown %s %q", kind, name))
+		}
+		return id, nil
+	}
+
+	return 0, nil
+}
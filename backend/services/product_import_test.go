@@ -0,0 +1,350 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"testing"
+
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+)
+
+// importExport exports the whole catalog as CSV and imports it again
+func importExport(t *testing.T, products *ProductService) *ImportResult {
+	t.Helper()
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	if err := products.Export(ctx, repositories.ProductFilter{}, "csv", &buf); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	rows, err := ParseProductImport(&buf, "products.csv")
+	if err != nil {
+		t.Fatalf("ParseProductImport: %v", err)
+	}
+	result, err := products.Import(ctx, 1, rows, false)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	return result
+}
+
+func TestImportRoundTripFractionalStock(t *testing.T) {
+	store := newTestStore(t)
+	products, _, _ := newTestServices(store)
+	supplier := createTestSupplier(t, store)
+	ctx := context.Background()
+
+	product, err := products.Create(ctx, 1, ProductInput{
+		SKU:         "FLOUR",
+		Name:        "Flour",
+		Price:       2,
+		Stock:       2.5,
+		MinStock:    0.5,
+		MaxStock:    20,
+		MinOrderQty: 1.5,
+		PackSize:    0.25,
+		SupplierID:  supplier.ID,
+		BaseUnit:    "kg",
+		Fractional:  true,
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	result := importExport(t, products)
+	if len(result.Errors) > 0 || result.Updated != 1 {
+		t.Fatalf("Import = %+v, want 1 update without errors", result)
+	}
+
+	saved, err := store.Products().FindByID(ctx, product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Stock != 2.5 || saved.BaseUnit != "kg" || !saved.Fractional {
+		t.Errorf("after import: stock %v %s fractional %v, want 2.5 kg fractional", saved.Stock, saved.BaseUnit, saved.Fractional)
+	}
+	if saved.MaxStock != 20 || saved.MinOrderQty != 1.5 || saved.PackSize != 0.25 {
+		t.Errorf("after import: max %v, min order %v, pack %v, want 20, 1.5, 0.25", saved.MaxStock, saved.MinOrderQty, saved.PackSize)
+	}
+}
+
+func TestImportCreatesFractionalProduct(t *testing.T) {
+	store := newTestStore(t)
+	products, _, _ := newTestServices(store)
+	supplier := createTestSupplier(t, store)
+	ctx := context.Background()
+
+	rows := []ImportRow{{Line: 2, Fields: map[string]string{
+		"sku": "RICE", "name": "Rice", "price": "3", "stock": "2.5",
+		"base_unit": "kg", "fractional": "true", "supplier_id": strconv.FormatUint(uint64(supplier.ID), 10),
+	}}}
+	result, err := products.Import(ctx, 1, rows, false)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if len(result.Errors) > 0 || result.Created != 1 {
+		t.Fatalf("Import = %+v, want 1 product created", result)
+	}
+
+	saved, err := store.Products().FindBySKU(ctx, "RICE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Stock != 2.5 || saved.BaseUnit != "kg" {
+		t.Errorf("imported stock %v %s, want 2.5 kg", saved.Stock, saved.BaseUnit)
+	}
+}
+
+func TestImportRejectsStockOnParent(t *testing.T) {
+	store := newTestStore(t)
+	products, _, _ := newTestServices(store)
+	supplier := createTestSupplier(t, store)
+	ctx := context.Background()
+
+	parent := createTestProduct(t, products, supplier.ID, "SHIRT", 0)
+	if _, err := products.GenerateVariants(ctx, 1, parent.ID, []VariantAttributeInput{{Name: "Size", Values: []string{"S", "M"}}}); err != nil {
+		t.Fatalf("GenerateVariants: %v", err)
+	}
+
+	result, err := products.Import(ctx, 1, []ImportRow{{Line: 2, Fields: map[string]string{"sku": "SHIRT", "stock": "5"}}}, false)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if len(result.Errors) != 1 {
+		t.Fatalf("Import of stock onto a parent: errors %+v, want 1", result.Errors)
+	}
+
+	if result := importExport(t, products); len(result.Errors) > 0 {
+		t.Errorf("Import of an unchanged export: errors %+v", result.Errors)
+	}
+}
+
+func TestImportVariantPrices(t *testing.T) {
+	store := newTestStore(t)
+	products, _, _ := newTestServices(store)
+	supplier := createTestSupplier(t, store)
+	ctx := context.Background()
+
+	parent := createTestProduct(t, products, supplier.ID, "SHIRT", 0)
+	if _, err := products.GenerateVariants(ctx, 1, parent.ID, []VariantAttributeInput{{Name: "Size", Values: []string{"S", "M"}}}); err != nil {
+		t.Fatalf("GenerateVariants: %v", err)
+	}
+	variants, err := store.Products().ListVariants(ctx, parent.ID)
+	if err != nil || len(variants) != 2 {
+		t.Fatalf("ListVariants: %d variants, %v", len(variants), err)
+	}
+	edited, inherited := variants[0], variants[1]
+
+	// The variant rows come first and carry their exported prices, except
+	// the edited one
+	rows := []ImportRow{
+		{Line: 2, Fields: map[string]string{"sku": edited.SKU, "price": "15"}},
+		{Line: 3, Fields: map[string]string{"sku": inherited.SKU, "price": "10"}},
+		{Line: 4, Fields: map[string]string{"sku": "SHIRT", "price": "12"}},
+	}
+	result, err := products.Import(ctx, 1, rows, false)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("Import errors: %+v", result.Errors)
+	}
+
+	saved, err := store.Products().FindByID(ctx, edited.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Price != 15 || saved.PriceOverride == nil || *saved.PriceOverride != 15 {
+		t.Errorf("edited variant: price %v override %v, want 15 overridden", saved.Price, saved.PriceOverride)
+	}
+	saved, err = store.Products().FindByID(ctx, inherited.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Price != 12 || saved.PriceOverride != nil {
+		t.Errorf("inherited variant: price %v override %v, want the parent price 12", saved.Price, saved.PriceOverride)
+	}
+}
+
+func TestImportRejectsStockOnVirtualKit(t *testing.T) {
+	store := newTestStore(t)
+	products, _, _ := newTestServices(store)
+	supplier := createTestSupplier(t, store)
+	ctx := context.Background()
+
+	component := createTestProduct(t, products, supplier.ID, "BATTERY", 10)
+	kit := createTestProduct(t, products, supplier.ID, "BUNDLE", 0)
+	_, err := products.SetKit(ctx, 1, kit.ID, 0, KitInput{
+		Type:       models.KitVirtual,
+		Components: []KitComponentInput{{ProductID: component.ID, Quantity: 2}},
+	})
+	if err != nil {
+		t.Fatalf("SetKit: %v", err)
+	}
+
+	result, err := products.Import(ctx, 1, []ImportRow{{Line: 2, Fields: map[string]string{"sku": "BUNDLE", "stock": "3"}}}, false)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if len(result.Errors) != 1 {
+		t.Fatalf("Import of stock onto a virtual kit: errors %+v, want 1", result.Errors)
+	}
+
+	if result := importExport(t, products); len(result.Errors) > 0 {
+		t.Errorf("Import of an unchanged export: errors %+v", result.Errors)
+	}
+}
+
+func TestImportStockAlertsAndEvents(t *testing.T) {
+	store := newTestStore(t)
+	products, _, _ := newTestServices(store)
+	supplier := createTestSupplier(t, store)
+	ctx := context.Background()
+	product := createTestProduct(t, products, supplier.ID, "SKU-1", 20)
+
+	events := &recordedEvents{}
+	alerts := NewStockAlertService(store, map[string]AlertNotifier{}, StockAlertOptions{})
+	products = NewProductService(store, alerts, events, nopSearch{})
+
+	result, err := products.Import(ctx, 1, []ImportRow{{Line: 2, Fields: map[string]string{"sku": "SKU-1", "stock": "3"}}}, false)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("Import errors: %+v", result.Errors)
+	}
+
+	want := []string{EventProductUpdated, EventStockChanged}
+	if len(events.types) != len(want) || events.types[0] != want[0] || events.types[1] != want[1] {
+		t.Errorf("published %v, want %v", events.types, want)
+	}
+	if _, err := store.StockAlerts().FindActive(ctx, product.ID); err != nil {
+		t.Errorf("no active alert after importing stock below the minimum: %v", err)
+	}
+}
+
+func TestImportAttributes(t *testing.T) {
+	store := newTestStore(t)
+	products, _, _ := newTestServices(store)
+	categories := NewCategoryService(store, nopEvents{}, nopSearch{})
+	supplier := createTestSupplier(t, store)
+	ctx := context.Background()
+
+	category := models.Category{Name: "Batteries"}
+	if err := store.Categories().Create(ctx, &category); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := categories.CreateAttribute(ctx, category.ID, AttributeDefinitionInput{Key: "voltage", Type: models.AttributeNumber, Required: true}); err != nil {
+		t.Fatalf("CreateAttribute: %v", err)
+	}
+	product, err := products.Create(ctx, 1, ProductInput{
+		SKU: "AA", Name: "AA cell", Price: 1, SupplierID: supplier.ID, CategoryID: &category.ID,
+		Attributes: map[string]interface{}{"voltage": 1.5},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	createTestProduct(t, products, supplier.ID, "PLAIN", 0)
+
+	for _, format := range []string{"csv", "json", "xlsx"} {
+		var buf bytes.Buffer
+		if err := products.Export(ctx, repositories.ProductFilter{}, format, &buf); err != nil {
+			t.Fatalf("Export %s: %v", format, err)
+		}
+		rows, err := ParseProductImport(&buf, "products."+format)
+		if err != nil {
+			t.Fatalf("ParseProductImport %s: %v", format, err)
+		}
+		if got := rows[0].Fields["attr.voltage"]; got != "1.5" {
+			t.Errorf("%s export: attr.voltage = %q, want 1.5", format, got)
+		}
+		result, err := products.Import(ctx, 1, rows, false)
+		if err != nil {
+			t.Fatalf("Import %s: %v", format, err)
+		}
+		if len(result.Errors) > 0 {
+			t.Errorf("Import of an unchanged %s export: errors %+v", format, result.Errors)
+		}
+	}
+
+	rows := []ImportRow{{Line: 2, Fields: map[string]string{"sku": "AA", "attr.voltage": "3"}}}
+	if result, err := products.Import(ctx, 1, rows, false); err != nil || len(result.Errors) > 0 {
+		t.Fatalf("Import of a new value: %+v, %v", result, err)
+	}
+	attributes, err := store.Products().ListAttributes(ctx, product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attributes) != 1 || attributes[0].Value != "3" {
+		t.Errorf("attributes after import: %+v, want voltage 3", attributes)
+	}
+
+	rows = []ImportRow{
+		{Line: 2, Fields: map[string]string{"sku": "PLAIN", "category": "Batteries"}},
+		{Line: 3, Fields: map[string]string{"sku": "PLAIN", "attr.voltage": "abc"}},
+	}
+	result, err := products.Import(ctx, 1, rows[:1], false)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if len(result.Errors) != 1 {
+		t.Errorf("moving into a category without its required attribute: errors %+v, want 1", result.Errors)
+	}
+	rows[1].Fields["category"] = "Batteries"
+	result, err = products.Import(ctx, 1, rows[1:], false)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if len(result.Errors) != 1 {
+		t.Errorf("invalid attribute value: errors %+v, want 1", result.Errors)
+	}
+}
+
+func TestImportCategoryPaths(t *testing.T) {
+	store := newTestStore(t)
+	products, _, _ := newTestServices(store)
+	categories := NewCategoryService(store, nopEvents{}, nopSearch{})
+	supplier := createTestSupplier(t, store)
+	ctx := context.Background()
+
+	// Names are unique among siblings only, so a bare Cables is ambiguous
+	var leaves []*models.Category
+	for _, name := range []string{"Power", "Data"} {
+		parent, err := categories.Create(ctx, CategoryInput{Name: name})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		leaf, err := categories.Create(ctx, CategoryInput{Name: "Cables", ParentID: &parent.ID})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		leaves = append(leaves, leaf)
+	}
+	data := leaves[1]
+	createTestProduct(t, products, supplier.ID, "USB", 0)
+
+	result, err := products.Import(ctx, 1, []ImportRow{{Line: 2, Fields: map[string]string{"sku": "USB", "category": "Cables"}}}, false)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if len(result.Errors) != 1 {
+		t.Fatalf("Import with an ambiguous category: errors %+v, want 1", result.Errors)
+	}
+
+	result, err = products.Import(ctx, 1, []ImportRow{{Line: 2, Fields: map[string]string{"sku": "USB", "category": "data>CABLES"}}}, false)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("Import with a category path: errors %+v", result.Errors)
+	}
+	saved, err := store.Products().FindBySKU(ctx, "USB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.CategoryID == nil || *saved.CategoryID != data.ID {
+		t.Errorf("category = %v, want %d", saved.CategoryID, data.ID)
+	}
+}
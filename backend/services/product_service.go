@@ -0,0 +1,534 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"io"
+	"strings"
+	"time"
+)
+
+// ProductInput holds the fields accepted when creating a product
+type ProductInput struct {
+	SKU         string
+	Name        string
+	Description string
+	Price       float64
+	Stock       float64
+	MinStock    float64
+	MaxStock    float64
+	MinOrderQty float64
+	PackSize    float64
+	SupplierID  uint
+	CategoryID  *uint
+	ImageURL    string
+	BaseUnit    string // pcs when empty
+	Fractional  bool
+	// Attributes holds the custom attribute values by key, as JSON values
+	// or text
+	Attributes map[string]interface{}
+}
+
+// ProductUpdate holds the fields to change on a product. Nil fields are left untouched.
+type ProductUpdate struct {
+	SKU         *string
+	Name        *string
+	Description *string
+	Price       *float64
+	Stock       *float64
+	MinStock    *float64
+	MaxStock    *float64
+	MinOrderQty *float64
+	PackSize    *float64
+	SupplierID  *uint
+	CategoryID  *uint
+	ImageURL    *string
+	// Attributes changes custom attribute values by key, nil removes one.
+	// Keys not given keep their value.
+	Attributes map[string]interface{}
+	// InheritPrice drops the price override of a variant so it follows the
+	// parent price again
+	InheritPrice bool
+}
+
+type ProductService struct {
+	store  repositories.Store
+	alerts *StockAlertService
+	events EventPublisher
+	search SearchIndex
+}
+
+func NewProductService(store repositories.Store, alerts *StockAlertService, events EventPublisher, search SearchIndex) *ProductService {
+	return &ProductService{store: store, alerts: alerts, events: events, search: search}
+}
+
+// List returns a page of the products matching filter. A search lists the
+// best matches by relevance.
+func (s *ProductService) List(ctx context.Context, filter repositories.ProductFilter) ([]models.Product, int64, error) {
+	filter, err := s.matchSearch(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.store.Products().List(ctx, filter)
+}
+
+// matchSearch looks the search of filter up in the search index
+func (s *ProductService) matchSearch(ctx context.Context, filter repositories.ProductFilter) (repositories.ProductFilter, error) {
+	filter.Search = strings.TrimSpace(filter.Search)
+	if filter.Search == "" {
+		return filter, nil
+	}
+	matches, err := s.search.Search(ctx, filter.Search, maxSearchHits)
+	if err != nil {
+		return filter, err
+	}
+	filter.Matches = matches
+	return filter, nil
+}
+
+// ReindexSearch rebuilds the search index of every product
+func (s *ProductService) ReindexSearch(ctx context.Context) (int, error) {
+	return s.search.Rebuild(ctx)
+}
+
+// exportBatchSize is the number of products read per query while exporting
+const exportBatchSize = 500
+
+// Export streams the products matching filter to writer in the given format,
+// reading them from the database in batches
+func (s *ProductService) Export(ctx context.Context, filter repositories.ProductFilter, format string, writer io.Writer) error {
+	return s.ExportWithProgress(ctx, filter, format, writer, nil)
+}
+
+// ExportWithProgress works like Export and calls progress after every batch
+// with the number of products written so far
+func (s *ProductService) ExportWithProgress(ctx context.Context, filter repositories.ProductFilter, format string, writer io.Writer, progress func(written int)) error {
+	attributeKeys, err := s.exportAttributeKeys(ctx, filter)
+	if err != nil {
+		return err
+	}
+	exporter, err := NewProductExporter(format, writer, attributeKeys)
+	if err != nil {
+		return err
+	}
+	filter, err = s.matchSearch(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	written := 0
+	err = s.store.Products().EachBatch(ctx, filter, exportBatchSize, func(products []models.Product) error {
+		if err := exporter.WriteBatch(products); err != nil {
+			return err
+		}
+		written += len(products)
+		if progress != nil {
+			progress(written)
+		}
+		return nil
+	})
+	if err != nil {
+		exporter.Close()
+		return err
+	}
+
+	return exporter.Close()
+}
+
+// exportAttributeKeys returns the keys of the attributes the exported
+// products can have: those along the filtered category path, or all of them
+func (s *ProductService) exportAttributeKeys(ctx context.Context, filter repositories.ProductFilter) ([]string, error) {
+	var definitions []models.AttributeDefinition
+	if filter.CategoryID != 0 {
+		above, err := s.store.Categories().ListAttributes(ctx, filter.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		below, err := s.store.Categories().ListSubtreeAttributes(ctx, filter.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(above, below...)
+	} else {
+		var err error
+		definitions, err = s.store.Categories().ListAllAttributes(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Sibling categories may define the same key
+	var keys []string
+	seen := make(map[string]bool, len(definitions))
+	for _, definition := range definitions {
+		if !seen[definition.Key] {
+			seen[definition.Key] = true
+			keys = append(keys, definition.Key)
+		}
+	}
+	return keys, nil
+}
+
+func (s *ProductService) Count(ctx context.Context, filter repositories.ProductFilter) (int64, error) {
+	filter, err := s.matchSearch(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return s.store.Products().Count(ctx, filter)
+}
+
+func (s *ProductService) Find(ctx context.Context, id uint) (*models.Product, error) {
+	product, err := s.store.Products().FindByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrProductNotFound)
+	}
+	return product, nil
+}
+
+// Get returns a product with its stock history
+func (s *ProductService) Get(ctx context.Context, id uint) (*models.Product, error) {
+	product, err := s.store.Products().FindWithHistory(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrProductNotFound)
+	}
+	return product, nil
+}
+
+func (s *ProductService) ListLowStock(ctx context.Context) ([]models.Product, error) {
+	return s.store.Products().ListLowStock(ctx)
+}
+
+func (s *ProductService) Create(ctx context.Context, userID uint, input ProductInput) (*models.Product, error) {
+	if input.SKU == "" || input.Name == "" || input.Price <= 0 || input.SupplierID == 0 {
+		return nil, invalid("SKU, Name, Price, and Supplier are required")
+	}
+	if input.Stock < 0 {
+		return nil, invalid("Stock cannot be negative")
+	}
+	if input.PackSize == 0 {
+		input.PackSize = 1
+	}
+	if input.BaseUnit == "" {
+		input.BaseUnit = defaultBaseUnit
+	}
+	if len(input.BaseUnit) > 20 {
+		return nil, invalid("Base unit must be at most 20 characters")
+	}
+
+	product := models.Product{
+		SKU:         input.SKU,
+		Name:        input.Name,
+		Description: input.Description,
+		Price:       input.Price,
+		MinStock:    roundQuantity(input.MinStock),
+		MaxStock:    roundQuantity(input.MaxStock),
+		MinOrderQty: roundQuantity(input.MinOrderQty),
+		PackSize:    roundQuantity(input.PackSize),
+		SupplierID:  input.SupplierID,
+		CategoryID:  input.CategoryID,
+		ImageURL:    input.ImageURL,
+		BaseUnit:    input.BaseUnit,
+		Fractional:  input.Fractional,
+	}
+	if err := validateOrdering(&product); err != nil {
+		return nil, err
+	}
+	stock := roundQuantity(input.Stock)
+	if err := checkQuantity(&product, "Stock", stock); err != nil {
+		return nil, err
+	}
+
+	err := s.store.Transaction(ctx, func(tx repositories.Store) error {
+		if err := releaseSKU(ctx, tx, input.SKU); err != nil {
+			return err
+		}
+
+		if product.CategoryID != nil {
+			if _, err := tx.Categories().FindByID(ctx, *product.CategoryID); err != nil {
+				return notFound(err, ErrCategoryNotFound)
+			}
+		}
+		if err := tx.Products().Create(ctx, &product); err != nil {
+			return err
+		}
+		attributes, err := resolveAttributes(ctx, tx, &product, nil, input.Attributes)
+		if err != nil {
+			return err
+		}
+		if err := tx.Products().ReplaceAttributes(ctx, product.ID, attributes); err != nil {
+			return err
+		}
+		product.Attributes = attributes
+
+		// Record the opening stock so the ledger accounts for every unit
+		if _, err := adjustStock(ctx, tx, &product, stock, "Initial stock"); err != nil {
+			return err
+		}
+		if err := tx.Products().Save(ctx, &product); err != nil {
+			return err
+		}
+
+		if err := s.search.Index(ctx, tx, product.ID); err != nil {
+			return err
+		}
+		if err := s.events.Publish(ctx, tx, AggregateProduct, product.ID, EventProductCreated, product); err != nil {
+			return err
+		}
+
+		logActivity(ctx, tx, userID, "CREATE", "Product", product.ID, fmt.Sprintf("Created product: %s (%s)", product.Name, product.SKU))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// Update applies input to a product. version is the version the caller last
+// saw; the update fails with ErrVersionMismatch when the product changed since,
+// 0 skips the check.
+func (s *ProductService) Update(ctx context.Context, userID uint, id uint, version uint, input ProductUpdate) (*models.Product, error) {
+	var product *models.Product
+
+	err := s.store.Transaction(ctx, func(tx repositories.Store) error {
+		var err error
+		product, err = tx.Products().FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return notFound(err, ErrProductNotFound)
+		}
+		if err := checkVersion(product.Version, version); err != nil {
+			return err
+		}
+		wasLow := product.Stock < product.MinStock
+
+		if input.SKU != nil && *input.SKU != "" && *input.SKU != product.SKU {
+			if err := releaseSKU(ctx, tx, *input.SKU); err != nil {
+				return err
+			}
+			product.SKU = *input.SKU
+		}
+
+		if input.Name != nil && *input.Name != "" {
+			product.Name = *input.Name
+		}
+		if input.Description != nil && *input.Description != "" {
+			product.Description = *input.Description
+		}
+		priceChanged := false
+		if input.Price != nil {
+			priceChanged = *input.Price != product.Price
+			product.Price = *input.Price
+			if product.ParentID != nil {
+				product.PriceOverride = input.Price
+			}
+		} else if input.InheritPrice && product.ParentID != nil {
+			parent, err := tx.Products().FindByID(ctx, *product.ParentID)
+			if err != nil {
+				return notFound(err, ErrProductNotFound)
+			}
+			product.Price = parent.Price
+			product.PriceOverride = nil
+		}
+		if input.Stock != nil && product.HasVariants && *input.Stock != product.Stock {
+			return invalid("The stock of a product with variants is kept on its variants")
+		}
+		if input.Stock != nil && product.KitType == models.KitVirtual && *input.Stock != product.Stock {
+			return invalid("A virtual kit holds no stock, its availability follows its components")
+		}
+		if input.Stock != nil {
+			stock := roundQuantity(*input.Stock)
+			if stock < 0 {
+				return invalid("Stock cannot be negative")
+			}
+			if err := checkQuantity(product, "Stock", stock); err != nil {
+				return err
+			}
+			// Route manual stock edits through the ledger
+			history, err := adjustStock(ctx, tx, product, stock, "Manual adjustment via product update")
+			if err != nil {
+				return err
+			}
+			if history != nil {
+				if err := s.events.Publish(ctx, tx, AggregateProduct, product.ID, EventStockChanged, stockChanged(product, history)); err != nil {
+					return err
+				}
+			}
+		}
+		if input.MinStock != nil {
+			product.MinStock = roundQuantity(*input.MinStock)
+		}
+		if input.MaxStock != nil {
+			product.MaxStock = roundQuantity(*input.MaxStock)
+		}
+		if input.MinOrderQty != nil {
+			product.MinOrderQty = roundQuantity(*input.MinOrderQty)
+		}
+		if input.PackSize != nil {
+			product.PackSize = roundQuantity(*input.PackSize)
+		}
+		if err := validateOrdering(product); err != nil {
+			return err
+		}
+		if input.SupplierID != nil {
+			if _, err := tx.Suppliers().FindByID(ctx, *input.SupplierID); err != nil {
+				return notFound(err, ErrSupplierNotFound)
+			}
+			product.SupplierID = *input.SupplierID
+		}
+		categoryChanged := false
+		if input.CategoryID != nil {
+			if _, err := tx.Categories().FindByID(ctx, *input.CategoryID); err != nil {
+				return notFound(err, ErrCategoryNotFound)
+			}
+			categoryChanged = product.CategoryID == nil || *product.CategoryID != *input.CategoryID
+			product.CategoryID = input.CategoryID
+		}
+		// Required attributes are checked whenever the attributes or the
+		// category change
+		if input.Attributes != nil || categoryChanged {
+			current, err := tx.Products().ListAttributes(ctx, product.ID)
+			if err != nil {
+				return err
+			}
+			attributes, err := resolveAttributes(ctx, tx, product, current, input.Attributes)
+			if err != nil {
+				return err
+			}
+			if err := tx.Products().ReplaceAttributes(ctx, product.ID, attributes); err != nil {
+				return err
+			}
+			product.Attributes = attributes
+		}
+		if input.ImageURL != nil {
+			product.ImageURL = *input.ImageURL
+		}
+
+		if err := tx.Products().Save(ctx, product); err != nil {
+			return conflict(err)
+		}
+		if product.HasVariants && priceChanged {
+			if err := s.syncVariantPrices(ctx, tx, product); err != nil {
+				return err
+			}
+		}
+
+		// Stock or MinStock edits can cross the low stock threshold too
+		if err := s.alerts.track(ctx, tx, product, wasLow); err != nil {
+			return err
+		}
+
+		if err := s.search.Index(ctx, tx, product.ID); err != nil {
+			return err
+		}
+		if err := s.events.Publish(ctx, tx, AggregateProduct, product.ID, EventProductUpdated, product); err != nil {
+			return err
+		}
+
+		logActivity(ctx, tx, userID, "UPDATE", "Product", product.ID, "Updated product: "+product.Name+" ("+product.SKU+")")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Reload relations for the response
+	return s.store.Products().FindByID(ctx, product.ID)
+}
+
+// Delete soft-deletes a product and renames its SKU so the code can be reused.
+// version works as in Update.
+func (s *ProductService) Delete(ctx context.Context, userID uint, id uint, version uint) (*models.Product, error) {
+	var product *models.Product
+
+	err := s.store.Transaction(ctx, func(tx repositories.Store) error {
+		var err error
+		product, err = tx.Products().FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return notFound(err, ErrProductNotFound)
+		}
+		if err := checkVersion(product.Version, version); err != nil {
+			return err
+		}
+		if product.HasVariants {
+			variants, err := tx.Products().ListVariants(ctx, product.ID)
+			if err != nil {
+				return err
+			}
+			if len(variants) > 0 {
+				return ErrProductHasVariants
+			}
+		}
+		kits, err := tx.Products().CountKitsUsing(ctx, product.ID)
+		if err != nil {
+			return err
+		}
+		if kits > 0 {
+			return ErrProductInKit
+		}
+
+		originalSKU := product.SKU
+		product.SKU = deletedSKU(product.SKU)
+		if err := tx.Products().Save(ctx, product); err != nil {
+			return conflict(err)
+		}
+
+		if err := tx.Products().Delete(ctx, product); err != nil {
+			return conflict(err)
+		}
+
+		if err := s.search.Remove(ctx, tx, product.ID); err != nil {
+			return err
+		}
+		if err := s.events.Publish(ctx, tx, AggregateProduct, product.ID, EventProductDeleted, DeletedEntity{ID: product.ID, SKU: originalSKU, Name: product.Name}); err != nil {
+			return err
+		}
+
+		logActivity(ctx, tx, userID, "DELETE", "Product", product.ID, "Deleted product: "+product.Name+" ("+originalSKU+")")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// validateOrdering checks the replenishment settings of a product
+func validateOrdering(product *models.Product) error {
+	if product.MinStock < 0 || product.MaxStock < 0 || product.MinOrderQty < 0 {
+		return invalid("Min stock, max stock and min order quantity cannot be negative")
+	}
+	if product.MaxStock != 0 && product.MaxStock < product.MinStock {
+		return invalid("Max stock cannot be below min stock")
+	}
+	if product.PackSize <= 0 {
+		return invalid("Pack size must be greater than 0")
+	}
+	return checkQuantities(product)
+}
+
+// releaseSKU makes sku available for a new product. A live product holding the
+// SKU is a collision, while a soft-deleted one is renamed out of the way.
+func releaseSKU(ctx context.Context, tx repositories.Store, sku string) error {
+	existing, err := tx.Products().FindBySKU(ctx, sku)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !existing.DeletedAt.Valid {
+		return ErrSKUExists
+	}
+
+	return tx.Products().RenameSKU(ctx, existing.ID, deletedSKU(existing.SKU))
+}
+
+func deletedSKU(sku string) string {
+	return fmt.Sprintf("%s_DELETED_%d", sku, time.Now().Unix())
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+)
+
+func TestProductCreateSKUExists(t *testing.T) {
+	store := newTestStore(t)
+	products, _, _ := newTestServices(store)
+	supplier := createTestSupplier(t, store)
+	createTestProduct(t, products, supplier.ID, "SKU-1", 0)
+
+	_, err := products.Create(context.Background(), 1, ProductInput{SKU: "SKU-1", Name: "Copy", Price: 10, SupplierID: supplier.ID})
+	if !errors.Is(err, ErrSKUExists) {
+		t.Fatalf("Create with a taken SKU: got %v, want ErrSKUExists", err)
+	}
+}
+
+func TestProductCreateReusesDeletedSKU(t *testing.T) {
+	store := newTestStore(t)
+	products, _, _ := newTestServices(store)
+	supplier := createTestSupplier(t, store)
+	deleted := createTestProduct(t, products, supplier.ID, "SKU-1", 0)
+	if _, err := products.Delete(context.Background(), 1, deleted.ID, 0); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	created := createTestProduct(t, products, supplier.ID, "SKU-1", 0)
+	if created.ID == deleted.ID {
+		t.Fatal("Create restored the deleted product")
+	}
+
+	renamed, err := store.Products().FindBySKU(context.Background(), "SKU-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.ID != created.ID {
+		t.Errorf("SKU-1 belongs to product %d, want %d", renamed.ID, created.ID)
+	}
+}
+
+func TestReleaseSKU(t *testing.T) {
+	db := newTestDB(t)
+	store := repositories.NewStore(db)
+	products, _, _ := newTestServices(store)
+	supplier := createTestSupplier(t, store)
+	ctx := context.Background()
+
+	live := createTestProduct(t, products, supplier.ID, "LIVE", 0)
+	if err := releaseSKU(ctx, store, live.SKU); !errors.Is(err, ErrSKUExists) {
+		t.Errorf("releaseSKU of a live product: got %v, want ErrSKUExists", err)
+	}
+
+	deleted := createTestProduct(t, products, supplier.ID, "GONE", 0)
+	if _, err := products.Delete(ctx, 1, deleted.ID, 0); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := releaseSKU(ctx, store, "GONE"); err != nil {
+		t.Fatalf("releaseSKU of a deleted product: %v", err)
+	}
+
+	var product models.Product
+	if err := db.Unscoped().First(&product, deleted.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(product.SKU, "GONE_DELETED_") {
+		t.Errorf("deleted product SKU = %q, want GONE_DELETED_*", product.SKU)
+	}
+
+	if err := releaseSKU(ctx, store, "UNUSED"); err != nil {
+		t.Errorf("releaseSKU of an unused SKU: %v", err)
+	}
+}
@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxVariantAttributes bounds the attributes of one parent
+	maxVariantAttributes = 5
+	// maxVariants bounds the variants of one parent
+	maxVariants = 500
+)
+
+// VariantAttributeInput defines one attribute of a parent and its values,
+// e.g. size with S, M and L
+type VariantAttributeInput struct {
+	Name   string
+	Values []string
+}
+
+// VariantGeneration is the result of GenerateVariants
+type VariantGeneration struct {
+	Parent  *models.Product  `json:"parent"`
+	Created []models.Product `json:"created"`
+}
+
+// GetVariants returns a parent product with its attributes and variants
+func (s *ProductService) GetVariants(ctx context.Context, id uint) (*models.Product, error) {
+	product, err := s.store.Products().FindWithVariants(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrProductNotFound)
+	}
+	return product, nil
+}
+
+// GenerateVariants turns a product into a parent with the given attributes
+// and creates a variant for every combination of values that does not exist
+// yet. On a product that already has variants the attributes must be the same
+// and values can only be added. Variants start without stock and copy the
+// price, supplier, category, image and ordering settings of the parent; the
+// parent keeps them as the defaults for variants added later.
+func (s *ProductService) GenerateVariants(ctx context.Context, userID uint, parentID uint, attributes []VariantAttributeInput) (*VariantGeneration, error) {
+	attributes, err := normalizeVariantAttributes(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VariantGeneration{Created: []models.Product{}}
+	err = s.store.Transaction(ctx, func(tx repositories.Store) error {
+		if _, err := tx.Products().FindByIDForUpdate(ctx, parentID); err != nil {
+			return notFound(err, ErrProductNotFound)
+		}
+		parent, err := tx.Products().FindWithVariants(ctx, parentID)
+		if err != nil {
+			return notFound(err, ErrProductNotFound)
+		}
+		if parent.ParentID != nil {
+			return invalid("A variant cannot have variants of its own")
+		}
+		if parent.KitType != "" {
+			return invalid("A kit cannot have variants, define a kit per product instead")
+		}
+		if !parent.HasVariants && parent.Stock != 0 {
+			return invalid(fmt.Sprintf("%s still holds %s %s, stock is kept on the variants so bring it to 0 first", parent.Name, formatQuantity(parent.Stock), parent.BaseUnit))
+		}
+
+		attributes, err := mergeVariantAttributes(parent.VariantAttributes, attributes)
+		if err != nil {
+			return err
+		}
+
+		combinations := variantCombinations(attributes)
+		if len(combinations) > maxVariants {
+			return invalid(fmt.Sprintf("%d combinations exceed the limit of %d variants per product", len(combinations), maxVariants))
+		}
+
+		existing := make(map[string]bool, len(parent.Variants))
+		for _, variant := range parent.Variants {
+			existing[variantKey(attributes, variant.VariantOptions)] = true
+		}
+
+		for _, values := range combinations {
+			options := make([]models.VariantOption, len(values))
+			for i, value := range values {
+				options[i] = models.VariantOption{Name: attributes[i].Name, Value: value}
+			}
+			if existing[variantKey(attributes, options)] {
+				continue
+			}
+
+			variant, err := s.createVariant(ctx, tx, parent, values, options)
+			if err != nil {
+				return err
+			}
+			result.Created = append(result.Created, *variant)
+		}
+
+		stored := make([]models.VariantAttribute, len(attributes))
+		for i, attribute := range attributes {
+			stored[i] = models.VariantAttribute{
+				ProductID: parent.ID,
+				Name:      attribute.Name,
+				Values:    strings.Join(attribute.Values, ","),
+				Position:  i,
+			}
+		}
+		if err := tx.Products().ReplaceVariantAttributes(ctx, parent.ID, stored); err != nil {
+			return err
+		}
+
+		wasLow := parent.Stock < parent.MinStock
+		parent.HasVariants = true
+		if err := tx.Products().Save(ctx, parent); err != nil {
+			return conflict(err)
+		}
+		// A parent is no longer tracked itself
+		if err := s.alerts.track(ctx, tx, parent, wasLow); err != nil {
+			return err
+		}
+		if err := s.events.Publish(ctx, tx, AggregateProduct, parent.ID, EventProductUpdated, parent); err != nil {
+			return err
+		}
+
+		logActivity(ctx, tx, userID, "CREATE", "Product", parent.ID, fmt.Sprintf("Generated %d variants of %s (%s)", len(result.Created), parent.Name, parent.SKU))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Parent, err = s.store.Products().FindWithVariants(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *ProductService) createVariant(ctx context.Context, tx repositories.Store, parent *models.Product, values []string, options []models.VariantOption) (*models.Product, error) {
+	sku := variantSKU(parent.SKU, values)
+	if len(sku) > 50 {
+		return nil, invalid(fmt.Sprintf("Variant SKU %s is longer than 50 characters, use shorter values", sku))
+	}
+	if err := releaseSKU(ctx, tx, sku); err != nil {
+		return nil, err
+	}
+
+	variant := models.Product{
+		SKU:            sku,
+		Name:           fmt.Sprintf("%s (%s)", parent.Name, strings.Join(values, " / ")),
+		Description:    parent.Description,
+		Price:          parent.Price,
+		MinStock:       parent.MinStock,
+		MaxStock:       parent.MaxStock,
+		MinOrderQty:    parent.MinOrderQty,
+		PackSize:       parent.PackSize,
+		BaseUnit:       parent.BaseUnit,
+		Fractional:     parent.Fractional,
+		PurchaseUnit:   parent.PurchaseUnit,
+		SalesUnit:      parent.SalesUnit,
+		ImageURL:       parent.ImageURL,
+		SupplierID:     parent.SupplierID,
+		CategoryID:     parent.CategoryID,
+		ParentID:       &parent.ID,
+		VariantOptions: options,
+	}
+	if err := tx.Products().Create(ctx, &variant); err != nil {
+		return nil, err
+	}
+	if err := copyAttributes(ctx, tx, parent, &variant); err != nil {
+		return nil, err
+	}
+	if err := copyUnits(ctx, tx, parent, &variant); err != nil {
+		return nil, err
+	}
+
+	if err := s.search.Index(ctx, tx, variant.ID); err != nil {
+		return nil, err
+	}
+	if err := s.events.Publish(ctx, tx, AggregateProduct, variant.ID, EventProductCreated, variant); err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// syncVariantPrices applies a new parent price to the variants without a
+// price override
+func (s *ProductService) syncVariantPrices(ctx context.Context, tx repositories.Store, parent *models.Product) error {
+	variants, err := tx.Products().ListVariants(ctx, parent.ID)
+	if err != nil {
+		return err
+	}
+
+	for i := range variants {
+		variant := &variants[i]
+		if variant.PriceOverride != nil || variant.Price == parent.Price {
+			continue
+		}
+		variant.Price = parent.Price
+		if err := tx.Products().Save(ctx, variant); err != nil {
+			return conflict(err)
+		}
+		if err := s.events.Publish(ctx, tx, AggregateProduct, variant.ID, EventProductUpdated, variant); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeVariantAttributes trims names and values and rejects empty or
+// duplicate ones
+func normalizeVariantAttributes(attributes []VariantAttributeInput) ([]VariantAttributeInput, error) {
+	if len(attributes) == 0 || len(attributes) > maxVariantAttributes {
+		return nil, invalid(fmt.Sprintf("Between 1 and %d attributes are required", maxVariantAttributes))
+	}
+
+	names := map[string]bool{}
+	normalized := make([]VariantAttributeInput, len(attributes))
+	for i, attribute := range attributes {
+		name := strings.TrimSpace(attribute.Name)
+		if name == "" || len(name) > 50 {
+			return nil, invalid("Attribute names are required and at most 50 characters")
+		}
+		if names[strings.ToLower(name)] {
+			return nil, invalid(fmt.Sprintf("Attribute %s is listed twice", name))
+		}
+		names[strings.ToLower(name)] = true
+
+		values := []string{}
+		seen := map[string]bool{}
+		for _, value := range attribute.Values {
+			value = strings.TrimSpace(value)
+			if value == "" || seen[strings.ToLower(value)] {
+				continue
+			}
+			if len(value) > 100 || strings.Contains(value, ",") {
+				return nil, invalid(fmt.Sprintf("Value %q of %s must be at most 100 characters without commas", value, name))
+			}
+			seen[strings.ToLower(value)] = true
+			values = append(values, value)
+		}
+		if len(values) == 0 {
+			return nil, invalid(fmt.Sprintf("Attribute %s needs at least one value", name))
+		}
+
+		normalized[i] = VariantAttributeInput{Name: name, Values: values}
+	}
+	return normalized, nil
+}
+
+// mergeVariantAttributes adds the new values to the stored attributes of a
+// parent. The attribute names must stay the same, since every existing
+// variant has exactly one value per attribute.
+func mergeVariantAttributes(stored []models.VariantAttribute, attributes []VariantAttributeInput) ([]VariantAttributeInput, error) {
+	if len(stored) == 0 {
+		return attributes, nil
+	}
+
+	current := make([]string, len(stored))
+	for i, attribute := range stored {
+		current[i] = attribute.Name
+	}
+	if len(stored) != len(attributes) {
+		return nil, invalid(fmt.Sprintf("The variants of this product are defined by %s, attributes cannot be added or removed", strings.Join(current, ", ")))
+	}
+
+	merged := make([]VariantAttributeInput, len(stored))
+	for i, attribute := range stored {
+		if !strings.EqualFold(attribute.Name, attributes[i].Name) {
+			return nil, invalid(fmt.Sprintf("The variants of this product are defined by %s, in that order", strings.Join(current, ", ")))
+		}
+
+		values := strings.Split(attribute.Values, ",")
+		known := map[string]bool{}
+		for _, value := range values {
+			known[strings.ToLower(value)] = true
+		}
+		for _, value := range attributes[i].Values {
+			if !known[strings.ToLower(value)] {
+				values = append(values, value)
+			}
+		}
+		merged[i] = VariantAttributeInput{Name: attribute.Name, Values: values}
+	}
+	return merged, nil
+}
+
+// variantCombinations returns every combination of attribute values, one
+// value per attribute, varying the last attribute fastest
+func variantCombinations(attributes []VariantAttributeInput) [][]string {
+	combinations := [][]string{{}}
+	for _, attribute := range attributes {
+		next := make([][]string, 0, len(combinations)*len(attribute.Values))
+		for _, combination := range combinations {
+			for _, value := range attribute.Values {
+				next = append(next, append(append([]string{}, combination...), value))
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// variantKey identifies a variant by its values in attribute order
+func variantKey(attributes []VariantAttributeInput, options []models.VariantOption) string {
+	values := make([]string, len(attributes))
+	for i, attribute := range attributes {
+		for _, option := range options {
+			if strings.EqualFold(option.Name, attribute.Name) {
+				values[i] = strings.ToLower(option.Value)
+			}
+		}
+	}
+	return strings.Join(values, "\x00")
+}
+
+// variantSKU derives a variant SKU from the parent SKU and the values, e.g.
+// TSHIRT-M-NAVY-BLUE
+func variantSKU(parentSKU string, values []string) string {
+	parts := []string{parentSKU}
+	for _, value := range values {
+		code := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToUpper(r)
+			}
+			return '-'
+		}, value)
+		for strings.Contains(code, "--") {
+			code = strings.ReplaceAll(code, "--", "-")
+		}
+		if code = strings.Trim(code, "-"); code != "" {
+			parts = append(parts, code)
+		}
+	}
+	return strings.Join(parts, "-")
+}
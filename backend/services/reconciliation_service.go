@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"log"
+	"time"
+)
+
+// reconciliationInterval is how often the ledger is checked for drift
+const reconciliationInterval = 24 * time.Hour
+
+// StockMismatch describes a product whose stock disagrees with its ledger
+type StockMismatch struct {
+	ProductID   uint    `json:"product_id"`
+	SKU         string  `json:"sku"`
+	Name        string  `json:"name"`
+	Stock       float64 `json:"stock"`        // Product.Stock
+	LedgerStock float64 `json:"ledger_stock"` // Opening balance replayed with every movement
+	Difference  float64 `json:"difference"`   // Stock - LedgerStock
+	Movements   int     `json:"movements"`
+	// BrokenChain is set when the last movement's stock_after does not match
+	// the replay, meaning rows were edited or movements are missing in between
+	BrokenChain bool `json:"broken_chain"`
+	Corrected   bool `json:"corrected"`
+}
+
+// ReconciliationReport is the result of replaying the stock ledger
+type ReconciliationReport struct {
+	CheckedAt  time.Time       `json:"checked_at"`
+	Products   int             `json:"products"`
+	Mismatches []StockMismatch `json:"mismatches"`
+	Corrected  int             `json:"corrected"`
+}
+
+// ReconciliationService replays the stock history of every product and
+// compares it with Product.Stock to find drift between the two
+type ReconciliationService struct {
+	store repositories.Store
+}
+
+func NewReconciliationService(store repositories.Store) *ReconciliationService {
+	return &ReconciliationService{store: store}
+}
+
+// Reconcile reports every product whose stock differs from its replayed
+// ledger. With apply set a corrective movement is posted for each mismatch so
+// the ledger matches the current stock again.
+func (s *ReconciliationService) Reconcile(ctx context.Context, userID uint, apply bool) (*ReconciliationReport, error) {
+	summaries, err := s.store.StockHistory().Ledger(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	ledgers := make(map[uint]repositories.LedgerSummary, len(summaries))
+	for _, summary := range summaries {
+		ledgers[summary.ProductID] = summary
+	}
+
+	report := &ReconciliationReport{CheckedAt: time.Now(), Mismatches: []StockMismatch{}}
+	err = s.store.Products().EachBatch(ctx, repositories.ProductFilter{}, exportBatchSize, func(products []models.Product) error {
+		for _, p := range products {
+			report.Products++
+			if mismatch, ok := compareLedger(p, ledgers[p.ID]); ok {
+				report.Mismatches = append(report.Mismatches, mismatch)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !apply {
+		return report, nil
+	}
+
+	for i := range report.Mismatches {
+		mismatch := &report.Mismatches[i]
+		if err := s.correct(ctx, userID, mismatch); err != nil {
+			return nil, err
+		}
+		if mismatch.Corrected {
+			report.Corrected++
+		}
+	}
+
+	return report, nil
+}
+
+// compareLedger checks a product against its ledger summary. Products without
+// movements are expected to hold no stock.
+func compareLedger(p models.Product, ledger repositories.LedgerSummary) (StockMismatch, bool) {
+	ledgerStock := roundQuantity(ledger.Opening + ledger.NetChange)
+	brokenChain := ledger.Movements > 0 && roundQuantity(ledger.Closing) != ledgerStock
+
+	if p.Stock == ledgerStock && !brokenChain {
+		return StockMismatch{}, false
+	}
+
+	return StockMismatch{
+		ProductID:   p.ID,
+		SKU:         p.SKU,
+		Name:        p.Name,
+		Stock:       p.Stock,
+		LedgerStock: ledgerStock,
+		Difference:  roundQuantity(p.Stock - ledgerStock),
+		Movements:   ledger.Movements,
+		BrokenChain: brokenChain,
+	}, true
+}
+
+// correct posts a movement that brings the ledger replay of a product back to
+// its current stock. The product is re-checked under lock first, so a
+// mismatch fixed by a concurrent movement is left alone.
+func (s *ReconciliationService) correct(ctx context.Context, userID uint, mismatch *StockMismatch) error {
+	return s.store.Transaction(ctx, func(tx repositories.Store) error {
+		product, err := tx.Products().FindByIDForUpdate(ctx, mismatch.ProductID)
+		if err != nil {
+			return notFound(err, ErrProductNotFound)
+		}
+
+		summaries, err := tx.StockHistory().Ledger(ctx, product.ID)
+		if err != nil {
+			return err
+		}
+		var ledger repositories.LedgerSummary
+		if len(summaries) > 0 {
+			ledger = summaries[0]
+		}
+
+		current, ok := compareLedger(*product, ledger)
+		if !ok || current.Difference == 0 {
+			// Nothing a movement can fix, a broken chain alone needs a manual review
+			return nil
+		}
+
+		// Record the drift as a movement from the replayed stock to the actual stock
+		stock := product.Stock
+		product.Stock = current.LedgerStock
+		history, err := adjustStock(ctx, tx, product, stock, fmt.Sprintf("Reconciliation adjustment (ledger %s, stock %s)", formatQuantity(current.LedgerStock), formatQuantity(stock)))
+		if err != nil {
+			return err
+		}
+
+		logActivity(ctx, tx, userID, "RECONCILE", "Product", product.ID, fmt.Sprintf("Posted reconciliation %s %s for %s (%s)", history.Type, formatQuantity(history.Quantity), product.Name, product.SKU))
+
+		*mismatch = current
+		mismatch.Corrected = true
+		return nil
+	})
+}
+
+// Start checks the ledger for drift once a day and logs what it finds
+func (s *ReconciliationService) Start(ctx context.Context) {
+	go every(ctx, reconciliationInterval, func(ctx context.Context) {
+		report, err := s.Reconcile(ctx, 0, false)
+		if err != nil {
+			log.Printf("Stock reconciliation failed: %v", err)
+			return
+		}
+		if len(report.Mismatches) > 0 {
+			log.Printf("⚠️ Stock reconciliation found %d of %d products out of sync with the ledger", len(report.Mismatches), report.Products)
+		}
+	})
+}
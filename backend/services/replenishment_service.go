@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"math"
+	"sort"
+	"time"
+)
+
+// Replenishment bases, i.e. how the order quantity was sized
+const (
+	BasisMaxStock = "max_stock" // Up to Product.MaxStock
+	BasisEOQ      = "eoq"       // Economic order quantity
+	BasisMinStock = "min_stock" // Back up to Product.MinStock
+)
+
+// ReplenishmentOptions configures the order quantity calculation
+type ReplenishmentOptions struct {
+	// DemandDays is the history used to estimate the annual demand
+	DemandDays int
+	// HoldingRate is the yearly cost of holding one unit as a fraction of its price
+	HoldingRate float64
+}
+
+// ReplenishmentItem is the suggested order of one product
+type ReplenishmentItem struct {
+	ProductID    uint    `json:"product_id"`
+	SKU          string  `json:"sku"`
+	Name         string  `json:"name"`
+	BaseUnit     string  `json:"base_unit"`
+	Stock        float64 `json:"stock"`
+	OnOrder      float64 `json:"on_order"` // Already on draft purchase orders
+	MinStock     float64 `json:"min_stock"`
+	MaxStock     float64 `json:"max_stock"`
+	AnnualUsage  float64 `json:"annual_usage"`
+	EOQ          float64 `json:"eoq"`
+	MinOrderQty  float64 `json:"min_order_qty"`
+	PackSize     float64 `json:"pack_size"`
+	Basis        string  `json:"basis"`
+	Quantity     float64 `json:"quantity"`      // In the base unit
+	Unit         string  `json:"unit"`          // Purchase unit of the product
+	UnitQuantity float64 `json:"unit_quantity"` // Quantity in Unit
+	UnitPrice    float64 `json:"unit_price"`    // Per base unit
+	Subtotal     float64 `json:"subtotal"`
+}
+
+// SupplierReplenishment groups the suggestions for one supplier
+type SupplierReplenishment struct {
+	SupplierID    uint                `json:"supplier_id"`
+	SupplierName  string              `json:"supplier_name"`
+	LeadTimeDays  int                 `json:"lead_time_days"`
+	TotalQuantity float64             `json:"total_quantity"`
+	TotalAmount   float64             `json:"total_amount"`
+	Items         []ReplenishmentItem `json:"items"`
+}
+
+// ReplenishmentPlan is the set of suggested orders, one group per supplier
+type ReplenishmentPlan struct {
+	GeneratedAt time.Time               `json:"generated_at"`
+	Suppliers   []SupplierReplenishment `json:"suppliers"`
+}
+
+// ReplenishmentService suggests order quantities for low stock products and
+// turns them into draft purchase orders
+type ReplenishmentService struct {
+	store   repositories.Store
+	options ReplenishmentOptions
+}
+
+func NewReplenishmentService(store repositories.Store, options ReplenishmentOptions) *ReplenishmentService {
+	if options.DemandDays <= 0 {
+		options.DemandDays = 90
+	}
+	if options.HoldingRate <= 0 {
+		options.HoldingRate = 0.25
+	}
+	return &ReplenishmentService{store: store, options: options}
+}
+
+// Suggest computes order quantities for every product below its MinStock,
+// counting stock already on draft purchase orders. supplierID limits the plan
+// to one supplier when set.
+func (s *ReplenishmentService) Suggest(ctx context.Context, supplierID uint) (*ReplenishmentPlan, error) {
+	return s.suggest(ctx, s.store, supplierID)
+}
+
+func (s *ReplenishmentService) suggest(ctx context.Context, store repositories.Store, supplierID uint) (*ReplenishmentPlan, error) {
+	products, err := store.Products().ListLowStock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	open, err := store.PurchaseOrders().OpenQuantities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	onOrder := make(map[uint]float64, len(open))
+	for _, row := range open {
+		onOrder[row.ProductID] = row.Quantity
+	}
+
+	from, to := forecastWindow(s.options.DemandDays)
+	stats, err := store.Analytics().Demand(ctx, from, to, 0)
+	if err != nil {
+		return nil, err
+	}
+	usage := make(map[uint]float64, len(stats))
+	for _, stat := range stats {
+		usage[stat.ProductID] = stat.Total / float64(s.options.DemandDays) * 365
+	}
+
+	plan := &ReplenishmentPlan{GeneratedAt: time.Now(), Suppliers: []SupplierReplenishment{}}
+	groups := map[uint]*SupplierReplenishment{}
+	for _, p := range products {
+		if supplierID != 0 && p.SupplierID != supplierID {
+			continue
+		}
+
+		item, ok := s.suggestItem(p, onOrder[p.ID], usage[p.ID])
+		if !ok {
+			continue
+		}
+
+		group, exists := groups[p.SupplierID]
+		if !exists {
+			group = &SupplierReplenishment{
+				SupplierID:   p.SupplierID,
+				SupplierName: p.Supplier.Name,
+				LeadTimeDays: p.Supplier.LeadTimeDays,
+				Items:        []ReplenishmentItem{},
+			}
+			groups[p.SupplierID] = group
+		}
+		group.Items = append(group.Items, item)
+		group.TotalQuantity = roundQuantity(group.TotalQuantity + item.Quantity)
+		group.TotalAmount += item.Subtotal
+	}
+
+	for _, group := range groups {
+		plan.Suppliers = append(plan.Suppliers, *group)
+	}
+	sort.Slice(plan.Suppliers, func(i, j int) bool {
+		return plan.Suppliers[i].SupplierName < plan.Suppliers[j].SupplierName
+	})
+
+	return plan, nil
+}
+
+// suggestItem sizes the order of one product. Products whose stock plus open
+// orders already reach MinStock need nothing. The quantity is rounded up to
+// whole purchase units when the product has one.
+func (s *ReplenishmentService) suggestItem(p models.Product, onOrder float64, annualUsage float64) (ReplenishmentItem, bool) {
+	position := roundQuantity(p.Stock + onOrder)
+	if position >= p.MinStock {
+		return ReplenishmentItem{}, false
+	}
+
+	item := ReplenishmentItem{
+		ProductID:   p.ID,
+		SKU:         p.SKU,
+		Name:        p.Name,
+		BaseUnit:    p.BaseUnit,
+		Stock:       p.Stock,
+		OnOrder:     onOrder,
+		MinStock:    p.MinStock,
+		MaxStock:    p.MaxStock,
+		AnnualUsage: round2(annualUsage),
+		EOQ:         ceilQuantity(&p, economicOrderQuantity(annualUsage, p.Supplier.OrderCost, p.Price*s.options.HoldingRate)),
+		MinOrderQty: p.MinOrderQty,
+		PackSize:    p.PackSize,
+		UnitPrice:   p.Price,
+	}
+	if item.PackSize <= 0 {
+		item.PackSize = 1
+	}
+
+	shortage := roundQuantity(p.MinStock - position)
+	switch {
+	case p.MaxStock > 0:
+		item.Basis = BasisMaxStock
+		item.Quantity = roundQuantity(p.MaxStock - position)
+	case item.EOQ > 0:
+		item.Basis = BasisEOQ
+		item.Quantity = max(item.EOQ, shortage)
+	default:
+		item.Basis = BasisMinStock
+		item.Quantity = shortage
+	}
+
+	// Supplier constraints: at least the minimum order, in whole packs
+	item.Quantity = max(item.Quantity, item.MinOrderQty)
+	item.Quantity = roundQuantity(math.Ceil(roundQuantity(item.Quantity/item.PackSize)) * item.PackSize)
+
+	// Ordered in whole purchase units, e.g. cartons
+	item.Unit, item.UnitQuantity = p.BaseUnit, item.Quantity
+	if unit, factor, err := unitFactor(&p, p.Units, p.PurchaseUnit); err == nil && factor != 1 {
+		item.Unit = unit
+		item.UnitQuantity = math.Ceil(roundQuantity(item.Quantity / factor))
+		item.Quantity = roundQuantity(item.UnitQuantity * factor)
+	}
+	item.Subtotal = round2(item.Quantity * item.UnitPrice)
+
+	return item, true
+}
+
+// economicOrderQuantity returns √(2DS/H), or 0 when demand, order cost or
+// holding cost is unknown
+func economicOrderQuantity(annualUsage, orderCost, holdingCost float64) float64 {
+	if annualUsage <= 0 || orderCost <= 0 || holdingCost <= 0 {
+		return 0
+	}
+	return math.Sqrt(2 * annualUsage * orderCost / holdingCost)
+}
+
+// CreateDraftOrders turns the current suggestions into one draft purchase
+// order per supplier. supplierIDs limits the conversion to those suppliers.
+// The plan is recomputed inside the transaction so the orders match the
+// stock at the time of the call.
+func (s *ReplenishmentService) CreateDraftOrders(ctx context.Context, userID uint, supplierIDs []uint) ([]models.PurchaseOrder, error) {
+	selected := make(map[uint]bool, len(supplierIDs))
+	for _, id := range supplierIDs {
+		selected[id] = true
+	}
+
+	orders := []models.PurchaseOrder{}
+	err := s.store.Transaction(ctx, func(tx repositories.Store) error {
+		plan, err := s.suggest(ctx, tx, 0)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, group := range plan.Suppliers {
+			if len(selected) > 0 && !selected[group.SupplierID] {
+				continue
+			}
+
+			order := models.PurchaseOrder{
+				SupplierID:    group.SupplierID,
+				Status:        models.PurchaseOrderDraft,
+				Note:          fmt.Sprintf("Generated from replenishment suggestions on %s", now.Format("2006-01-02 15:04")),
+				TotalQuantity: group.TotalQuantity,
+				TotalAmount:   round2(group.TotalAmount),
+				CreatedBy:     userID,
+			}
+			for _, item := range group.Items {
+				order.Items = append(order.Items, models.PurchaseOrderItem{
+					ProductID:    item.ProductID,
+					Quantity:     item.Quantity,
+					Unit:         item.Unit,
+					UnitQuantity: item.UnitQuantity,
+					UnitPrice:    item.UnitPrice,
+					Subtotal:     item.Subtotal,
+				})
+			}
+
+			if err := tx.PurchaseOrders().Create(ctx, &order); err != nil {
+				return err
+			}
+			order.Number = fmt.Sprintf("PO-%s-%d", now.Format("20060102"), order.ID)
+			if err := tx.PurchaseOrders().Save(ctx, &order); err != nil {
+				return err
+			}
+
+			logActivity(ctx, tx, userID, "CREATE", "PurchaseOrder", order.ID, fmt.Sprintf("Created draft purchase order %s for %s with %d items", order.Number, group.SupplierName, len(order.Items)))
+			orders = append(orders, order)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+func (s *ReplenishmentService) ListOrders(ctx context.Context, filter repositories.PurchaseOrderFilter) ([]models.PurchaseOrder, error) {
+	if filter.Status != "" && filter.Status != models.PurchaseOrderDraft && filter.Status != models.PurchaseOrderCancelled {
+		return nil, invalid("Status must be 'draft' or 'cancelled'")
+	}
+	if filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+	return s.store.PurchaseOrders().List(ctx, filter)
+}
+
+func (s *ReplenishmentService) GetOrder(ctx context.Context, id uint) (*models.PurchaseOrder, error) {
+	order, err := s.store.PurchaseOrders().FindByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrPurchaseOrderNotFound)
+	}
+	return order, nil
+}
+
+// CancelOrder cancels a draft purchase order so its quantities no longer
+// count as on order
+func (s *ReplenishmentService) CancelOrder(ctx context.Context, userID uint, id uint) (*models.PurchaseOrder, error) {
+	var order *models.PurchaseOrder
+	err := s.store.Transaction(ctx, func(tx repositories.Store) error {
+		var err error
+		order, err = tx.PurchaseOrders().FindByID(ctx, id)
+		if err != nil {
+			return notFound(err, ErrPurchaseOrderNotFound)
+		}
+		if order.Status != models.PurchaseOrderDraft {
+			return invalid("Only draft purchase orders can be cancelled")
+		}
+
+		order.Status = models.PurchaseOrderCancelled
+		if err := tx.PurchaseOrders().Save(ctx, order); err != nil {
+			return err
+		}
+
+		logActivity(ctx, tx, userID, "CANCEL", "PurchaseOrder", order.ID, "Cancelled purchase order "+order.Number)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
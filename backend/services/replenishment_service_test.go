@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+
+	"inventory-backend/models"
+)
+
+func TestEconomicOrderQuantity(t *testing.T) {
+	// √(2 * 1200 * 50 / 3) = 200
+	if got := economicOrderQuantity(1200, 50, 3); got != 200 {
+		t.Errorf("economicOrderQuantity(1200, 50, 3) = %v, want 200", got)
+	}
+	for _, args := range [][3]float64{{0, 50, 3}, {1200, 0, 3}, {1200, 50, 0}} {
+		if got := economicOrderQuantity(args[0], args[1], args[2]); got != 0 {
+			t.Errorf("economicOrderQuantity(%v) = %v, want 0 when an input is unknown", args, got)
+		}
+	}
+}
+
+func TestSuggestItem(t *testing.T) {
+	s := NewReplenishmentService(nil, ReplenishmentOptions{HoldingRate: 0.25})
+	// A price of 12 holds at 3 a year, EOQ is 200 at 1200 a year
+	product := func() models.Product {
+		return models.Product{
+			Price: 12, Stock: 5, MinStock: 20, PackSize: 1, BaseUnit: "pcs",
+			Supplier: models.Supplier{OrderCost: 50},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		edit     func(p *models.Product)
+		onOrder  float64
+		basis    string
+		quantity float64
+	}{
+		{"eoq", func(p *models.Product) {}, 0, BasisEOQ, 200},
+		{"whole packs", func(p *models.Product) { p.PackSize = 12 }, 0, BasisEOQ, 204},
+		{"max stock", func(p *models.Product) { p.MaxStock = 100 }, 10, BasisMaxStock, 85},
+		{"minimum order", func(p *models.Product) { p.MaxStock = 100; p.MinOrderQty = 90 }, 10, BasisMaxStock, 90},
+		{"no cost data", func(p *models.Product) { p.Supplier.OrderCost = 0 }, 0, BasisMinStock, 15},
+		{"purchase unit", func(p *models.Product) {
+			p.PurchaseUnit = "carton"
+			p.Units = []models.ProductUnit{{Name: "carton", Factor: 24}}
+		}, 0, BasisEOQ, 216},
+	}
+	for _, tt := range tests {
+		p := product()
+		tt.edit(&p)
+		item, ok := s.suggestItem(p, tt.onOrder, 1200)
+		if !ok {
+			t.Errorf("%s: no suggestion", tt.name)
+			continue
+		}
+		if item.Basis != tt.basis || item.Quantity != tt.quantity {
+			t.Errorf("%s: %v of %s, want %v of %s", tt.name, item.Quantity, item.Basis, tt.quantity, tt.basis)
+		}
+	}
+
+	p := product()
+	if _, ok := s.suggestItem(p, 15, 1200); ok {
+		t.Error("suggested an order for a product whose open orders reach MinStock")
+	}
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/xuri/excelize/v2"
+)
+
+// Report is a titled table that can be rendered as PDF or XLSX
+type Report struct {
+	Title       string          `json:"title"`
+	Subtitle    string          `json:"subtitle,omitempty"`
+	Columns     []string        `json:"columns"`
+	Rows        [][]interface{} `json:"rows"`
+	GeneratedAt time.Time       `json:"generated_at"`
+}
+
+// ReportContentTypes lists the formats a Report can be rendered to
+var ReportContentTypes = map[string]string{
+	"pdf":  "application/pdf",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// RenderReport writes report to writer as pdf or xlsx
+func RenderReport(report *Report, format string, writer io.Writer) error {
+	switch format {
+	case "pdf":
+		return renderReportPDF(report, writer)
+	case "xlsx":
+		return renderReportExcel(report, writer)
+	}
+	return invalid("Format must be 'pdf' or 'xlsx'")
+}
+
+func renderReportPDF(report *Report, writer io.Writer) error {
+	orientation := "P"
+	if len(report.Columns) > 6 {
+		orientation = "L"
+	}
+
+	pdf := gofpdf.New(orientation, "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Arial", "B", 16)
+	pdf.Cell(40, 10, report.Title)
+	pdf.Ln(12)
+
+	pdf.SetFont("Arial", "", 10)
+	if report.Subtitle != "" {
+		pdf.Cell(0, 8, report.Subtitle)
+		pdf.Ln(8)
+	}
+	pdf.Cell(0, 10, fmt.Sprintf("Generated on: %s", report.GeneratedAt.Format("2006-01-02 15:04:05")))
+	pdf.Ln(12)
+
+	pageWidth, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	colWidth := (pageWidth - left - right) / float64(max(len(report.Columns), 1))
+	// Rough number of characters that fit in a column at font size 9
+	maxChars := int(colWidth / 1.9)
+
+	header := func() {
+		pdf.SetFont("Arial", "B", 10)
+		pdf.SetFillColor(240, 240, 240)
+		for _, column := range report.Columns {
+			pdf.CellFormat(colWidth, 10, truncate(column, maxChars), "1", 0, "", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Arial", "", 9)
+	}
+
+	// Table Header
+	header()
+
+	// Table Rows
+	_, pageHeight := pdf.GetPageSize()
+	_, _, _, bottom := pdf.GetMargins()
+	for _, row := range report.Rows {
+		if pdf.GetY()+8 > pageHeight-bottom {
+			pdf.AddPage()
+			header()
+		}
+		for _, value := range row {
+			align := ""
+			switch value.(type) {
+			case int, int64, uint, float64, reportQuantity:
+				align = "R"
+			}
+			pdf.CellFormat(colWidth, 8, truncate(formatReportValue(value), maxChars), "1", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	return pdf.Output(writer)
+}
+
+func renderReportExcel(report *Report, writer io.Writer) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheetName := "Report"
+	f.SetSheetName("Sheet1", sheetName)
+
+	f.SetCellValue(sheetName, "A1", report.Title)
+	f.SetCellValue(sheetName, "A2", report.Subtitle)
+	f.SetCellValue(sheetName, "A3", fmt.Sprintf("Generated on: %s", report.GeneratedAt.Format("2006-01-02 15:04:05")))
+
+	// Headers
+	for i, column := range report.Columns {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 5)
+		col, _ := excelize.ColumnNumberToName(i + 1)
+		f.SetCellValue(sheetName, cell, column)
+		f.SetColWidth(sheetName, col, col, 20)
+	}
+
+	// Style Header
+	style, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#CCCCCC"}, Pattern: 1},
+	})
+	lastHeader, _ := excelize.CoordinatesToCellName(max(len(report.Columns), 1), 5)
+	f.SetCellStyle(sheetName, "A5", lastHeader, style)
+	titleStyle, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}})
+	f.SetCellStyle(sheetName, "A1", "A1", titleStyle)
+
+	// Data
+	for i, row := range report.Rows {
+		for j, value := range row {
+			if value == nil {
+				continue
+			}
+			if q, ok := value.(reportQuantity); ok {
+				value = float64(q)
+			}
+			cell, _ := excelize.CoordinatesToCellName(j+1, i+6)
+			f.SetCellValue(sheetName, cell, value)
+		}
+	}
+
+	return f.Write(writer)
+}
+
+// reportQuantity marks a stock quantity in a report row so it is rendered
+// with its own decimals instead of as an amount
+type reportQuantity float64
+
+func formatReportValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "-"
+	case float64:
+		return fmt.Sprintf("%.2f", v)
+	case reportQuantity:
+		return formatQuantity(float64(v))
+	case time.Time:
+		return v.Format("2006-01-02")
+	}
+	return fmt.Sprint(value)
+}
+
+func truncate(s string, maxChars int) string {
+	if maxChars > 3 && len(s) > maxChars {
+		return s[:maxChars-3] + "..."
+	}
+	return s
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Report delivery channels
+const (
+	DeliveryEmail = "email"
+	DeliveryDisk  = "disk"
+)
+
+// schedulerInterval is how often due report schedules are checked
+const schedulerInterval = time.Minute
+
+// ReportScheduleInput holds schedule fields. On update, empty fields and a nil
+// Enabled are left untouched.
+type ReportScheduleInput struct {
+	Name       string `json:"name"`
+	ReportType string `json:"report_type"`
+	Format     string `json:"format"`
+	CronExpr   string `json:"cron_expr"`
+	PeriodDays int    `json:"period_days"`
+	Delivery   string `json:"delivery"`
+	Recipients string `json:"recipients"`
+	Enabled    *bool  `json:"enabled"`
+}
+
+// ReportScheduleService stores report schedules and runs them when they are
+// due. Delivery goes through a Sender per channel, so SMTP can be swapped for
+// a directory sink while testing.
+type ReportScheduleService struct {
+	store   repositories.Store
+	reports *ReportService
+	senders map[string]Sender
+}
+
+func NewReportScheduleService(store repositories.Store, reports *ReportService, senders map[string]Sender) *ReportScheduleService {
+	return &ReportScheduleService{store: store, reports: reports, senders: senders}
+}
+
+func (s *ReportScheduleService) List(ctx context.Context) ([]models.ReportSchedule, error) {
+	return s.store.ReportSchedules().List(ctx)
+}
+
+func (s *ReportScheduleService) Get(ctx context.Context, id uint) (*models.ReportSchedule, error) {
+	schedule, err := s.store.ReportSchedules().FindByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrReportScheduleNotFound)
+	}
+	return schedule, nil
+}
+
+func (s *ReportScheduleService) Create(ctx context.Context, userID uint, input ReportScheduleInput) (*models.ReportSchedule, error) {
+	schedule := models.ReportSchedule{
+		Format:     "pdf",
+		Delivery:   DeliveryEmail,
+		PeriodDays: 7,
+		Enabled:    true,
+		CreatedBy:  userID,
+	}
+	applyScheduleInput(&schedule, input)
+
+	if err := s.prepare(&schedule); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.ReportSchedules().Create(ctx, &schedule); err != nil {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+func (s *ReportScheduleService) Update(ctx context.Context, id uint, input ReportScheduleInput) (*models.ReportSchedule, error) {
+	schedule, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	applyScheduleInput(schedule, input)
+	if err := s.prepare(schedule); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.ReportSchedules().Save(ctx, schedule); err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+func (s *ReportScheduleService) Delete(ctx context.Context, id uint) error {
+	schedule, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.store.ReportSchedules().Delete(ctx, schedule)
+}
+
+// RunNow runs a schedule immediately without changing its next run time
+func (s *ReportScheduleService) RunNow(ctx context.Context, id uint) (*models.ReportSchedule, error) {
+	schedule, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.run(ctx, schedule)
+	return schedule, nil
+}
+
+// Start checks for due schedules every minute until ctx is cancelled. The
+// scheduler assumes a single running instance of the API.
+func (s *ReportScheduleService) Start(ctx context.Context) {
+	go every(ctx, schedulerInterval, s.runDue)
+}
+
+func (s *ReportScheduleService) runDue(ctx context.Context) {
+	now := time.Now()
+	schedules, err := s.store.ReportSchedules().ListDue(ctx, now)
+	if err != nil {
+		log.Printf("Failed to list due report schedules: %v", err)
+		return
+	}
+
+	for i := range schedules {
+		schedule := &schedules[i]
+
+		// Advance before running so a slow or failing report is not retried every minute
+		cron, err := ParseCron(schedule.CronExpr)
+		if err != nil {
+			schedule.Enabled = false
+			schedule.LastStatus = "failed"
+			schedule.LastError = fmt.Sprintf("invalid cron expression: %v", err)
+			s.store.ReportSchedules().Save(ctx, schedule)
+			continue
+		}
+		next := cron.Next(now)
+		schedule.NextRunAt = &next
+
+		s.run(ctx, schedule)
+	}
+}
+
+// run builds, renders and delivers a report and records the outcome on the schedule
+func (s *ReportScheduleService) run(ctx context.Context, schedule *models.ReportSchedule) {
+	err := s.deliver(ctx, schedule)
+
+	ranAt := time.Now()
+	schedule.LastRunAt = &ranAt
+	if err != nil {
+		log.Printf("Report schedule %d (%s) failed: %v", schedule.ID, schedule.Name, err)
+		schedule.LastStatus = "failed"
+		schedule.LastError = err.Error()
+	} else {
+		schedule.LastStatus = "success"
+		schedule.LastError = ""
+	}
+
+	if err := s.store.ReportSchedules().Save(ctx, schedule); err != nil {
+		log.Printf("Failed to save report schedule %d: %v", schedule.ID, err)
+	}
+}
+
+func (s *ReportScheduleService) deliver(ctx context.Context, schedule *models.ReportSchedule) error {
+	sender, ok := s.senders[schedule.Delivery]
+	if !ok {
+		return fmt.Errorf("no sender configured for %q delivery", schedule.Delivery)
+	}
+
+	report, err := s.reports.Build(ctx, schedule.ReportType, ReportParams{PeriodDays: schedule.PeriodDays})
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := RenderReport(report, schedule.Format, &buf); err != nil {
+		return err
+	}
+
+	fileName := fmt.Sprintf("%s_%s.%s", schedule.ReportType, report.GeneratedAt.Format("20060102_1504"), schedule.Format)
+	return sender.Send(ctx, Message{
+		To:      splitRecipients(schedule.Recipients),
+		Subject: fmt.Sprintf("[Inventory] %s - %s", schedule.Name, report.GeneratedAt.Format("2006-01-02")),
+		Body:    fmt.Sprintf("%s\n%s\n\nThe full report is attached (%d rows).", report.Title, report.Subtitle, len(report.Rows)),
+		Attachments: []Attachment{{
+			FileName:    fileName,
+			ContentType: ReportContentTypes[schedule.Format],
+			Data:        buf.Bytes(),
+		}},
+	})
+}
+
+func applyScheduleInput(schedule *models.ReportSchedule, input ReportScheduleInput) {
+	if input.Name != "" {
+		schedule.Name = input.Name
+	}
+	if input.ReportType != "" {
+		schedule.ReportType = input.ReportType
+	}
+	if input.Format != "" {
+		schedule.Format = input.Format
+	}
+	if input.CronExpr != "" {
+		schedule.CronExpr = input.CronExpr
+	}
+	if input.PeriodDays != 0 {
+		schedule.PeriodDays = input.PeriodDays
+	}
+	if input.Delivery != "" {
+		schedule.Delivery = input.Delivery
+	}
+	if input.Recipients != "" {
+		schedule.Recipients = input.Recipients
+	}
+	if input.Enabled != nil {
+		schedule.Enabled = *input.Enabled
+	}
+}
+
+// prepare validates a schedule and computes its next run time
+func (s *ReportScheduleService) prepare(schedule *models.ReportSchedule) error {
+	if schedule.Name == "" {
+		return invalid("Name is required")
+	}
+	if !IsReportType(schedule.ReportType) {
+		return invalid("Report type must be 'low_stock', 'valuation', 'stock_movements', 'abc' or 'dead_stock'")
+	}
+	if _, ok := ReportContentTypes[schedule.Format]; !ok {
+		return invalid("Format must be 'pdf' or 'xlsx'")
+	}
+	if schedule.PeriodDays < 0 {
+		return invalid("Period days cannot be negative")
+	}
+	if _, ok := s.senders[schedule.Delivery]; !ok {
+		return invalid("Delivery must be 'email' or 'disk'")
+	}
+	if schedule.Delivery == DeliveryEmail {
+		recipients := splitRecipients(schedule.Recipients)
+		if len(recipients) == 0 {
+			return invalid("Recipients are required for email delivery")
+		}
+		for _, recipient := range recipients {
+			if _, err := mail.ParseAddress(recipient); err != nil {
+				return invalid(fmt.Sprintf("Invalid recipient %q", recipient))
+			}
+		}
+	}
+
+	cron, err := ParseCron(schedule.CronExpr)
+	if err != nil {
+		return invalid(fmt.Sprintf("Invalid cron expression: %v", err))
+	}
+	next := cron.Next(time.Now())
+	if next.IsZero() {
+		return invalid("Cron expression never matches")
+	}
+	schedule.NextRunAt = &next
+
+	return nil
+}
+
+func splitRecipients(recipients string) []string {
+	var result []string
+	for _, recipient := range strings.Split(recipients, ",") {
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			result = append(result, recipient)
+		}
+	}
+	return result
+}
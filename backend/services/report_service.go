@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"strings"
+	"time"
+)
+
+// Report types
+const (
+	ReportLowStock       = "low_stock"
+	ReportValuation      = "valuation"
+	ReportStockMovements = "stock_movements"
+	ReportABC            = "abc"
+	ReportDeadStock      = "dead_stock"
+)
+
+// ReportParams holds the options shared by report definitions
+type ReportParams struct {
+	// PeriodDays is the look-back window of period based reports
+	PeriodDays int
+}
+
+// ReportService builds the tabular reports used for scheduled delivery
+type ReportService struct {
+	store repositories.Store
+}
+
+func NewReportService(store repositories.Store) *ReportService {
+	return &ReportService{store: store}
+}
+
+// IsReportType reports whether reportType names a known report
+func IsReportType(reportType string) bool {
+	switch reportType {
+	case ReportLowStock, ReportValuation, ReportStockMovements, ReportABC, ReportDeadStock:
+		return true
+	}
+	return false
+}
+
+// Build generates the report of the given type
+func (s *ReportService) Build(ctx context.Context, reportType string, params ReportParams) (*Report, error) {
+	switch reportType {
+	case ReportLowStock:
+		return s.lowStock(ctx)
+	case ReportValuation:
+		return s.valuation(ctx)
+	case ReportStockMovements:
+		return s.stockMovements(ctx, params)
+	case ReportABC:
+		abc, err := s.ABC(ctx, 0, ABCParams{Days: params.PeriodDays}, false)
+		if err != nil {
+			return nil, err
+		}
+		return abc.Report(), nil
+	case ReportDeadStock:
+		dead, err := s.DeadStock(ctx, 0, params.PeriodDays, false)
+		if err != nil {
+			return nil, err
+		}
+		return dead.Report(), nil
+	}
+	return nil, invalid(fmt.Sprintf("Unknown report type %q", reportType))
+}
+
+func (s *ReportService) lowStock(ctx context.Context) (*Report, error) {
+	products, err := s.store.Products().ListLowStock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Title:       "Low Stock Report",
+		Subtitle:    fmt.Sprintf("%d products below minimum stock", len(products)),
+		Columns:     []string{"SKU", "Name", "Supplier", "Stock", "Min Stock", "Shortage"},
+		Rows:        [][]interface{}{},
+		GeneratedAt: time.Now(),
+	}
+	for _, p := range products {
+		report.Rows = append(report.Rows, []interface{}{p.SKU, p.Name, p.Supplier.Name, reportQuantity(p.Stock), reportQuantity(p.MinStock), reportQuantity(roundQuantity(p.MinStock - p.Stock))})
+	}
+
+	return report, nil
+}
+
+func (s *ReportService) valuation(ctx context.Context) (*Report, error) {
+	report := &Report{
+		Title:       "Inventory Valuation Report",
+		Columns:     []string{"SKU", "Name", "Category", "Supplier", "Stock", "Price", "Value"},
+		Rows:        [][]interface{}{},
+		GeneratedAt: time.Now(),
+	}
+
+	var totalUnits float64
+	var totalValue float64
+	err := s.store.Products().EachBatch(ctx, repositories.ProductFilter{}, exportBatchSize, func(products []models.Product) error {
+		for _, p := range products {
+			category := "-"
+			if p.CategoryID != nil {
+				category = p.Category.Name
+			}
+			value := p.Stock * p.Price
+			report.Rows = append(report.Rows, []interface{}{p.SKU, p.Name, category, p.Supplier.Name, reportQuantity(p.Stock), p.Price, value})
+			totalUnits += p.Stock
+			totalValue += value
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.Rows = append(report.Rows, []interface{}{"TOTAL", "", "", "", reportQuantity(roundQuantity(totalUnits)), nil, totalValue})
+	report.Subtitle = fmt.Sprintf("%d products, total value %.2f", len(report.Rows)-1, totalValue)
+	return report, nil
+}
+
+func (s *ReportService) stockMovements(ctx context.Context, params ReportParams) (*Report, error) {
+	days := params.PeriodDays
+	if days <= 0 {
+		days = 7
+	}
+	to := time.Now()
+
+	movements, err := s.Movements(ctx, repositories.MovementQuery{
+		From:    to.AddDate(0, 0, -days),
+		To:      to,
+		GroupBy: "product",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := movements.Report()
+	report.Title = "Stock Movement Summary"
+	return report, nil
+}
+
+// MovementReport is the result of a movement aggregation
+type MovementReport struct {
+	From     time.Time                        `json:"from"`
+	To       time.Time                        `json:"to"`
+	GroupBy  string                           `json:"group_by"`
+	Interval string                           `json:"interval"`
+	Totals   MovementTotals                   `json:"totals"`
+	Rows     []repositories.MovementAggregate `json:"rows"`
+}
+
+type MovementTotals struct {
+	QtyIn     float64 `json:"qty_in"`
+	QtyOut    float64 `json:"qty_out"`
+	Net       float64 `json:"net"`
+	Movements int     `json:"movements"`
+}
+
+// Movements aggregates stock history in [From, To) by the requested group
+// and interval. GroupBy defaults to product.
+func (s *ReportService) Movements(ctx context.Context, query repositories.MovementQuery) (*MovementReport, error) {
+	if query.GroupBy == "" {
+		query.GroupBy = "product"
+	}
+	if !repositories.IsMovementGroup(query.GroupBy) {
+		return nil, invalid("group_by must be 'product', 'category', 'supplier' or 'type'")
+	}
+	if !repositories.IsMovementInterval(query.Interval) {
+		return nil, invalid("interval must be 'day', 'week' or 'month'")
+	}
+	if query.Type != "" && query.Type != "in" && query.Type != "out" {
+		return nil, invalid("Type must be 'in' or 'out'")
+	}
+	if !query.From.Before(query.To) {
+		return nil, invalid("from must be before to")
+	}
+
+	rows, err := s.store.StockHistory().Aggregate(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MovementReport{
+		From:     query.From,
+		To:       query.To,
+		GroupBy:  query.GroupBy,
+		Interval: query.Interval,
+		Rows:     rows,
+	}
+	for _, row := range rows {
+		result.Totals.QtyIn = roundQuantity(result.Totals.QtyIn + row.QtyIn)
+		result.Totals.QtyOut = roundQuantity(result.Totals.QtyOut + row.QtyOut)
+		result.Totals.Movements += row.Movements
+	}
+	result.Totals.Net = roundQuantity(result.Totals.QtyIn - result.Totals.QtyOut)
+
+	return result, nil
+}
+
+// Report converts the aggregation into a table for PDF or XLSX rendering
+func (m *MovementReport) Report() *Report {
+	report := &Report{
+		Title:       "Stock Movement Report",
+		Subtitle:    fmt.Sprintf("%s to %s, grouped by %s", m.From.Format("2006-01-02 15:04"), m.To.Format("2006-01-02 15:04"), m.GroupBy),
+		Rows:        [][]interface{}{},
+		GeneratedAt: time.Now(),
+	}
+
+	if m.Interval != "" {
+		report.Columns = []string{"Period"}
+		report.Subtitle += " per " + m.Interval
+	}
+	report.Columns = append(report.Columns, strings.ToUpper(m.GroupBy[:1])+m.GroupBy[1:], "In", "Out", "Net", "Movements")
+
+	for _, row := range m.Rows {
+		var values []interface{}
+		if m.Interval != "" {
+			values = append(values, row.Period)
+		}
+		report.Rows = append(report.Rows, append(values, row.GroupName, reportQuantity(row.QtyIn), reportQuantity(row.QtyOut), reportQuantity(roundQuantity(row.QtyIn-row.QtyOut)), row.Movements))
+	}
+
+	var totals []interface{}
+	if m.Interval != "" {
+		totals = append(totals, "")
+	}
+	report.Rows = append(report.Rows, append(totals, "TOTAL", reportQuantity(m.Totals.QtyIn), reportQuantity(m.Totals.QtyOut), reportQuantity(m.Totals.Net), m.Totals.Movements))
+
+	return report
+}
+
+// StockAsOfReport lists the stock of every product at a point in time
+type StockAsOfReport struct {
+	At         time.Time                 `json:"at"`
+	TotalUnits float64                   `json:"total_units"`
+	TotalValue float64                   `json:"total_value"` // valued at current prices
+	Products   []repositories.StockLevel `json:"products"`
+}
+
+// StockAsOf returns the stock on hand of every product that existed at the
+// given time, derived from the movement ledger and the nightly snapshots
+func (s *ReportService) StockAsOf(ctx context.Context, at time.Time, categoryID uint) (*StockAsOfReport, error) {
+	if at.After(time.Now()) {
+		return nil, invalid("Date cannot be in the future")
+	}
+
+	levels, err := s.store.StockSnapshots().StockAsOf(ctx, at, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &StockAsOfReport{At: at, Products: levels}
+	for _, level := range levels {
+		result.TotalUnits = roundQuantity(result.TotalUnits + level.Quantity)
+		result.TotalValue += level.Quantity * level.Price
+	}
+	return result, nil
+}
+
+// Report converts the snapshot into a table for PDF or XLSX rendering
+func (r *StockAsOfReport) Report() *Report {
+	report := &Report{
+		Title:       "Stock As Of Report",
+		Subtitle:    fmt.Sprintf("Stock on hand at %s (valued at current prices)", r.At.Format("2006-01-02 15:04")),
+		Columns:     []string{"SKU", "Name", "Quantity", "Price", "Value"},
+		Rows:        [][]interface{}{},
+		GeneratedAt: time.Now(),
+	}
+	for _, level := range r.Products {
+		report.Rows = append(report.Rows, []interface{}{level.SKU, level.Name, reportQuantity(level.Quantity), level.Price, level.Quantity * level.Price})
+	}
+	report.Rows = append(report.Rows, []interface{}{"TOTAL", "", reportQuantity(r.TotalUnits), nil, r.TotalValue})
+	return report
+}
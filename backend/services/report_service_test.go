@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+)
+
+func TestMovementsIncludeSubcategories(t *testing.T) {
+	store := newTestStore(t)
+	products, stock, _ := newTestServices(store)
+	supplier := createTestSupplier(t, store)
+	ctx := context.Background()
+
+	parent := models.Category{Name: "Power"}
+	if err := store.Categories().Create(ctx, &parent); err != nil {
+		t.Fatal(err)
+	}
+	child := models.Category{Name: "Batteries", ParentID: &parent.ID}
+	if err := store.Categories().Create(ctx, &child); err != nil {
+		t.Fatal(err)
+	}
+	product, err := products.Create(ctx, 1, ProductInput{SKU: "AA", Name: "AA cell", Price: 1, Stock: 10, SupplierID: supplier.ID, CategoryID: &child.ID})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := stock.Move(ctx, 1, product.ID, StockMovementInput{Type: "out", Quantity: 4}); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+
+	report, err := NewReportService(store).Movements(ctx, repositories.MovementQuery{
+		From:       time.Now().Add(-time.Hour),
+		To:         time.Now().Add(time.Hour),
+		GroupBy:    "type",
+		CategoryID: parent.ID,
+	})
+	if err != nil {
+		t.Fatalf("Movements: %v", err)
+	}
+	if report.Totals.QtyOut != 4 {
+		t.Errorf("out of the parent category = %v, want the 4 moved in its subcategory", report.Totals.QtyOut)
+	}
+}
+
+func TestStockAsOfIncludesSubcategories(t *testing.T) {
+	store := newTestStore(t)
+	products, _, _ := newTestServices(store)
+	supplier := createTestSupplier(t, store)
+	ctx := context.Background()
+
+	parent := models.Category{Name: "Power"}
+	if err := store.Categories().Create(ctx, &parent); err != nil {
+		t.Fatal(err)
+	}
+	child := models.Category{Name: "Batteries", ParentID: &parent.ID}
+	if err := store.Categories().Create(ctx, &child); err != nil {
+		t.Fatal(err)
+	}
+	product, err := products.Create(ctx, 1, ProductInput{SKU: "AA", Name: "AA cell", Price: 1, Stock: 10, SupplierID: supplier.ID, CategoryID: &child.ID})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	report, err := NewReportService(store).StockAsOf(ctx, time.Now(), parent.ID)
+	if err != nil {
+		t.Fatalf("StockAsOf: %v", err)
+	}
+	if len(report.Products) != 1 || report.Products[0].ProductID != product.ID {
+		t.Errorf("stock of the parent category = %+v, want the product of its subcategory", report.Products)
+	}
+}
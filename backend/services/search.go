@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// maxSearchHits bounds the products a search returns, best first
+	maxSearchHits = 1000
+	// fuzzyRatio keeps typo matches scoring at least this share of the best
+	// one, dropping those that only share a few character pairs
+	fuzzyRatio = 0.5
+	// searchBatchSize is the number of products indexed per query, and per
+	// transaction while catching up
+	searchBatchSize = 500
+	// minWordLength is the shortest word in the word indexes, MySQL's
+	// innodb_ft_min_token_size. Shorter words only match through bigrams.
+	minWordLength = 3
+	// searchCatchUpInterval is how often products missing from the index are
+	// looked for
+	searchCatchUpInterval = 10 * time.Minute
+)
+
+// SearchIndex ranks products for a free text query. It is updated inside the
+// transaction of every product write, so searches never see a document that
+// disagrees with committed data.
+type SearchIndex interface {
+	// Index adds or refreshes the documents of products. Ids of products
+	// that no longer exist are removed.
+	Index(ctx context.Context, tx repositories.Store, productIDs ...uint) error
+	// Remove drops the documents of products
+	Remove(ctx context.Context, tx repositories.Store, productIDs ...uint) error
+	// Search returns the ids of the best matches for query, most relevant
+	// first
+	Search(ctx context.Context, query string, limit int) ([]uint, error)
+	// Rebuild refreshes the whole index and returns the products indexed
+	Rebuild(ctx context.Context) (int, error)
+}
+
+// FullTextIndex is a SearchIndex on the database FULLTEXT indexes of
+// models.ProductSearch. Words match whole or as prefixes, e.g. "key" finds
+// keyboard; when no word matches, a bigram index finds misspellings, e.g.
+// "keybaord".
+type FullTextIndex struct {
+	store repositories.Store
+}
+
+func NewFullTextIndex(store repositories.Store) *FullTextIndex {
+	return &FullTextIndex{store: store}
+}
+
+func (i *FullTextIndex) Index(ctx context.Context, tx repositories.Store, productIDs ...uint) error {
+	for start := 0; start < len(productIDs); start += searchBatchSize {
+		ids := productIDs[start:min(start+searchBatchSize, len(productIDs))]
+		products, err := tx.Products().ListByIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		categories, err := tx.Categories().List(ctx)
+		if err != nil {
+			return err
+		}
+
+		documents := make([]models.ProductSearch, len(products))
+		found := make(map[uint]bool, len(products))
+		for n, product := range products {
+			documents[n] = searchDocument(product, categories)
+			found[product.ID] = true
+		}
+		if err := tx.Search().Save(ctx, documents); err != nil {
+			return err
+		}
+
+		var missing []uint
+		for _, id := range ids {
+			if !found[id] {
+				missing = append(missing, id)
+			}
+		}
+		if err := tx.Search().Delete(ctx, missing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *FullTextIndex) Remove(ctx context.Context, tx repositories.Store, productIDs ...uint) error {
+	return tx.Search().Delete(ctx, productIDs)
+}
+
+func (i *FullTextIndex) Search(ctx context.Context, query string, limit int) ([]uint, error) {
+	words := searchWords(query)
+	if len(words) == 0 {
+		return []uint{}, nil
+	}
+
+	// Every word must match, the last one also as a prefix while typing
+	var terms []string
+	for n, word := range words {
+		if len([]rune(word)) < minWordLength {
+			continue
+		}
+		term := "+" + word
+		if n == len(words)-1 {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+
+	matches, err := i.store.Search().Match(ctx, strings.Join(terms, " "), strings.Join(words, " "), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Typo matches only stand in when no word matches, and only the close ones
+	ids := make([]uint, 0, len(matches))
+	for _, match := range matches {
+		if match.Words > 0 {
+			ids = append(ids, match.ProductID)
+		}
+	}
+	if len(ids) > 0 || len(matches) == 0 {
+		return ids, nil
+	}
+	for _, match := range matches {
+		if match.Grams >= matches[0].Grams*fuzzyRatio {
+			ids = append(ids, match.ProductID)
+		}
+	}
+	return ids, nil
+}
+
+// Start keeps catching up on products missing from the index, e.g. written
+// before the index existed, until ctx is cancelled
+func (i *FullTextIndex) Start(ctx context.Context) {
+	go every(ctx, searchCatchUpInterval, func(ctx context.Context) {
+		count, err := i.catchUp(ctx)
+		if err != nil {
+			log.Printf("Failed to update search index: %v", err)
+			return
+		}
+		if count > 0 {
+			log.Printf("Indexed %d products for search", count)
+		}
+	})
+}
+
+func (i *FullTextIndex) catchUp(ctx context.Context) (int, error) {
+	indexed := 0
+	for {
+		ids, err := i.store.Search().ListUnindexed(ctx, searchBatchSize)
+		if err != nil || len(ids) == 0 {
+			return indexed, err
+		}
+		err = i.store.Transaction(ctx, func(tx repositories.Store) error {
+			return i.Index(ctx, tx, ids...)
+		})
+		if err != nil {
+			return indexed, err
+		}
+		indexed += len(ids)
+	}
+}
+
+// Rebuild refreshes the document of every product and drops those of deleted
+// products
+func (i *FullTextIndex) Rebuild(ctx context.Context) (int, error) {
+	ids, err := i.store.Products().ListIDs(ctx, repositories.ProductFilter{})
+	if err != nil {
+		return 0, err
+	}
+	for start := 0; start < len(ids); start += searchBatchSize {
+		batch := ids[start:min(start+searchBatchSize, len(ids))]
+		err := i.store.Transaction(ctx, func(tx repositories.Store) error {
+			return i.Index(ctx, tx, batch...)
+		})
+		if err != nil {
+			return start, err
+		}
+	}
+
+	if _, err := i.store.Search().Prune(ctx); err != nil {
+		return len(ids), err
+	}
+	return len(ids), nil
+}
+
+// searchDocument copies the searchable text of a product with its supplier
+// loaded
+func searchDocument(product models.Product, categories []models.Category) models.ProductSearch {
+	document := models.ProductSearch{
+		ProductID:   product.ID,
+		SKU:         product.SKU,
+		Name:        product.Name,
+		Description: product.Description,
+		Supplier:    product.Supplier.Name,
+	}
+	if product.CategoryID != nil {
+		document.Category = categoryPath(categories, *product.CategoryID)
+	}
+	document.Terms = strings.Join([]string{document.SKU, document.Name, document.Supplier, document.Category}, " ")
+	return document
+}
+
+// categoryPath names a category with its ancestors, e.g. "Electronics >
+// Phones"
+func categoryPath(categories []models.Category, id uint) string {
+	var names []string
+	for steps := 0; steps <= len(categories); steps++ {
+		category := findCategory(categories, id)
+		if category == nil {
+			break
+		}
+		names = append([]string{category.Name}, names...)
+		if category.ParentID == nil {
+			break
+		}
+		id = *category.ParentID
+	}
+	return strings.Join(names, " > ")
+}
+
+// searchWords splits query into lower case words of letters and digits, the
+// way the FULLTEXT parser does, which also drops the boolean mode operators
+func searchWords(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// reindexProducts refreshes the documents of the products matching filter,
+// e.g. after the name of their supplier changed
+func reindexProducts(ctx context.Context, tx repositories.Store, search SearchIndex, filter repositories.ProductFilter) error {
+	ids, err := tx.Products().ListIDs(ctx, filter)
+	if err != nil {
+		return err
+	}
+	return search.Index(ctx, tx, ids...)
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+)
+
+// matchStore is a store whose search repository answers every query with
+// fixed matches, standing in for the MySQL FULLTEXT ranking
+type matchStore struct {
+	repositories.Store
+	search *fakeSearchRepository
+}
+
+func (s matchStore) Search() repositories.SearchRepository {
+	return s.search
+}
+
+type fakeSearchRepository struct {
+	repositories.SearchRepository
+	matches []repositories.SearchMatch
+	words   string
+	text    string
+}
+
+func (r *fakeSearchRepository) Match(ctx context.Context, words string, text string, limit int) ([]repositories.SearchMatch, error) {
+	r.words, r.text = words, text
+	return r.matches, nil
+}
+
+func TestSearchWords(t *testing.T) {
+	words := searchWords(`Cable-USB "3.0" +fast* Größe`)
+	want := []string{"cable", "usb", "3", "0", "fast", "größe"}
+	if !reflect.DeepEqual(words, want) {
+		t.Fatalf("searchWords = %q, want %q", words, want)
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	repository := &fakeSearchRepository{}
+	index := NewFullTextIndex(matchStore{search: repository})
+
+	if _, err := index.Search(context.Background(), "usb C cable", 10); err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	// Short words only take part in the bigram query
+	if repository.words != "+usb +cable*" {
+		t.Errorf("words = %q, want %q", repository.words, "+usb +cable*")
+	}
+	if repository.text != "usb c cable" {
+		t.Errorf("text = %q, want %q", repository.text, "usb c cable")
+	}
+
+	repository.words = "unchanged"
+	ids, err := index.Search(context.Background(), " -*+ ", 10)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(ids) != 0 || repository.words != "unchanged" {
+		t.Errorf("query without words: ids %v, words %q, want no lookup", ids, repository.words)
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	tests := []struct {
+		name    string
+		matches []repositories.SearchMatch
+		want    []uint
+	}{
+		{
+			name: "word matches in relevance order without typo matches",
+			matches: []repositories.SearchMatch{
+				{ProductID: 3, Words: 4, Grams: 2},
+				{ProductID: 1, Words: 1, Grams: 9},
+				{ProductID: 2, Words: 0, Grams: 8},
+			},
+			want: []uint{3, 1},
+		},
+		{
+			name: "close typo matches when no word matches",
+			matches: []repositories.SearchMatch{
+				{ProductID: 5, Grams: 10},
+				{ProductID: 6, Grams: 5},
+				{ProductID: 7, Grams: 4.9},
+			},
+			want: []uint{5, 6},
+		},
+		{
+			name: "nothing found",
+			want: []uint{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index := NewFullTextIndex(matchStore{search: &fakeSearchRepository{matches: tt.matches}})
+			ids, err := index.Search(context.Background(), "cable", 10)
+			if err != nil {
+				t.Fatalf("search: %v", err)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("ids = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchDocument(t *testing.T) {
+	power := uint(1)
+	cables := uint(2)
+	categories := []models.Category{
+		{ID: power, Name: "Power"},
+		{ID: cables, Name: "Cables", ParentID: &power},
+	}
+	product := models.Product{
+		ID:          7,
+		SKU:         "PWR-01",
+		Name:        "Power cord",
+		Description: "Two meters",
+		CategoryID:  &cables,
+		Supplier:    models.Supplier{Name: "Acme"},
+	}
+
+	document := searchDocument(product, categories)
+	if document.Category != "Power > Cables" {
+		t.Errorf("category = %q, want %q", document.Category, "Power > Cables")
+	}
+	// The description only goes into the word indexes
+	if want := "PWR-01 Power cord Acme Power > Cables"; document.Terms != want {
+		t.Errorf("terms = %q, want %q", document.Terms, want)
+	}
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Attachment is a file sent along with a Message
+type Attachment struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// Message is a notification with optional attachments
+type Message struct {
+	To          []string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Sender delivers messages. SMTPSender sends them as email and
+// DirectorySender writes them to disk, which is handy for local testing.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewEmailSenderFromEnv returns an SMTP sender when SMTP_HOST is set and a
+// directory sink in MAIL_OUTBOX_PATH otherwise
+func NewEmailSenderFromEnv() Sender {
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		return &SMTPSender{
+			Host:     host,
+			Port:     getEnv("SMTP_PORT", "587"),
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     getEnv("SMTP_FROM", "inventory@localhost"),
+			Timeout:  time.Duration(getEnvInt("SMTP_TIMEOUT_SECONDS", 30)) * time.Second,
+		}
+	}
+	return &DirectorySender{Dir: getEnv("MAIL_OUTBOX_PATH", "./mail_outbox")}
+}
+
+// defaultSMTPTimeout bounds a delivery when SMTPSender.Timeout is not set
+const defaultSMTPTimeout = 30 * time.Second
+
+type SMTPSender struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	// Timeout bounds the whole delivery, from dialing to QUIT. A context
+	// deadline that comes first wins.
+	Timeout time.Duration
+}
+
+// Send delivers msg like smtp.SendMail, upgrading to TLS when the server
+// offers STARTTLS, but gives up when ctx is done or Timeout has passed
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	if len(msg.To) == 0 {
+		return fmt.Errorf("message has no recipients")
+	}
+
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = defaultSMTPTimeout
+	}
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.Host, s.Port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	// Cancelling ctx unblocks a read or write in progress
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.Host}); err != nil {
+			return err
+		}
+	}
+	if s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(s.From); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buildMIME(s.From, msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMIME encodes msg as a multipart/mixed email
+func buildMIME(from string, msg Message) []byte {
+	var buf bytes.Buffer
+	boundary := fmt.Sprintf("inventory-%d", time.Now().UnixNano())
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", boundary)
+
+	fmt.Fprintf(&buf, "--%s\r\n", boundary)
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	buf.WriteString(msg.Body)
+	buf.WriteString("\r\n")
+
+	for _, attachment := range msg.Attachments {
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		fmt.Fprintf(&buf, "Content-Type: %s\r\n", attachment.ContentType)
+		buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+		fmt.Fprintf(&buf, "Content-Disposition: attachment; filename=%q\r\n\r\n", attachment.FileName)
+
+		encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+		for len(encoded) > 76 {
+			buf.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		buf.WriteString(encoded + "\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+
+	return buf.Bytes()
+}
+
+// DirectorySender writes every message to its own folder under Dir, with the
+// headers and body in message.txt and the attachments next to it
+type DirectorySender struct {
+	Dir string
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func (s *DirectorySender) Send(ctx context.Context, msg Message) error {
+	name := fmt.Sprintf("%s_%s", time.Now().Format("20060102_150405.000"), unsafeFileChars.ReplaceAllString(msg.Subject, "_"))
+	dir := filepath.Join(s.Dir, truncate(name, 100))
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", strings.Join(msg.To, ", "), msg.Subject, msg.Body)
+	if err := os.WriteFile(filepath.Join(dir, "message.txt"), []byte(text), 0o644); err != nil {
+		return err
+	}
+
+	for _, attachment := range msg.Attachments {
+		fileName := unsafeFileChars.ReplaceAllString(filepath.Base(attachment.FileName), "_")
+		if err := os.WriteFile(filepath.Join(dir, fileName), attachment.Data, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
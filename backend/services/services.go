@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"net/http"
+	"time"
+)
+
+// Services bundles the domain services so they can be wired once at startup
+// and handed to the HTTP layer, a CLI or a background job.
+type Services struct {
+	Products      *ProductService
+	Stock         *StockService
+	Suppliers     *SupplierService
+	Categories    *CategoryService
+	Exports       *ExportJobService
+	Reports       *ReportService
+	Schedules     *ReportScheduleService
+	Snapshots     *StockSnapshotService
+	Reconciler    *ReconciliationService
+	Dashboard     *DashboardService
+	Forecasts     *ForecastService
+	Replenishment *ReplenishmentService
+	Alerts        *StockAlertService
+	Webhooks      *WebhookService
+	Outbox        *OutboxService
+	Stream        *StreamHub
+	Idempotency   *IdempotencyService
+	Search        *FullTextIndex
+}
+
+func NewServices(store repositories.Store) *Services {
+	mailer := NewEmailSenderFromEnv()
+	alerts := NewStockAlertService(store, map[string]AlertNotifier{
+		models.AlertChannelWebhook: &WebhookNotifier{Client: &http.Client{Timeout: 15 * time.Second}},
+		models.AlertChannelEmail:   &EmailNotifier{Sender: mailer},
+	}, StockAlertOptions{
+		Cooldown:    time.Duration(getEnvInt("ALERT_COOLDOWN_MINUTES", 30)) * time.Minute,
+		MaxAttempts: getEnvInt("ALERT_MAX_ATTEMPTS", 6),
+	})
+	webhooks := NewWebhookService(store, WebhookOptions{
+		MaxAttempts: getEnvInt("WEBHOOK_MAX_ATTEMPTS", 10),
+		Timeout:     time.Duration(getEnvInt("WEBHOOK_TIMEOUT_SECONDS", 10)) * time.Second,
+		Retention:   time.Duration(getEnvInt("WEBHOOK_RETENTION_DAYS", 30)) * 24 * time.Hour,
+	})
+	stream := NewStreamHub(time.Duration(getEnvInt("STREAM_HEARTBEAT_SECONDS", 15)) * time.Second)
+	outbox := NewOutboxService(store, append(NewOutboxSinksFromEnv(webhooks), stream), OutboxOptions{
+		Retention: time.Duration(getEnvInt("OUTBOX_RETENTION_DAYS", 7)) * 24 * time.Hour,
+	})
+	search := NewFullTextIndex(store)
+	products := NewProductService(store, alerts, outbox, search)
+	reports := NewReportService(store)
+
+	return &Services{
+		Products:   products,
+		Stock:      NewStockService(store, alerts, outbox),
+		Suppliers:  NewSupplierService(store, outbox, search),
+		Categories: NewCategoryService(store, outbox, search),
+		Exports: NewExportJobService(store, products, ExportJobOptions{
+			Dir:     getEnv("EXPORT_PATH", "./exports"),
+			TTL:     time.Duration(getEnvInt("EXPORT_TTL_HOURS", 24)) * time.Hour,
+			Workers: getEnvInt("EXPORT_WORKERS", 2),
+		}),
+		Reports: reports,
+		Schedules: NewReportScheduleService(store, reports, map[string]Sender{
+			DeliveryEmail: mailer,
+			DeliveryDisk:  &DirectorySender{Dir: getEnv("REPORT_PATH", "./reports")},
+		}),
+		Snapshots:  NewStockSnapshotService(store),
+		Reconciler: NewReconciliationService(store),
+		Dashboard:  NewDashboardService(store),
+		Forecasts: NewForecastService(store, alerts, outbox, ForecastOptions{
+			WindowDays:   getEnvInt("FORECAST_WINDOW_DAYS", 90),
+			ServiceLevel: float64(getEnvInt("FORECAST_SERVICE_LEVEL", 95)) / 100,
+			Interval:     time.Duration(getEnvInt("FORECAST_INTERVAL_HOURS", 168)) * time.Hour,
+		}),
+		Replenishment: NewReplenishmentService(store, ReplenishmentOptions{
+			DemandDays:  getEnvInt("FORECAST_WINDOW_DAYS", 90),
+			HoldingRate: float64(getEnvInt("HOLDING_COST_PERCENT", 25)) / 100,
+		}),
+		Alerts:      alerts,
+		Webhooks:    webhooks,
+		Outbox:      outbox,
+		Stream:      stream,
+		Idempotency: NewIdempotencyService(store, time.Duration(getEnvInt("IDEMPOTENCY_TTL_HOURS", 24))*time.Hour),
+		Search:      search,
+	}
+}
+
+// Start launches the background workers. They run until ctx is cancelled.
+func (s *Services) Start(ctx context.Context) {
+	s.Exports.Start(ctx)
+	s.Schedules.Start(ctx)
+	s.Snapshots.Start(ctx)
+	s.Reconciler.Start(ctx)
+	s.Forecasts.Start(ctx)
+	s.Alerts.Start(ctx)
+	s.Webhooks.Start(ctx)
+	s.Outbox.Start(ctx)
+	s.Idempotency.Start(ctx)
+	s.Search.Start(ctx)
+}
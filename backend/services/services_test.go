@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// newTestStore returns a store on a fresh database from newTestDB
+func newTestStore(tb testing.TB) repositories.Store {
+	return repositories.NewStore(newTestDB(tb))
+}
+
+// newTestDB opens a private in-memory database with the schema the services
+// need. The FULLTEXT search table is left out, sqlite has no such indexes;
+// tests use nopSearch instead.
+func newTestDB(tb testing.TB) *gorm.DB {
+	tb.Helper()
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(tb.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		tb.Fatalf("open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		tb.Fatalf("open database: %v", err)
+	}
+	// A single connection keeps the memory database alive and serializes
+	// the transactions like the row locks would
+	sqlDB.SetMaxOpenConns(1)
+	tb.Cleanup(func() { sqlDB.Close() })
+
+	tables := []interface{}{
+		&models.User{},
+		&models.Supplier{},
+		&models.Product{},
+		&models.VariantAttribute{},
+		&models.VariantOption{},
+		&models.ProductUnit{},
+		&models.BillOfMaterials{},
+		&models.StockHistory{},
+		&models.ActivityLog{},
+		&models.Category{},
+		&models.AttributeDefinition{},
+		&models.ProductAttribute{},
+		&models.StockAlert{},
+		&models.AlertChannel{},
+		&models.AlertDelivery{},
+		&models.OutboxEvent{},
+		&models.ExportJob{},
+		&models.IdempotencyKey{},
+		&models.ReorderProposal{},
+		&models.StockSnapshot{},
+	}
+	for _, model := range tables {
+		// sqlite has no enum type, the cached schema is patched before the
+		// migration reads it
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			tb.Fatalf("parse %T: %v", model, err)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if strings.HasPrefix(string(field.DataType), "enum(") {
+				field.DataType = schema.String
+			}
+		}
+	}
+	if err := db.AutoMigrate(tables...); err != nil {
+		tb.Fatalf("migrate database: %v", err)
+	}
+
+	return db
+}
+
+// nopEvents drops every event
+type nopEvents struct{}
+
+func (nopEvents) Publish(ctx context.Context, tx repositories.Store, aggregateType string, aggregateID uint, eventType string, data interface{}) error {
+	return nil
+}
+
+// nopSearch is a SearchIndex that indexes nothing
+type nopSearch struct{}
+
+func (nopSearch) Index(ctx context.Context, tx repositories.Store, productIDs ...uint) error {
+	return nil
+}
+
+func (nopSearch) Remove(ctx context.Context, tx repositories.Store, productIDs ...uint) error {
+	return nil
+}
+
+func (nopSearch) Search(ctx context.Context, query string, limit int) ([]uint, error) {
+	return nil, nil
+}
+
+func (nopSearch) Rebuild(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+// newTestServices wires the services under test on store
+func newTestServices(store repositories.Store) (*ProductService, *StockService, *SupplierService) {
+	alerts := NewStockAlertService(store, map[string]AlertNotifier{}, StockAlertOptions{})
+	return NewProductService(store, alerts, nopEvents{}, nopSearch{}),
+		NewStockService(store, alerts, nopEvents{}),
+		NewSupplierService(store, nopEvents{}, nopSearch{})
+}
+
+func createTestSupplier(tb testing.TB, store repositories.Store) *models.Supplier {
+	tb.Helper()
+
+	supplier := models.Supplier{Name: "Acme"}
+	if err := store.Suppliers().Create(context.Background(), &supplier); err != nil {
+		tb.Fatalf("create supplier: %v", err)
+	}
+	return &supplier
+}
+
+func createTestProduct(tb testing.TB, products *ProductService, supplierID uint, sku string, stock float64) *models.Product {
+	tb.Helper()
+
+	product, err := products.Create(context.Background(), 1, ProductInput{
+		SKU:        sku,
+		Name:       "Product " + sku,
+		Price:      10,
+		Stock:      stock,
+		SupplierID: supplierID,
+	})
+	if err != nil {
+		tb.Fatalf("create product %s: %v", sku, err)
+	}
+	return product
+}
+
+// recordedEvents keeps the types of the published events
+type recordedEvents struct {
+	types []string
+}
+
+func (r *recordedEvents) Publish(ctx context.Context, tx repositories.Store, aggregateType string, aggregateID uint, eventType string, data interface{}) error {
+	r.types = append(r.types, eventType)
+	return nil
+}
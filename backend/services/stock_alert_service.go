@@ -0,0 +1,422 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// alertDeliveryInterval is how often pending alert notifications are sent
+const alertDeliveryInterval = 15 * time.Second
+
+// StockAlertOptions configures de-duplication and retries of stock alerts
+type StockAlertOptions struct {
+	// Cooldown delays the recovered notification. A product dropping below
+	// MinStock again within it reopens its alert without a new notification.
+	Cooldown time.Duration
+	// MaxAttempts is how often a notification is tried before giving up
+	MaxAttempts int
+}
+
+// AlertChannelInput holds alert channel fields. On update, empty fields are
+// left untouched.
+type AlertChannelInput struct {
+	Name    string
+	Type    string
+	Target  string
+	Events  []string
+	Enabled *bool
+}
+
+// StockAlertService raises an alert when a product drops below its MinStock,
+// resolves it when the stock recovers and notifies the configured channels
+type StockAlertService struct {
+	store     repositories.Store
+	notifiers map[string]AlertNotifier
+	options   StockAlertOptions
+}
+
+// NewStockAlertService returns a service delivering notifications through
+// notifiers, keyed by channel type
+func NewStockAlertService(store repositories.Store, notifiers map[string]AlertNotifier, options StockAlertOptions) *StockAlertService {
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 6
+	}
+	return &StockAlertService{store: store, notifiers: notifiers, options: options}
+}
+
+// track raises or resolves the alert of product when its stock crossed
+// MinStock. wasLow tells whether the product was below the threshold before
+// the change. It runs inside the transaction changing the stock.
+func (s *StockAlertService) track(ctx context.Context, tx repositories.Store, product *models.Product, wasLow bool) error {
+	// Parents keep their stock on the variants, which are tracked on their own
+	isLow := !product.HasVariants && product.KitType != models.KitVirtual && product.Stock < product.MinStock
+	if isLow == wasLow {
+		return nil
+	}
+
+	active, err := tx.StockAlerts().FindActive(ctx, product.ID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return err
+	}
+
+	now := time.Now()
+	if !isLow {
+		if active == nil {
+			return nil
+		}
+		active.Status = models.StockAlertResolved
+		active.ResolvedAt = &now
+		if err := tx.StockAlerts().Save(ctx, active); err != nil {
+			return err
+		}
+		return s.queue(ctx, tx, active, product, AlertEventRecovered, now.Add(s.options.Cooldown))
+	}
+
+	if active != nil {
+		// Already alerting for this product
+		return nil
+	}
+
+	if s.options.Cooldown > 0 {
+		recent, err := tx.StockAlerts().FindResolvedSince(ctx, product.ID, now.Add(-s.options.Cooldown))
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return err
+		}
+		if recent != nil {
+			// The recovery was not announced yet, so withdraw it and carry on
+			// with the alert the channels already know about
+			cancelled, err := tx.AlertDeliveries().CancelPending(ctx, recent.ID, AlertEventRecovered)
+			if err != nil {
+				return err
+			}
+			if cancelled > 0 {
+				recent.Status = models.StockAlertOpen
+				recent.ResolvedAt = nil
+				recent.Occurrences++
+				return tx.StockAlerts().Save(ctx, recent)
+			}
+		}
+	}
+
+	alert := models.StockAlert{
+		ProductID:   product.ID,
+		Status:      models.StockAlertOpen,
+		Stock:       product.Stock,
+		MinStock:    product.MinStock,
+		Occurrences: 1,
+		TriggeredAt: now,
+	}
+	if err := tx.StockAlerts().Create(ctx, &alert); err != nil {
+		return err
+	}
+	return s.queue(ctx, tx, &alert, product, AlertEventLowStock, now)
+}
+
+// queue creates a pending delivery of event for every enabled channel
+// subscribed to it
+func (s *StockAlertService) queue(ctx context.Context, tx repositories.Store, alert *models.StockAlert, product *models.Product, event string, at time.Time) error {
+	channels, err := tx.AlertChannels().ListEnabled(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(AlertEvent{
+		Event:      event,
+		AlertID:    alert.ID,
+		ProductID:  product.ID,
+		SKU:        product.SKU,
+		Name:       product.Name,
+		Stock:      product.Stock,
+		MinStock:   product.MinStock,
+		OccurredAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	var deliveries []models.AlertDelivery
+	for _, channel := range channels {
+		if !subscribed(channel, event) {
+			continue
+		}
+		deliveries = append(deliveries, models.AlertDelivery{
+			AlertID:       alert.ID,
+			ChannelID:     channel.ID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        models.AlertDeliveryPending,
+			NextAttemptAt: at,
+		})
+	}
+	return tx.AlertDeliveries().Create(ctx, deliveries)
+}
+
+// subscribed reports whether channel wants event. A channel without events
+// receives all of them.
+func subscribed(channel models.AlertChannel, event string) bool {
+	if strings.TrimSpace(channel.Events) == "" {
+		return true
+	}
+	for _, e := range strings.Split(channel.Events, ",") {
+		if strings.TrimSpace(e) == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Start sends pending notifications until ctx is cancelled
+func (s *StockAlertService) Start(ctx context.Context) {
+	go every(ctx, alertDeliveryInterval, s.deliverDue)
+}
+
+func (s *StockAlertService) deliverDue(ctx context.Context) {
+	deliveries, err := s.store.AlertDeliveries().ListDue(ctx, time.Now(), 50)
+	if err != nil {
+		log.Printf("Failed to list alert deliveries: %v", err)
+		return
+	}
+
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		s.deliver(ctx, &deliveries[i])
+	}
+}
+
+// deliver makes one attempt at a notification and schedules a retry with
+// exponential backoff when it fails
+func (s *StockAlertService) deliver(ctx context.Context, delivery *models.AlertDelivery) {
+	err := s.send(ctx, delivery)
+
+	now := time.Now()
+	delivery.Attempts++
+	switch {
+	case err == nil:
+		delivery.Status = models.AlertDeliverySent
+		delivery.SentAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= s.options.MaxAttempts:
+		delivery.Status = models.AlertDeliveryFailed
+		delivery.LastError = err.Error()
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(retryBackoff(delivery.Attempts))
+	}
+
+	if err := s.store.AlertDeliveries().Save(ctx, delivery); err != nil {
+		log.Printf("Failed to update alert delivery %d: %v", delivery.ID, err)
+	}
+}
+
+func (s *StockAlertService) send(ctx context.Context, delivery *models.AlertDelivery) error {
+	channel, err := s.store.AlertChannels().FindByID(ctx, delivery.ChannelID)
+	if err != nil {
+		return fmt.Errorf("channel unavailable: %w", err)
+	}
+	if !channel.Enabled {
+		return fmt.Errorf("channel is disabled")
+	}
+
+	var event AlertEvent
+	if err := json.Unmarshal([]byte(delivery.Payload), &event); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+
+	return s.notify(ctx, *channel, event)
+}
+
+func (s *StockAlertService) notify(ctx context.Context, channel models.AlertChannel, event AlertEvent) error {
+	notifier, ok := s.notifiers[channel.Type]
+	if !ok {
+		return fmt.Errorf("no notifier for channel type %q", channel.Type)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	return notifier.Notify(ctx, channel, event)
+}
+
+// retryBackoff doubles the wait after every failed attempt, from one minute
+// up to an hour
+func retryBackoff(attempts int) time.Duration {
+	backoff := time.Minute << (attempts - 1)
+	if backoff > time.Hour || backoff <= 0 {
+		return time.Hour
+	}
+	return backoff
+}
+
+// ListAlerts returns alerts newest first, limited to 200
+func (s *StockAlertService) ListAlerts(ctx context.Context, filter repositories.StockAlertFilter) ([]models.StockAlert, error) {
+	switch filter.Status {
+	case "", models.StockAlertOpen, models.StockAlertAcknowledged, models.StockAlertResolved:
+	default:
+		return nil, invalid("Status must be 'open', 'acknowledged' or 'resolved'")
+	}
+	if filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+	return s.store.StockAlerts().List(ctx, filter)
+}
+
+// GetAlert returns an alert with its notification history
+func (s *StockAlertService) GetAlert(ctx context.Context, id uint) (*models.StockAlert, error) {
+	alert, err := s.store.StockAlerts().FindByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrStockAlertNotFound)
+	}
+	return alert, nil
+}
+
+// Acknowledge marks an open alert as seen. It stays active until the stock
+// recovers, so no new alert is raised for the product meanwhile.
+func (s *StockAlertService) Acknowledge(ctx context.Context, userID uint, id uint) (*models.StockAlert, error) {
+	var alert *models.StockAlert
+	err := s.store.Transaction(ctx, func(tx repositories.Store) error {
+		var err error
+		alert, err = tx.StockAlerts().FindByID(ctx, id)
+		if err != nil {
+			return notFound(err, ErrStockAlertNotFound)
+		}
+
+		switch alert.Status {
+		case models.StockAlertAcknowledged:
+			return nil
+		case models.StockAlertResolved:
+			return invalid("Alert is already resolved")
+		}
+
+		now := time.Now()
+		alert.Status = models.StockAlertAcknowledged
+		alert.AcknowledgedBy = &userID
+		alert.AcknowledgedAt = &now
+		if err := tx.StockAlerts().Save(ctx, alert); err != nil {
+			return err
+		}
+
+		logActivity(ctx, tx, userID, "ACKNOWLEDGE", "StockAlert", alert.ID, fmt.Sprintf("Acknowledged low stock alert for %s (%s)", alert.Product.Name, alert.Product.SKU))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return alert, nil
+}
+
+func (s *StockAlertService) ListChannels(ctx context.Context) ([]models.AlertChannel, error) {
+	return s.store.AlertChannels().List(ctx)
+}
+
+func (s *StockAlertService) CreateChannel(ctx context.Context, input AlertChannelInput) (*models.AlertChannel, error) {
+	channel := models.AlertChannel{Enabled: true}
+	if err := s.applyChannel(&channel, input); err != nil {
+		return nil, err
+	}
+	if err := s.store.AlertChannels().Create(ctx, &channel); err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+func (s *StockAlertService) UpdateChannel(ctx context.Context, id uint, input AlertChannelInput) (*models.AlertChannel, error) {
+	channel, err := s.store.AlertChannels().FindByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrAlertChannelNotFound)
+	}
+	if err := s.applyChannel(channel, input); err != nil {
+		return nil, err
+	}
+	if err := s.store.AlertChannels().Save(ctx, channel); err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+func (s *StockAlertService) DeleteChannel(ctx context.Context, id uint) error {
+	channel, err := s.store.AlertChannels().FindByID(ctx, id)
+	if err != nil {
+		return notFound(err, ErrAlertChannelNotFound)
+	}
+	return s.store.AlertChannels().Delete(ctx, channel)
+}
+
+// TestChannel sends a test event to a channel right away
+func (s *StockAlertService) TestChannel(ctx context.Context, id uint) error {
+	channel, err := s.store.AlertChannels().FindByID(ctx, id)
+	if err != nil {
+		return notFound(err, ErrAlertChannelNotFound)
+	}
+
+	err = s.notify(ctx, *channel, AlertEvent{
+		Event:      AlertEventTest,
+		SKU:        "TEST-SKU",
+		Name:       "Test product",
+		Stock:      2,
+		MinStock:   10,
+		OccurredAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAlertTestFailed, err)
+	}
+	return nil
+}
+
+// applyChannel copies input onto channel and validates the result
+func (s *StockAlertService) applyChannel(channel *models.AlertChannel, input AlertChannelInput) error {
+	if input.Name != "" {
+		channel.Name = input.Name
+	}
+	if input.Type != "" {
+		channel.Type = input.Type
+	}
+	if input.Target != "" {
+		channel.Target = strings.TrimSpace(input.Target)
+	}
+	if input.Events != nil {
+		for _, event := range input.Events {
+			if event != AlertEventLowStock && event != AlertEventRecovered {
+				return invalid(fmt.Sprintf("Unknown event %q, use 'stock.low' or 'stock.recovered'", event))
+			}
+		}
+		channel.Events = strings.Join(input.Events, ",")
+	}
+	if input.Enabled != nil {
+		channel.Enabled = *input.Enabled
+	}
+
+	if channel.Name == "" || channel.Type == "" || channel.Target == "" {
+		return invalid("Name, type and target are required")
+	}
+	if _, ok := s.notifiers[channel.Type]; !ok {
+		return invalid("Type must be 'webhook' or 'email'")
+	}
+
+	switch channel.Type {
+	case models.AlertChannelWebhook:
+		u, err := url.Parse(channel.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return invalid("Target must be an http or https URL")
+		}
+	case models.AlertChannelEmail:
+		recipients := splitRecipients(channel.Target)
+		if len(recipients) == 0 {
+			return invalid("Target must list at least one email address")
+		}
+		for _, recipient := range recipients {
+			if !strings.Contains(recipient, "@") {
+				return invalid(fmt.Sprintf("Invalid email address %q", recipient))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+)
+
+// StockMovementInput describes a stock in/out request
+type StockMovementInput struct {
+	Type     string // "in" atau "out"
+	Quantity float64
+	Unit     string // Any unit of the product, the purchase unit (in) or sales unit (out) when empty
+	Note     string
+}
+
+// StockMovementResult is the outcome of a recorded stock movement. A
+// movement of a virtual kit has no History of its own, it is recorded as
+// Movements of the components and the stock is the kits available.
+type StockMovementResult struct {
+	Product     *models.Product
+	History     *models.StockHistory
+	Movements   []models.StockHistory
+	StockBefore float64
+	StockAfter  float64
+}
+
+type StockService struct {
+	store  repositories.Store
+	alerts *StockAlertService
+	events EventPublisher
+}
+
+func NewStockService(store repositories.Store, alerts *StockAlertService, events EventPublisher) *StockService {
+	return &StockService{store: store, alerts: alerts, events: events}
+}
+
+// Move applies a stock movement to a product and records it in the stock
+// history. The quantity is converted from the given unit to the base unit of
+// the product, which is what the stock and the history hold. The product row
+// is locked for the duration of the transaction so concurrent movements
+// cannot overdraw the stock. Movements of a virtual kit are applied to its
+// components.
+func (s *StockService) Move(ctx context.Context, userID uint, productID uint, input StockMovementInput) (*StockMovementResult, error) {
+	if input.Type == "" || input.Quantity <= 0 {
+		return nil, invalid("Type and quantity are required")
+	}
+	if input.Type != "in" && input.Type != "out" {
+		return nil, invalid("Type must be 'in' or 'out'")
+	}
+
+	var result StockMovementResult
+
+	err := s.store.Transaction(ctx, func(tx repositories.Store) error {
+		product, err := tx.Products().FindByIDForUpdate(ctx, productID)
+		if err != nil {
+			return notFound(err, ErrProductNotFound)
+		}
+		if product.HasVariants {
+			return invalid("Stock of a product with variants is moved on its variants")
+		}
+
+		units, err := tx.Products().ListUnits(ctx, product.ID)
+		if err != nil {
+			return err
+		}
+		unit := input.Unit
+		if unit == "" {
+			unit = product.PurchaseUnit
+			if input.Type == "out" {
+				unit = product.SalesUnit
+			}
+		}
+		unit, quantity, err := toBaseQuantity(product, units, unit, input.Quantity)
+		if err != nil {
+			return err
+		}
+
+		if product.KitType == models.KitVirtual {
+			return s.moveVirtualKit(ctx, tx, userID, product, input.Type, quantity, input.Note, &result)
+		}
+
+		history, err := s.applyMovement(ctx, tx, product, models.StockHistory{
+			Type:         input.Type,
+			Quantity:     quantity,
+			Unit:         unit,
+			UnitQuantity: roundQuantity(input.Quantity),
+			Note:         input.Note,
+		})
+		if err != nil {
+			return err
+		}
+
+		logActivity(ctx, tx, userID, "UPDATE", "Product", product.ID, fmt.Sprintf("Updated stock for %s (%s): %s %s %s (New Stock: %s %s)", product.Name, product.SKU, input.Type, formatQuantity(input.Quantity), unit, formatQuantity(history.StockAfter), product.BaseUnit))
+
+		result = StockMovementResult{
+			Product:     product,
+			History:     history,
+			StockBefore: history.StockBefore,
+			StockAfter:  history.StockAfter,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// applyMovement records movement on a product locked by the caller, updating
+// its stock, the stock alerts and the events. movement needs Type, Quantity
+// and Note; an out movement beyond the stock fails with ErrInsufficientStock.
+func (s *StockService) applyMovement(ctx context.Context, tx repositories.Store, product *models.Product, movement models.StockHistory) (*models.StockHistory, error) {
+	movement.ProductID = product.ID
+	movement.StockBefore = product.Stock
+	if movement.Unit == "" {
+		movement.Unit, movement.UnitQuantity = product.BaseUnit, movement.Quantity
+	}
+
+	wasLow := product.Stock < product.MinStock
+	movement.StockAfter = roundQuantity(product.Stock + movement.Quantity)
+	if movement.Type == "out" {
+		if product.Stock < movement.Quantity {
+			return nil, ErrInsufficientStock
+		}
+		movement.StockAfter = roundQuantity(product.Stock - movement.Quantity)
+	}
+
+	product.Stock = movement.StockAfter
+	if err := tx.Products().Save(ctx, product); err != nil {
+		return nil, err
+	}
+	if err := tx.StockHistory().Create(ctx, &movement); err != nil {
+		return nil, err
+	}
+
+	if err := s.alerts.track(ctx, tx, product, wasLow); err != nil {
+		return nil, err
+	}
+	if err := s.events.Publish(ctx, tx, AggregateProduct, product.ID, EventStockChanged, stockChanged(product, &movement)); err != nil {
+		return nil, err
+	}
+	return &movement, nil
+}
+
+// History returns a product together with its stock movements, newest first
+func (s *StockService) History(ctx context.Context, productID uint) (*models.Product, []models.StockHistory, error) {
+	product, err := s.store.Products().FindByID(ctx, productID)
+	if err != nil {
+		return nil, nil, notFound(err, ErrProductNotFound)
+	}
+
+	history, err := s.store.StockHistory().ListByProduct(ctx, productID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return product, history, nil
+}
+
+// adjustStock moves product to target stock by recording an in or out
+// movement for the difference. It returns nil when the stock is unchanged.
+// The caller is responsible for saving the product.
+func adjustStock(ctx context.Context, tx repositories.Store, product *models.Product, target float64, note string) (*models.StockHistory, error) {
+	diff := roundQuantity(target - product.Stock)
+	if diff == 0 {
+		return nil, nil
+	}
+
+	history := models.StockHistory{
+		ProductID:   product.ID,
+		Type:        "in",
+		Quantity:    diff,
+		Unit:        product.BaseUnit,
+		Note:        note,
+		StockBefore: product.Stock,
+		StockAfter:  target,
+	}
+	if diff < 0 {
+		history.Type = "out"
+		history.Quantity = -diff
+	}
+	history.UnitQuantity = history.Quantity
+
+	if err := tx.StockHistory().Create(ctx, &history); err != nil {
+		return nil, err
+	}
+
+	product.Stock = target
+	return &history, nil
+}
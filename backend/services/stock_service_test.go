@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStockMoveInsufficientStock(t *testing.T) {
+	store := newTestStore(t)
+	products, stock, _ := newTestServices(store)
+	supplier := createTestSupplier(t, store)
+	product := createTestProduct(t, products, supplier.ID, "SKU-1", 5)
+
+	_, err := stock.Move(context.Background(), 1, product.ID, StockMovementInput{Type: "out", Quantity: 6})
+	if !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("Move out 6 of 5: got %v, want ErrInsufficientStock", err)
+	}
+
+	saved, err := store.Products().FindByID(context.Background(), product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Stock != 5 {
+		t.Errorf("stock after failed move = %v, want 5", saved.Stock)
+	}
+}
+
+func TestStockMove(t *testing.T) {
+	store := newTestStore(t)
+	products, stock, _ := newTestServices(store)
+	supplier := createTestSupplier(t, store)
+	product := createTestProduct(t, products, supplier.ID, "SKU-1", 5)
+
+	result, err := stock.Move(context.Background(), 1, product.ID, StockMovementInput{Type: "out", Quantity: 5})
+	if err != nil {
+		t.Fatalf("Move out 5 of 5: %v", err)
+	}
+	if result.StockBefore != 5 || result.StockAfter != 0 {
+		t.Errorf("stock %v -> %v, want 5 -> 0", result.StockBefore, result.StockAfter)
+	}
+}
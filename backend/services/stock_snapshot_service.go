@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"inventory-backend/repositories"
+	"log"
+	"time"
+)
+
+// snapshotCheckInterval is how often the snapshot job checks for a missing
+// nightly snapshot
+const snapshotCheckInterval = time.Hour
+
+// StockSnapshotService stores the stock of every product at midnight so
+// point-in-time stock lookups stay fast as the movement history grows
+type StockSnapshotService struct {
+	store repositories.Store
+}
+
+func NewStockSnapshotService(store repositories.Store) *StockSnapshotService {
+	return &StockSnapshotService{store: store}
+}
+
+// Snapshot stores the stock of every product at the given time
+func (s *StockSnapshotService) Snapshot(ctx context.Context, at time.Time) (int64, error) {
+	if at.After(time.Now()) {
+		return 0, invalid("Snapshot time cannot be in the future")
+	}
+	return s.store.StockSnapshots().CreateSnapshot(ctx, at)
+}
+
+// Start takes the snapshot for the most recent midnight whenever it is
+// missing, checking every hour until ctx is cancelled
+func (s *StockSnapshotService) Start(ctx context.Context) {
+	go every(ctx, snapshotCheckInterval, s.snapshotLastMidnight)
+}
+
+func (s *StockSnapshotService) snapshotLastMidnight(ctx context.Context) {
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	exists, err := s.store.StockSnapshots().HasSnapshot(ctx, midnight)
+	if err != nil {
+		log.Printf("Failed to check stock snapshot: %v", err)
+		return
+	}
+	if exists {
+		return
+	}
+
+	count, err := s.store.StockSnapshots().CreateSnapshot(ctx, midnight)
+	if err != nil {
+		log.Printf("Failed to create stock snapshot: %v", err)
+		return
+	}
+	log.Printf("📸 Stock snapshot for %s created (%d products)", midnight.Format("2006-01-02"), count)
+}
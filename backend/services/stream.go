@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamBuffer is how many events a client may fall behind before it is
+// disconnected
+const streamBuffer = 64
+
+// StreamFilter selects the events a stream client receives. Empty fields
+// match everything.
+type StreamFilter struct {
+	Events     []string
+	ProductIDs []uint
+}
+
+func (f StreamFilter) matches(msg OutboxMessage) bool {
+	if len(f.Events) > 0 && !containsString(f.Events, msg.EventType) {
+		return false
+	}
+	if len(f.ProductIDs) > 0 {
+		if msg.AggregateType != AggregateProduct {
+			return false
+		}
+		for _, id := range f.ProductIDs {
+			if id == msg.AggregateID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// StreamSubscription is one connected client. Events is closed when the
+// client is unsubscribed or fell too far behind.
+type StreamSubscription struct {
+	Events <-chan OutboxMessage
+
+	events chan OutboxMessage
+	filter StreamFilter
+}
+
+// StreamHub fans committed events out to the connected stream clients. It is
+// an outbox sink, so clients only see changes that were committed.
+type StreamHub struct {
+	// Heartbeat is how often idle connections are pinged
+	Heartbeat time.Duration
+
+	mu          sync.Mutex
+	subscribers map[*StreamSubscription]struct{}
+}
+
+func NewStreamHub(heartbeat time.Duration) *StreamHub {
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+	return &StreamHub{Heartbeat: heartbeat, subscribers: map[*StreamSubscription]struct{}{}}
+}
+
+// Subscribe registers a client. Unsubscribe must be called when it
+// disconnects.
+func (h *StreamHub) Subscribe(filter StreamFilter) (*StreamSubscription, error) {
+	for _, event := range filter.Events {
+		if !IsEventType(event) {
+			return nil, invalid(fmt.Sprintf("Unknown event %q, use one of %s", event, strings.Join(EventTypes, ", ")))
+		}
+	}
+
+	events := make(chan OutboxMessage, streamBuffer)
+	sub := &StreamSubscription{Events: events, events: events, filter: filter}
+
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub, nil
+}
+
+func (h *StreamHub) Unsubscribe(sub *StreamSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(sub)
+}
+
+// remove drops a subscriber, h.mu must be held
+func (h *StreamHub) remove(sub *StreamSubscription) {
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Clients returns the number of connected clients
+func (h *StreamHub) Clients() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+func (h *StreamHub) Name() string {
+	return "stream"
+}
+
+// Publish hands the event to every matching client. It never blocks the
+// relay: a client whose buffer is full is disconnected and has to reconnect
+// and refetch.
+func (h *StreamHub) Publish(ctx context.Context, msg OutboxMessage) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers {
+		if !sub.filter.matches(msg) {
+			continue
+		}
+		select {
+		case sub.events <- msg:
+		default:
+			h.remove(sub)
+		}
+	}
+	return nil
+}
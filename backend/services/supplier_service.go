@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+)
+
+// SupplierInput holds supplier fields. On update, empty fields are left untouched.
+type SupplierInput struct {
+	Name        string
+	ContactName string
+	Phone       string
+	Email       string
+	Address     string
+	// LeadTimeDays is the usual delivery time, used for reorder points
+	LeadTimeDays int
+	// OrderCost is the fixed cost of one purchase order, nil leaves it untouched on update
+	OrderCost *float64
+}
+
+// defaultLeadTimeDays is used for suppliers created without a lead time
+const defaultLeadTimeDays = 7
+
+type SupplierService struct {
+	store  repositories.Store
+	events EventPublisher
+	search SearchIndex
+}
+
+func NewSupplierService(store repositories.Store, events EventPublisher, search SearchIndex) *SupplierService {
+	return &SupplierService{store: store, events: events, search: search}
+}
+
+func (s *SupplierService) List(ctx context.Context) ([]models.Supplier, error) {
+	return s.store.Suppliers().List(ctx)
+}
+
+func (s *SupplierService) Get(ctx context.Context, id uint) (*models.Supplier, error) {
+	supplier, err := s.store.Suppliers().FindWithProducts(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrSupplierNotFound)
+	}
+	return supplier, nil
+}
+
+func (s *SupplierService) Create(ctx context.Context, input SupplierInput) (*models.Supplier, error) {
+	if input.Name == "" {
+		return nil, invalid("Name is required")
+	}
+	if input.LeadTimeDays < 0 {
+		return nil, invalid("Lead time cannot be negative")
+	}
+	if input.LeadTimeDays == 0 {
+		input.LeadTimeDays = defaultLeadTimeDays
+	}
+	if input.OrderCost != nil && *input.OrderCost < 0 {
+		return nil, invalid("Order cost cannot be negative")
+	}
+
+	supplier := models.Supplier{
+		Name:         input.Name,
+		ContactName:  input.ContactName,
+		Phone:        input.Phone,
+		Email:        input.Email,
+		Address:      input.Address,
+		LeadTimeDays: input.LeadTimeDays,
+	}
+	if input.OrderCost != nil {
+		supplier.OrderCost = *input.OrderCost
+	}
+
+	err := s.store.Transaction(ctx, func(tx repositories.Store) error {
+		if err := tx.Suppliers().Create(ctx, &supplier); err != nil {
+			return err
+		}
+		return s.events.Publish(ctx, tx, AggregateSupplier, supplier.ID, EventSupplierCreated, supplier)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &supplier, nil
+}
+
+// Update applies input to a supplier. version is the version the caller last
+// saw; the update fails with ErrVersionMismatch when the supplier changed
+// since, 0 skips the check.
+func (s *SupplierService) Update(ctx context.Context, id uint, version uint, input SupplierInput) (*models.Supplier, error) {
+	supplier, err := s.store.Suppliers().FindByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrSupplierNotFound)
+	}
+	if err := checkVersion(supplier.Version, version); err != nil {
+		return nil, err
+	}
+
+	renamed := input.Name != "" && input.Name != supplier.Name
+	if input.Name != "" {
+		supplier.Name = input.Name
+	}
+	if input.ContactName != "" {
+		supplier.ContactName = input.ContactName
+	}
+	if input.Phone != "" {
+		supplier.Phone = input.Phone
+	}
+	if input.Email != "" {
+		supplier.Email = input.Email
+	}
+	if input.Address != "" {
+		supplier.Address = input.Address
+	}
+	if input.LeadTimeDays < 0 {
+		return nil, invalid("Lead time cannot be negative")
+	}
+	if input.LeadTimeDays != 0 {
+		supplier.LeadTimeDays = input.LeadTimeDays
+	}
+	if input.OrderCost != nil {
+		if *input.OrderCost < 0 {
+			return nil, invalid("Order cost cannot be negative")
+		}
+		supplier.OrderCost = *input.OrderCost
+	}
+
+	err = s.store.Transaction(ctx, func(tx repositories.Store) error {
+		if err := tx.Suppliers().Save(ctx, supplier); err != nil {
+			return conflict(err)
+		}
+		// Product search covers supplier names
+		if renamed {
+			if err := reindexProducts(ctx, tx, s.search, repositories.ProductFilter{SupplierID: supplier.ID}); err != nil {
+				return err
+			}
+		}
+		return s.events.Publish(ctx, tx, AggregateSupplier, supplier.ID, EventSupplierUpdated, supplier)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return supplier, nil
+}
+
+// Delete removes a supplier that no longer has products. version works as in
+// Update.
+func (s *SupplierService) Delete(ctx context.Context, id uint, version uint) error {
+	return s.store.Transaction(ctx, func(tx repositories.Store) error {
+		supplier, err := tx.Suppliers().FindByID(ctx, id)
+		if err != nil {
+			return notFound(err, ErrSupplierNotFound)
+		}
+		if err := checkVersion(supplier.Version, version); err != nil {
+			return err
+		}
+
+		productCount, err := tx.Products().CountBySupplier(ctx, id)
+		if err != nil {
+			return err
+		}
+		if productCount > 0 {
+			return ErrSupplierInUse
+		}
+
+		if err := tx.Suppliers().Delete(ctx, supplier); err != nil {
+			return conflict(err)
+		}
+		return s.events.Publish(ctx, tx, AggregateSupplier, supplier.ID, EventSupplierDeleted, DeletedEntity{ID: supplier.ID, Name: supplier.Name})
+	})
+}
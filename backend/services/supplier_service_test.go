@@ -0,0 +1,26 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSupplierDeleteInUse(t *testing.T) {
+	store := newTestStore(t)
+	products, _, suppliers := newTestServices(store)
+	supplier := createTestSupplier(t, store)
+	product := createTestProduct(t, products, supplier.ID, "SKU-1", 0)
+
+	err := suppliers.Delete(context.Background(), supplier.ID, 0)
+	if !errors.Is(err, ErrSupplierInUse) {
+		t.Fatalf("Delete of a supplier with products: got %v, want ErrSupplierInUse", err)
+	}
+
+	if _, err := products.Delete(context.Background(), 1, product.ID, 0); err != nil {
+		t.Fatalf("Delete product: %v", err)
+	}
+	if err := suppliers.Delete(context.Background(), supplier.ID, 0); err != nil {
+		t.Errorf("Delete of an unused supplier: %v", err)
+	}
+}
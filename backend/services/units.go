@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultBaseUnit is the base unit of products counted in pieces
+	defaultBaseUnit = "pcs"
+	// quantityScale is the precision of stored quantities, three decimals
+	quantityScale = 1000
+	// factorScale is the precision of unit conversion factors, six decimals
+	factorScale = 1000000
+	// maxProductUnits bounds the alternative units of one product
+	maxProductUnits = 10
+)
+
+// ProductUnitInput defines an alternative unit as the number of base units it
+// holds, e.g. carton with factor 24
+type ProductUnitInput struct {
+	Name   string
+	Factor float64
+}
+
+// ProductUnitsInput replaces the units of a product
+type ProductUnitsInput struct {
+	BaseUnit     string
+	Fractional   bool
+	PurchaseUnit string // Empty for the base unit
+	SalesUnit    string // Empty for the base unit
+	Units        []ProductUnitInput
+}
+
+// roundQuantity rounds q to the stored precision, dropping float noise from
+// conversions
+func roundQuantity(q float64) float64 {
+	return math.Round(q*quantityScale) / quantityScale
+}
+
+// ceilQuantity rounds q up to the smallest quantity the product can hold,
+// whole units unless it is fractional
+func ceilQuantity(product *models.Product, q float64) float64 {
+	if product.Fractional {
+		return math.Ceil(math.Round(q*quantityScale*1000)/1000) / quantityScale
+	}
+	return math.Ceil(roundQuantity(q))
+}
+
+// formatQuantity renders q without trailing zeros, e.g. 24 or 1.25
+func formatQuantity(q float64) string {
+	return strconv.FormatFloat(roundQuantity(q), 'f', -1, 64)
+}
+
+// checkQuantity rejects fractional quantities on products counted in whole
+// units
+func checkQuantity(product *models.Product, field string, q float64) error {
+	if !product.Fractional && q != math.Trunc(q) {
+		return invalid(fmt.Sprintf("%s must be a whole number of %s", field, product.BaseUnit))
+	}
+	return nil
+}
+
+// checkQuantities validates the stored quantities of a product against its
+// unit settings
+func checkQuantities(product *models.Product) error {
+	fields := []struct {
+		name  string
+		value float64
+	}{
+		{"Stock", product.Stock},
+		{"Min stock", product.MinStock},
+		{"Max stock", product.MaxStock},
+		{"Min order quantity", product.MinOrderQty},
+		{"Pack size", product.PackSize},
+	}
+	for _, field := range fields {
+		if err := checkQuantity(product, field.name, field.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unitFactor resolves unit among the base unit and units of product. An
+// empty unit is the base unit. It returns the unit name as defined and the
+// base units in one of it.
+func unitFactor(product *models.Product, units []models.ProductUnit, unit string) (string, float64, error) {
+	unit = strings.TrimSpace(unit)
+	if unit == "" || strings.EqualFold(unit, product.BaseUnit) {
+		return product.BaseUnit, 1, nil
+	}
+	for _, u := range units {
+		if strings.EqualFold(u.Name, unit) {
+			return u.Name, u.Factor, nil
+		}
+	}
+
+	names := []string{product.BaseUnit}
+	for _, u := range units {
+		names = append(names, u.Name)
+	}
+	return "", 0, invalid(fmt.Sprintf("Unknown unit %q for %s, use one of %s", unit, product.SKU, strings.Join(names, ", ")))
+}
+
+// toBaseQuantity converts quantity in unit to the base unit of product
+func toBaseQuantity(product *models.Product, units []models.ProductUnit, unit string, quantity float64) (string, float64, error) {
+	name, factor, err := unitFactor(product, units, unit)
+	if err != nil {
+		return "", 0, err
+	}
+
+	base := roundQuantity(quantity * factor)
+	if base <= 0 {
+		return "", 0, invalid(fmt.Sprintf("%s %s is less than the smallest quantity of %s", formatQuantity(quantity), name, product.BaseUnit))
+	}
+	field := fmt.Sprintf("Quantity %s", formatQuantity(base))
+	if factor != 1 {
+		field = fmt.Sprintf("%s %s (%s %s)", formatQuantity(quantity), name, formatQuantity(base), product.BaseUnit)
+	}
+	if err := checkQuantity(product, field, base); err != nil {
+		return "", 0, err
+	}
+	return name, base, nil
+}
+
+// GetUnits returns a product with its alternative units
+func (s *ProductService) GetUnits(ctx context.Context, id uint) (*models.Product, error) {
+	product, err := s.store.Products().FindByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrProductNotFound)
+	}
+	product.Units, err = s.store.Products().ListUnits(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// SetUnits replaces the base unit, the alternative units and the default
+// purchase and sales units of a product. The variants of a parent share its
+// units, so they are updated along with it and cannot be changed on their
+// own. The base unit can only change while the stock is 0 because every
+// stored quantity is in the base unit.
+func (s *ProductService) SetUnits(ctx context.Context, userID uint, id uint, version uint, input ProductUnitsInput) (*models.Product, error) {
+	input, err := normalizeProductUnits(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var product *models.Product
+	err = s.store.Transaction(ctx, func(tx repositories.Store) error {
+		product, err = tx.Products().FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return notFound(err, ErrProductNotFound)
+		}
+		if err := checkVersion(product.Version, version); err != nil {
+			return err
+		}
+		if product.ParentID != nil {
+			return invalid("The units of a variant are set on its parent")
+		}
+
+		products := []*models.Product{product}
+		if product.HasVariants {
+			variants, err := tx.Products().ListVariants(ctx, product.ID)
+			if err != nil {
+				return err
+			}
+			for i := range variants {
+				products = append(products, &variants[i])
+			}
+		}
+
+		for _, p := range products {
+			if err := s.applyUnits(ctx, tx, p, input); err != nil {
+				return err
+			}
+		}
+
+		logActivity(ctx, tx, userID, "UPDATE", "Product", product.ID, fmt.Sprintf("Set units of %s (%s): base %s, %d alternative units", product.Name, product.SKU, product.BaseUnit, len(product.Units)))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// applyUnits stores input as the units of one product
+func (s *ProductService) applyUnits(ctx context.Context, tx repositories.Store, product *models.Product, input ProductUnitsInput) error {
+	if product.BaseUnit != input.BaseUnit && product.Stock != 0 {
+		return invalid(fmt.Sprintf("%s still holds %s %s, the base unit can only change at 0 stock", product.SKU, formatQuantity(product.Stock), product.BaseUnit))
+	}
+
+	product.BaseUnit = input.BaseUnit
+	product.Fractional = input.Fractional
+	product.PurchaseUnit = input.PurchaseUnit
+	product.SalesUnit = input.SalesUnit
+	if err := checkQuantities(product); err != nil {
+		return invalid(fmt.Sprintf("%s: %s", product.SKU, err.Error()))
+	}
+
+	units := make([]models.ProductUnit, len(input.Units))
+	for i, unit := range input.Units {
+		units[i] = models.ProductUnit{ProductID: product.ID, Name: unit.Name, Factor: unit.Factor}
+	}
+	if err := tx.Products().ReplaceUnits(ctx, product.ID, units); err != nil {
+		return err
+	}
+
+	if err := tx.Products().Save(ctx, product); err != nil {
+		return conflict(err)
+	}
+	product.Units = units
+	return s.events.Publish(ctx, tx, AggregateProduct, product.ID, EventProductUpdated, product)
+}
+
+// copyUnits gives a new variant the units of its parent
+func copyUnits(ctx context.Context, tx repositories.Store, parent *models.Product, variant *models.Product) error {
+	units, err := tx.Products().ListUnits(ctx, parent.ID)
+	if err != nil || len(units) == 0 {
+		return err
+	}
+	for i := range units {
+		units[i] = models.ProductUnit{ProductID: variant.ID, Name: units[i].Name, Factor: units[i].Factor}
+	}
+	variant.Units = units
+	return tx.Products().ReplaceUnits(ctx, variant.ID, units)
+}
+
+// normalizeProductUnits trims the unit names and checks that they are unique,
+// that the factors are positive and that the default units are defined
+func normalizeProductUnits(input ProductUnitsInput) (ProductUnitsInput, error) {
+	input.BaseUnit = strings.TrimSpace(input.BaseUnit)
+	if input.BaseUnit == "" || len(input.BaseUnit) > 20 {
+		return input, invalid("Base unit is required and at most 20 characters")
+	}
+	if len(input.Units) > maxProductUnits {
+		return input, invalid(fmt.Sprintf("A product has at most %d alternative units", maxProductUnits))
+	}
+
+	names := map[string]string{strings.ToLower(input.BaseUnit): input.BaseUnit}
+	units := make([]ProductUnitInput, len(input.Units))
+	for i, unit := range input.Units {
+		name := strings.TrimSpace(unit.Name)
+		if name == "" || len(name) > 20 {
+			return input, invalid("Unit names are required and at most 20 characters")
+		}
+		if _, exists := names[strings.ToLower(name)]; exists {
+			return input, invalid(fmt.Sprintf("Unit %s is listed twice", name))
+		}
+		names[strings.ToLower(name)] = name
+
+		factor := math.Round(unit.Factor*factorScale) / factorScale
+		if factor <= 0 {
+			return input, invalid(fmt.Sprintf("Unit %s needs a positive factor, the number of %s it holds", name, input.BaseUnit))
+		}
+		if !input.Fractional && factor != math.Trunc(factor) {
+			return input, invalid(fmt.Sprintf("Unit %s must hold a whole number of %s", name, input.BaseUnit))
+		}
+		units[i] = ProductUnitInput{Name: name, Factor: factor}
+	}
+	input.Units = units
+
+	for _, field := range []*string{&input.PurchaseUnit, &input.SalesUnit} {
+		unit := strings.TrimSpace(*field)
+		if unit == "" {
+			continue
+		}
+		name, ok := names[strings.ToLower(unit)]
+		if !ok {
+			return input, invalid(fmt.Sprintf("Default unit %s is not one of the units of the product", unit))
+		}
+		*field = name
+		if name == input.BaseUnit {
+			*field = ""
+		}
+	}
+	return input, nil
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"inventory-backend/models"
+)
+
+func TestToBaseQuantity(t *testing.T) {
+	product := &models.Product{SKU: "CABLE", BaseUnit: "m", Fractional: true}
+	units := []models.ProductUnit{{Name: "Roll", Factor: 50}, {Name: "cm", Factor: 0.01}}
+
+	tests := []struct {
+		unit     string
+		quantity float64
+		name     string
+		base     float64
+	}{
+		{"", 2.5, "m", 2.5},
+		{"M", 2.5, "m", 2.5},
+		{"roll", 3, "Roll", 150},
+		{"cm", 125, "cm", 1.25},
+	}
+	for _, tt := range tests {
+		name, base, err := toBaseQuantity(product, units, tt.unit, tt.quantity)
+		if err != nil {
+			t.Errorf("%v %q: %v", tt.quantity, tt.unit, err)
+			continue
+		}
+		if name != tt.name || base != tt.base {
+			t.Errorf("%v %q = %v %s, want %v %s", tt.quantity, tt.unit, base, name, tt.base, tt.name)
+		}
+	}
+
+	if _, _, err := toBaseQuantity(product, units, "box", 1); err == nil {
+		t.Error("unknown unit accepted")
+	}
+	if _, _, err := toBaseQuantity(product, units, "cm", 0.01); err == nil {
+		t.Error("quantity below the stored precision accepted")
+	}
+
+	// Whole units only, half a carton of 5 is 2.5 pieces
+	pieces := &models.Product{SKU: "AA", BaseUnit: "pcs"}
+	if _, _, err := toBaseQuantity(pieces, []models.ProductUnit{{Name: "carton", Factor: 5}}, "carton", 0.5); err == nil {
+		t.Error("fractional pieces accepted")
+	}
+}
+
+func TestCeilQuantity(t *testing.T) {
+	whole := &models.Product{}
+	fractional := &models.Product{Fractional: true}
+
+	tests := []struct {
+		product *models.Product
+		q, want float64
+	}{
+		{whole, 2.0000001, 2},
+		{whole, 2.01, 3},
+		{fractional, 1.2341, 1.235},
+		{fractional, 1.2340000001, 1.234},
+		{whole, -0.0001, 0},
+	}
+	for _, tt := range tests {
+		if got := ceilQuantity(tt.product, tt.q); got != tt.want {
+			t.Errorf("ceilQuantity(fractional %v, %v) = %v, want %v", tt.product.Fractional, tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestStockMoveDefaultUnits(t *testing.T) {
+	store := newTestStore(t)
+	products, stock, _ := newTestServices(store)
+	supplier := createTestSupplier(t, store)
+	product := createTestProduct(t, products, supplier.ID, "SKU-1", 0)
+	ctx := context.Background()
+
+	_, err := products.SetUnits(ctx, 1, product.ID, 0, ProductUnitsInput{
+		BaseUnit:     "pcs",
+		PurchaseUnit: "carton",
+		SalesUnit:    "pack",
+		Units:        []ProductUnitInput{{Name: "carton", Factor: 24}, {Name: "pack", Factor: 6}},
+	})
+	if err != nil {
+		t.Fatalf("SetUnits: %v", err)
+	}
+
+	in, err := stock.Move(ctx, 1, product.ID, StockMovementInput{Type: "in", Quantity: 2})
+	if err != nil {
+		t.Fatalf("Move in: %v", err)
+	}
+	if in.StockAfter != 48 || in.History.Unit != "carton" {
+		t.Errorf("Move in 2: stock %v in %s, want 48 in carton", in.StockAfter, in.History.Unit)
+	}
+
+	out, err := stock.Move(ctx, 1, product.ID, StockMovementInput{Type: "out", Quantity: 3})
+	if err != nil {
+		t.Fatalf("Move out: %v", err)
+	}
+	if out.StockAfter != 30 || out.History.Unit != "pack" {
+		t.Errorf("Move out 3: stock %v in %s, want 30 in pack", out.StockAfter, out.History.Unit)
+	}
+
+	base, err := stock.Move(ctx, 1, product.ID, StockMovementInput{Type: "out", Quantity: 1, Unit: "pcs"})
+	if err != nil {
+		t.Fatalf("Move out in pcs: %v", err)
+	}
+	if base.StockAfter != 29 {
+		t.Errorf("Move out 1 pcs: stock %v, want 29", base.StockAfter)
+	}
+}
@@ -0,0 +1,423 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"inventory-backend/models"
+	"inventory-backend/repositories"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Webhook request headers
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature" // t=<unix time>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+const (
+	// webhookPollInterval is how often due deliveries are sent
+	webhookPollInterval = 5 * time.Second
+	// webhookResponseLimit is how much of a response body is kept in the log
+	webhookResponseLimit = 2048
+)
+
+// WebhookOptions configures delivery retries and log retention
+type WebhookOptions struct {
+	MaxAttempts int
+	Timeout     time.Duration
+	Retention   time.Duration
+}
+
+// WebhookInput holds subscription fields. On update, empty fields are left
+// untouched.
+type WebhookInput struct {
+	Name    string
+	URL     string
+	Events  []string
+	Enabled *bool
+}
+
+// WebhookService manages webhook subscriptions and delivers inventory events
+// to them through a persistent queue with exponential backoff
+type WebhookService struct {
+	store   repositories.Store
+	client  *http.Client
+	options WebhookOptions
+}
+
+func NewWebhookService(store repositories.Store, options WebhookOptions) *WebhookService {
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 10
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 10 * time.Second
+	}
+	if options.Retention <= 0 {
+		options.Retention = 30 * 24 * time.Hour
+	}
+	return &WebhookService{store: store, client: &http.Client{Timeout: options.Timeout}, options: options}
+}
+
+// Enqueue queues an outbox message for every enabled subscription that wants
+// it. The relay may hand over the same message again after a failure, so a
+// message that was already queued is skipped.
+func (s *WebhookService) Enqueue(ctx context.Context, msg OutboxMessage) error {
+	queued, err := s.store.WebhookDeliveries().ExistsForEvent(ctx, msg.EventID)
+	if err != nil || queued {
+		return err
+	}
+
+	subscriptions, err := s.store.WebhookSubscriptions().ListEnabled(ctx)
+	if err != nil {
+		return err
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, subscription := range subscriptions {
+		if !wantsEvent(subscription, msg.EventType) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        msg.EventID,
+			Event:          msg.EventType,
+			Payload:        string(msg.Payload),
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+		})
+	}
+	return s.store.WebhookDeliveries().Create(ctx, deliveries)
+}
+
+// wantsEvent reports whether a subscription listens to eventType
+func wantsEvent(subscription models.WebhookSubscription, eventType string) bool {
+	for _, e := range strings.Split(subscription.Events, ",") {
+		if e = strings.TrimSpace(e); e == "*" || e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Start delivers due events and prunes the delivery log until ctx is cancelled
+func (s *WebhookService) Start(ctx context.Context) {
+	go every(ctx, webhookPollInterval, s.deliverDue)
+	go every(ctx, 24*time.Hour, func(ctx context.Context) {
+		removed, err := s.store.WebhookDeliveries().DeleteFinishedBefore(ctx, time.Now().Add(-s.options.Retention))
+		if err != nil {
+			log.Printf("Failed to prune webhook deliveries: %v", err)
+			return
+		}
+		if removed > 0 {
+			log.Printf("🧹 Pruned %d old webhook deliveries", removed)
+		}
+	})
+}
+
+func (s *WebhookService) deliverDue(ctx context.Context) {
+	deliveries, err := s.store.WebhookDeliveries().ListDue(ctx, time.Now(), 100)
+	if err != nil {
+		log.Printf("Failed to list webhook deliveries: %v", err)
+		return
+	}
+
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		s.deliver(ctx, &deliveries[i])
+	}
+}
+
+// deliver makes one attempt, records the response and schedules a retry when
+// the receiver did not answer with a 2xx status
+func (s *WebhookService) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	err := s.send(ctx, delivery)
+
+	now := time.Now()
+	delivery.Attempts++
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= s.options.MaxAttempts:
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.LastError = err.Error()
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(webhookBackoff(delivery.Attempts))
+	}
+
+	if err := s.store.WebhookDeliveries().Save(ctx, delivery); err != nil {
+		log.Printf("Failed to update webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+func (s *WebhookService) send(ctx context.Context, delivery *models.WebhookDelivery) error {
+	subscription, err := s.store.WebhookSubscriptions().FindByID(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return fmt.Errorf("subscription unavailable: %w", err)
+	}
+	if !subscription.Enabled {
+		return fmt.Errorf("subscription is disabled")
+	}
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "inventory-webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, delivery.EventID)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(subscription.Secret, time.Now().Unix(), body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		delivery.ResponseStatus = 0
+		delivery.ResponseBody = ""
+		return err
+	}
+	defer resp.Body.Close()
+
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	delivery.ResponseStatus = resp.StatusCode
+	delivery.ResponseBody = string(response)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookBackoff doubles the wait after every failed attempt, from 30 seconds
+// up to 12 hours
+func webhookBackoff(attempts int) time.Duration {
+	backoff := 30 * time.Second << (attempts - 1)
+	if backoff > 12*time.Hour || backoff <= 0 {
+		return 12 * time.Hour
+	}
+	return backoff
+}
+
+// SignWebhook returns the signature header value for body sent at timestamp
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, webhookMAC(secret, timestamp, body))
+}
+
+// VerifyWebhookSignature checks a signature header against body. Signatures
+// older than tolerance are rejected to prevent replays.
+func VerifyWebhookSignature(secret, header string, body []byte, tolerance time.Duration) error {
+	var timestamp int64
+	var signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp, _ = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			signature = value
+		}
+	}
+	if timestamp == 0 || signature == "" {
+		return fmt.Errorf("malformed signature header")
+	}
+	if age := time.Since(time.Unix(timestamp, 0)); tolerance > 0 && (age > tolerance || age < -tolerance) {
+		return fmt.Errorf("signature timestamp outside tolerance")
+	}
+	if !hmac.Equal([]byte(signature), []byte(webhookMAC(secret, timestamp, body))) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+func webhookMAC(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *WebhookService) List(ctx context.Context) ([]models.WebhookSubscription, error) {
+	return s.store.WebhookSubscriptions().List(ctx)
+}
+
+func (s *WebhookService) Get(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	subscription, err := s.store.WebhookSubscriptions().FindByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrWebhookNotFound)
+	}
+	return subscription, nil
+}
+
+// Create adds a subscription with a newly generated signing secret
+func (s *WebhookService) Create(ctx context.Context, userID uint, input WebhookInput) (*models.WebhookSubscription, error) {
+	subscription := models.WebhookSubscription{Enabled: true, CreatedBy: userID, Secret: newWebhookSecret()}
+	if err := applyWebhook(&subscription, input); err != nil {
+		return nil, err
+	}
+	if err := s.store.WebhookSubscriptions().Create(ctx, &subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (s *WebhookService) Update(ctx context.Context, id uint, input WebhookInput) (*models.WebhookSubscription, error) {
+	subscription, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyWebhook(subscription, input); err != nil {
+		return nil, err
+	}
+	if err := s.store.WebhookSubscriptions().Save(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// RotateSecret replaces the signing secret of a subscription
+func (s *WebhookService) RotateSecret(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	subscription, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	subscription.Secret = newWebhookSecret()
+	if err := s.store.WebhookSubscriptions().Save(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (s *WebhookService) Delete(ctx context.Context, id uint) error {
+	subscription, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.store.WebhookSubscriptions().Delete(ctx, subscription)
+}
+
+// Test sends a webhook.test event to a subscription right away and returns
+// the logged delivery, whether or not the receiver accepted it
+func (s *WebhookService) Test(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	subscription, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	event := newEvent(EventWebhookTest, map[string]interface{}{
+		"subscription_id": subscription.ID,
+		"message":         "This is a test event",
+	})
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []models.WebhookDelivery{{
+		SubscriptionID: subscription.ID,
+		EventID:        event.ID,
+		Event:          EventWebhookTest,
+		Payload:        string(payload),
+		Status:         models.WebhookDeliveryPending,
+		NextAttemptAt:  event.OccurredAt,
+	}}
+	if err := s.store.WebhookDeliveries().Create(ctx, deliveries); err != nil {
+		return nil, err
+	}
+
+	delivery := &deliveries[0]
+	s.deliver(ctx, delivery)
+	return delivery, nil
+}
+
+// ListDeliveries returns the delivery log of a subscription, newest first
+func (s *WebhookService) ListDeliveries(ctx context.Context, filter repositories.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	switch filter.Status {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliverySucceeded, models.WebhookDeliveryFailed:
+	default:
+		return nil, invalid("Status must be 'pending', 'succeeded' or 'failed'")
+	}
+	if filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+	return s.store.WebhookDeliveries().List(ctx, filter)
+}
+
+func (s *WebhookService) GetDelivery(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	delivery, err := s.store.WebhookDeliveries().FindByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrWebhookDeliveryNotFound)
+	}
+	return delivery, nil
+}
+
+// Retry queues a delivery again with a fresh set of attempts
+func (s *WebhookService) Retry(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	delivery, err := s.GetDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status == models.WebhookDeliverySucceeded {
+		return nil, invalid("Delivery already succeeded")
+	}
+
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	if err := s.store.WebhookDeliveries().Save(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// applyWebhook copies input onto subscription and validates the result
+func applyWebhook(subscription *models.WebhookSubscription, input WebhookInput) error {
+	if input.Name != "" {
+		subscription.Name = input.Name
+	}
+	if input.URL != "" {
+		subscription.URL = strings.TrimSpace(input.URL)
+	}
+	if input.Events != nil {
+		for _, event := range input.Events {
+			if event != "*" && !IsEventType(event) {
+				return invalid(fmt.Sprintf("Unknown event %q, use one of %s or *", event, strings.Join(EventTypes, ", ")))
+			}
+		}
+		subscription.Events = strings.Join(input.Events, ",")
+	}
+	if input.Enabled != nil {
+		subscription.Enabled = *input.Enabled
+	}
+
+	if subscription.Name == "" || subscription.URL == "" || subscription.Events == "" {
+		return invalid("Name, URL and at least one event are required")
+	}
+	u, err := url.Parse(subscription.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return invalid("URL must be an http or https URL")
+	}
+	return nil
+}
+
+func newWebhookSecret() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignWebhook(t *testing.T) {
+	// HMAC-SHA256 of `1700000000.{"id":1}` with the key whsec_test
+	want := "t=1700000000,v1=2f441ba4b3b2d50d28a9ab9d9fd8880376ecd1eb5d0435401553f5d8d0a5dcf8"
+	if got := SignWebhook("whsec_test", 1700000000, []byte(`{"id":1}`)); got != want {
+		t.Errorf("SignWebhook = %s, want %s", got, want)
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"event":"product.updated"}`)
+	now := time.Now().Unix()
+	header := SignWebhook("secret", now, body)
+
+	if err := VerifyWebhookSignature("secret", header, body, 5*time.Minute); err != nil {
+		t.Errorf("valid signature: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+	}{
+		{"wrong secret", "other", header, body},
+		{"changed body", "secret", header, []byte(`{"event":"product.deleted"}`)},
+		{"expired", "secret", SignWebhook("secret", now-600, body), body},
+		{"from the future", "secret", SignWebhook("secret", now+600, body), body},
+		{"no timestamp", "secret", "v1=abc", body},
+		{"no signature", "secret", "t=1700000000", body},
+	}
+	for _, tt := range tests {
+		if err := VerifyWebhookSignature(tt.secret, tt.header, tt.body, 5*time.Minute); err == nil {
+			t.Errorf("%s: verified, want an error", tt.name)
+		}
+	}
+
+	// Without a tolerance old signatures are accepted
+	if err := VerifyWebhookSignature("secret", SignWebhook("secret", now-600, body), body, 0); err != nil {
+		t.Errorf("old signature without tolerance: %v", err)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{5, 8 * time.Minute},
+		{11, 512 * time.Minute},
+		{12, 12 * time.Hour},
+		// The shift overflows long before this
+		{100, 12 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -29,19 +29,22 @@ export const productService = {
     return response.data;
   },
 
-  // Update product with image
-  update: async (id, formData) => {
+  // Update product with image. version is the product version the form was loaded with.
+  update: async (id, formData, version) => {
     const response = await axios.put(`/products/${id}`, formData, {
       headers: {
         'Content-Type': 'multipart/form-data',
+        'If-Match': `"${version}"`,
       },
     });
     return response.data;
   },
 
   // Delete product
-  delete: async (id) => {
-    const response = await axios.delete(`/products/${id}`);
+  delete: async (id, version) => {
+    const response = await axios.delete(`/products/${id}`, {
+      headers: { 'If-Match': `"${version}"` },
+    });
     return response.data;
   },
 
@@ -51,6 +54,12 @@ export const productService = {
     return response.data;
   },
 
+  // Get the base unit and alternative units of a product
+  getUnits: async (id) => {
+    const response = await axios.get(`/products/${id}/units`);
+    return response.data;
+  },
+
   // Update stock
   updateStock: async (id, data) => {
     const response = await axios.post(`/products/${id}/stock`, data);
@@ -16,13 +16,17 @@ export const supplierService = {
     return response.data;
   },
 
-  update: async (id, data) => {
-    const response = await axios.put(`/suppliers/${id}`, data);
+  update: async (id, data, version) => {
+    const response = await axios.put(`/suppliers/${id}`, data, {
+      headers: { 'If-Match': `"${version}"` },
+    });
     return response.data;
   },
 
-  delete: async (id) => {
-    const response = await axios.delete(`/suppliers/${id}`);
+  delete: async (id, version) => {
+    const response = await axios.delete(`/suppliers/${id}`, {
+      headers: { 'If-Match': `"${version}"` },
+    });
     return response.data;
   },
 };
\ No newline at end of file
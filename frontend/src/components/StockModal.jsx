@@ -1,16 +1,33 @@
-import { useState } from 'react';
+import { useState, useEffect } from 'react';
 import { FiX, FiTrendingUp, FiTrendingDown, FiPackage, FiInfo } from 'react-icons/fi';
+import { productService } from '../api/productService';
 
 const StockModal = ({ isOpen, onClose, onSubmit, product }) => {
   const [type, setType] = useState('in');
   const [quantity, setQuantity] = useState('');
   const [note, setNote] = useState('');
+  const [units, setUnits] = useState([]);
+
+  useEffect(() => {
+    if (isOpen && product?.id) {
+      setUnits([]);
+      productService.getUnits(product.id)
+        .then((data) => setUnits(data.product?.units || []))
+        .catch(() => setUnits([]));
+    }
+  }, [isOpen, product?.id]);
+
+  const baseUnit = product?.base_unit || 'pcs';
+  // Stock in is counted in the purchase unit and stock out in the sales unit
+  const unit = (type === 'in' ? product?.purchase_unit : product?.sales_unit) || baseUnit;
+  const factor = unit === baseUnit ? 1 : units.find((u) => u.name === unit)?.factor || 1;
 
   const handleSubmit = (e) => {
     e.preventDefault();
     onSubmit({
       type,
-      quantity: parseInt(quantity),
+      quantity: parseFloat(quantity),
+      unit,
       note,
     });
 
@@ -22,8 +39,8 @@ const StockModal = ({ isOpen, onClose, onSubmit, product }) => {
   if (!isOpen) return null;
 
   const currentStock = product?.stock || 0;
-  const quantityNum = parseInt(quantity) || 0;
-  const newStock = type === 'in' ? currentStock + quantityNum : currentStock - quantityNum;
+  const quantityNum = (parseFloat(quantity) || 0) * factor;
+  const newStock = Math.round((type === 'in' ? currentStock + quantityNum : currentStock - quantityNum) * 1000) / 1000;
 
   return (
     <div className="fixed inset-0 z-50 flex items-center justify-center p-4 animate-fade-in">
@@ -48,7 +65,7 @@ const StockModal = ({ isOpen, onClose, onSubmit, product }) => {
           </div>
           <div>
             <h3 className="font-semibold text-gray-900 line-clamp-1">{product?.name}</h3>
-            <p className="text-sm text-gray-500">Current Stock: <span className="font-medium text-gray-900">{product?.stock} pcs</span></p>
+            <p className="text-sm text-gray-500">Current Stock: <span className="font-medium text-gray-900">{product?.stock} {baseUnit}</span></p>
           </div>
         </div>
 
@@ -98,10 +115,11 @@ const StockModal = ({ isOpen, onClose, onSubmit, product }) => {
                   onChange={(e) => setQuantity(e.target.value)}
                   className="w-full pl-4 pr-12 py-3 bg-gray-50 border border-gray-200 rounded-xl focus:bg-white focus:ring-2 focus:ring-primary-100 focus:border-primary-500 transition-all outline-none font-medium text-lg"
                   placeholder="0"
-                  min="1"
+                  min={product?.fractional ? '0.001' : '1'}
+                  step={product?.fractional ? '0.001' : '1'}
                   required
                 />
-                <span className="absolute right-4 top-1/2 -translate-y-1/2 text-gray-400 font-medium">pcs</span>
+                <span className="absolute right-4 top-1/2 -translate-y-1/2 text-gray-400 font-medium">{unit}</span>
               </div>
             </div>
 
@@ -127,7 +145,7 @@ const StockModal = ({ isOpen, onClose, onSubmit, product }) => {
                   <FiInfo />
                   <span className="text-sm font-medium">New Stock Balance:</span>
                 </div>
-                <span className="text-lg font-bold">{newStock} pcs</span>
+                <span className="text-lg font-bold">{newStock} {baseUnit}</span>
               </div>
             )}
           </div>
@@ -37,7 +37,7 @@ const SupplierModal = ({ isOpen, onClose, refreshSuppliers }) => {
         e.preventDefault();
         try {
             if (editingSupplier) {
-                await supplierService.update(editingSupplier.id, formData);
+                await supplierService.update(editingSupplier.id, formData, editingSupplier.version);
             } else {
                 await supplierService.create(formData);
             }
@@ -52,11 +52,13 @@ const SupplierModal = ({ isOpen, onClose, refreshSuppliers }) => {
     const handleDelete = async (id) => {
         if (window.confirm('Are you sure? This might affect products linked to this supplier.')) {
             try {
-                await supplierService.delete(id);
+                const supplier = suppliers.find((s) => s.id === id);
+                await supplierService.delete(id, supplier?.version);
                 fetchSuppliers();
                 refreshSuppliers();
             } catch (error) {
-                alert('Failed to delete supplier');
+                alert(error.response?.data?.error || 'Failed to delete supplier');
+                fetchSuppliers();
             }
         }
     };
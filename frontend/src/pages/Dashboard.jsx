@@ -95,7 +95,7 @@ const Dashboard = () => {
   const handleCreateOrUpdate = async (formData) => {
     try {
       if (editingProduct) {
-        await productService.update(editingProduct.id, formData);
+        await productService.update(editingProduct.id, formData, editingProduct.version);
       } else {
         await productService.create(formData);
       }
@@ -110,10 +110,12 @@ const Dashboard = () => {
   const handleDelete = async (id) => {
     if (window.confirm('Are you sure you want to delete this product?')) {
       try {
-        await productService.delete(id);
+        const product = products.find((p) => p.id === id);
+        await productService.delete(id, product?.version);
+        fetchData();
+      } catch (error) {
+        alert(error.response?.data?.error || 'Failed to delete product');
         fetchData();
-      } catch {
-        alert('Failed to delete product');
       }
     }
   };